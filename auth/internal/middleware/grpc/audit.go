@@ -0,0 +1,25 @@
+package grpc
+
+import (
+	authv1 "github.com/go-chat/auth/pkg/api/auth/v1"
+	"github.com/go-chat/lib/audit"
+)
+
+// AuditMethods declares the auth methods recorded in the audit log. Credentials and tokens
+// are marked debug_redact in the protos and never recorded; the account is identified by
+// email in the request and by user ID once authenticated.
+func AuditMethods() map[string]audit.MethodConfig {
+	return map[string]audit.MethodConfig{
+		authv1.AuthService_Register_FullMethodName: {
+			RequestFields:  []string{"email"},
+			ResponseFields: []string{"user_id"},
+		},
+		authv1.AuthService_Login_FullMethodName: {
+			RequestFields:  []string{"email"},
+			ResponseFields: []string{"user_id"},
+		},
+		authv1.AuthService_Refresh_FullMethodName: {
+			ResponseFields: []string{"user_id"},
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	authv1 "github.com/go-chat/auth/pkg/api/auth/v1"
+	"github.com/go-chat/lib/audit"
+	"google.golang.org/grpc"
+)
+
+func TestAuditMethods_Login_RedactsCredentials(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := audit.NewLogger(audit.NewWriterSink(&buf))
+	if err != nil {
+		t.Fatalf("NewLogger() failed: %v", err)
+	}
+	interceptor := audit.NewInterceptor(logger, AuditMethods(), testLogger).UnaryServerInterceptor()
+
+	req := &authv1.LoginRequest{Email: "user@example.com", Password: "SecurePass123!"}
+	_, err = interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: authv1.AuthService_Login_FullMethodName},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &authv1.LoginResponse{AccessToken: "access", RefreshToken: "refresh", UserId: "550e8400-e29b-41d4-a716-446655440000"}, nil
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var entry audit.Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry.Target["email"] != "user@example.com" || entry.Target["user_id"] != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("Expected email and user ID to be recorded, got %v", entry.Target)
+	}
+	for _, secret := range []string{"SecurePass123!", "access", "refresh"} {
+		if bytes.Contains(buf.Bytes(), []byte(`"`+secret+`"`)) {
+			t.Errorf("Expected %q not to be recorded, got %s", secret, buf.String())
+		}
+	}
+}
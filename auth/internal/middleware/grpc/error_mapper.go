@@ -1,41 +1,23 @@
 package grpc
 
 import (
-	"context"
-	"errors"
+	"log/slog"
 
 	"github.com/go-chat/auth/internal/domain"
-	"google.golang.org/grpc"
+	"github.com/go-chat/lib/grpc_middleware"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// ErrorMapperInterceptor returns a unary server interceptor that maps domain errors to gRPC status codes
-func ErrorMapperInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		resp, err := handler(ctx, req)
-		if err != nil {
-			return resp, mapDomainError(err)
-		}
-		return resp, nil
-	}
-}
+// ErrorDomain identifies auth errors in google.rpc.ErrorInfo details
+const ErrorDomain = "auth.go-chat"
 
-// mapDomainError converts domain errors to appropriate gRPC status codes
-func mapDomainError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrEmailAlreadyExists):
-		return status.Error(codes.AlreadyExists, "email already registered")
-	case errors.Is(err, domain.ErrInvalidCredentials):
-		return status.Error(codes.Unauthenticated, "invalid email or password")
-	case errors.Is(err, domain.ErrInvalidToken),
-		errors.Is(err, domain.ErrTokenExpired),
-		errors.Is(err, domain.ErrTokenRevoked):
-		return status.Error(codes.Unauthenticated, "invalid or expired token")
-	default:
-		// Log internal error details here if needed
-		// For now, return a generic internal error
-		return status.Error(codes.Internal, "internal server error")
-	}
+// NewErrorRegistry maps auth domain errors to the gRPC statuses clients receive.
+// Unregistered errors are logged to logger and returned as Internal.
+func NewErrorRegistry(logger *slog.Logger) *grpc_middleware.ErrorRegistry {
+	return grpc_middleware.NewErrorRegistry(ErrorDomain, logger).
+		Register(domain.ErrEmailAlreadyExists, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "email already registered", Reason: "EMAIL_ALREADY_EXISTS"}).
+		Register(domain.ErrInvalidCredentials, grpc_middleware.ErrorMapping{Code: codes.Unauthenticated, Message: "invalid email or password", Reason: "INVALID_CREDENTIALS"}).
+		Register(domain.ErrInvalidToken, grpc_middleware.ErrorMapping{Code: codes.Unauthenticated, Message: "invalid or expired token", Reason: "INVALID_TOKEN"}).
+		Register(domain.ErrTokenExpired, grpc_middleware.ErrorMapping{Code: codes.Unauthenticated, Message: "invalid or expired token", Reason: "TOKEN_EXPIRED"}).
+		Register(domain.ErrTokenRevoked, grpc_middleware.ErrorMapping{Code: codes.Unauthenticated, Message: "invalid or expired token", Reason: "TOKEN_REVOKED"})
 }
-
@@ -1,54 +1,87 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net"
+	"os"
 
 	"github.com/go-chat/chat/internal/handler"
 	grpcmw "github.com/go-chat/chat/internal/middleware/grpc"
 	"github.com/go-chat/chat/internal/service"
 	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	"github.com/go-chat/lib/admin"
+	"github.com/go-chat/lib/grpc_client"
 	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/go-chat/lib/health"
+	"github.com/go-chat/lib/telemetry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
 const (
 	port = ":8080"
+
+	// defaultSocialAddr is used when SOCIAL_ADDR is unset
+	defaultSocialAddr = "social:8080"
 )
 
 func main() {
 	log.Println("Chat Service starting...")
 
-	// Create middleware manager with validation enabled by default
-	mgr, err := grpc_middleware.NewManager()
+	// Structured JSON logs for per-request logging
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service", "chat")
+
+	// Tracing and Prometheus metrics; spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	telemetryCfg := telemetry.ConfigFromEnv("chat")
+	tel, err := telemetry.New(context.Background(), telemetryCfg)
 	if err != nil {
-		log.Fatalf("Failed to create middleware manager: %v", err)
+		log.Fatalf("Failed to initialize telemetry: %v", err)
 	}
+	go func() {
+		if err := tel.ServeMetrics(); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
 
-	// Get interceptor chains
-	unaryInterceptors, err := mgr.UnaryInterceptors()
+	// Streams stay open for as long as the client listens, so they are exempt from deadline policies
+	deadlines := grpc_middleware.DefaultDeadlines()
+	deadlines.Methods = map[string]grpc_middleware.DeadlinePolicy{
+		chatv1.ChatService_StreamMessages_FullMethodName: {},
+	}
+
+	// Staging sets RESPONSE_VALIDATION to catch handlers returning responses that break their own rules
+	responseValidation, err := grpc_middleware.ResponseActionsFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to get unary interceptors: %v", err)
+		log.Fatalf("Invalid response validation config: %v", err)
 	}
 
-	// Add chat service error mapper middleware
-	chatErrorMapper := grpcmw.ErrorMapperInterceptor()
-	unaryInterceptors = append(unaryInterceptors, chatErrorMapper)
+	// Create middleware manager with validation enabled by default, panic recovery, authorization, request logging, telemetry and deadlines
+	mgr, err := grpc_middleware.NewManager(
+		grpc_middleware.WithRecovery(nil),
+		grpc_middleware.WithResponseValidation(responseValidation),
+		grpc_middleware.WithLogger(logger),
+		grpc_middleware.WithTelemetry(tel.TracerProvider(), tel.MeterProvider()),
+		grpc_middleware.WithAuthorization(grpc_middleware.ServiceTokenAuthenticator(grpc_middleware.ServiceTokenFromEnv())),
+		grpc_middleware.WithDeadlines(deadlines),
+		// Map domain errors to statuses innermost, so logging and metrics see the final code
+		grpc_middleware.WithErrorRegistry(grpcmw.NewErrorRegistry(logger)),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create middleware manager: %v", err)
+	}
 
-	streamInterceptors, err := mgr.StreamInterceptors()
+	serverOpts, err := mgr.ServerOptions()
 	if err != nil {
-		log.Fatalf("Failed to get stream interceptors: %v", err)
+		log.Fatalf("Failed to build interceptor chains: %v", err)
 	}
 
 	// Create gRPC server with middleware
-	grpcServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(unaryInterceptors...),
-		grpc.ChainStreamInterceptor(streamInterceptors...),
-	)
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// TODO: Replace with actual service implementations in next iteration
-	// For now, use nil services - handlers will panic if called
+	// For now, use nil services - handlers will panic if called, which recovery turns into Internal errors
 	var chatService service.ChatService = nil
 	var messageService service.MessageService = nil
 
@@ -56,6 +89,48 @@ func main() {
 	chatv1.RegisterChatServiceServer(grpcServer, chatHandler)
 	reflection.Register(grpcServer)
 
+	// Chat checks relationships with the social service; connections are lazy, so chat starts before social is up
+	clients := grpc_client.NewFactory(
+		grpc_client.WithServiceToken("chat", grpc_middleware.ServiceTokenFromEnv()),
+		grpc_client.WithTelemetry(tel.TracerProvider(), tel.MeterProvider()),
+	)
+	defer clients.Close()
+
+	socialAddr := os.Getenv("SOCIAL_ADDR")
+	if socialAddr == "" {
+		socialAddr = defaultSocialAddr
+	}
+	socialConn, err := clients.Conn(grpc_client.Config{Target: socialAddr})
+	if err != nil {
+		log.Fatalf("Failed to create social client: %v", err)
+	}
+
+	// Expose grpc.health.v1; social is reported as its own component so an outage there degrades chat instead of taking it out of rotation
+	healthServer := health.NewServer([]string{chatv1.ChatService_ServiceDesc.ServiceName},
+		health.WithOptionalCheck("social", health.GRPCCheck(socialConn, "")),
+	)
+	healthServer.Register(grpcServer)
+
+	// Methods without a (go_chat.auth) option are denied; surface them before they are called
+	if missing := mgr.UnannotatedMethods(grpcServer.GetServiceInfo()); len(missing) > 0 {
+		log.Printf("Methods without an authorization policy will be denied: %v", missing)
+	}
+	go healthServer.Run(context.Background())
+	// On SIGTERM report NOT_SERVING first, so load balancers stop routing here, then drain in-flight calls
+	go healthServer.DrainOnSignal(grpcServer, health.DefaultDrainDelay)
+
+	// pprof, config and route table on an internal listener; ADMIN_ADDR defaults to localhost
+	adminServer := admin.New(admin.AddrFromEnv(),
+		admin.WithConfig(telemetryCfg),
+		admin.WithRoutes(admin.GRPCRoutes(grpcServer)),
+		admin.WithConnections(map[string]*grpc.ClientConn{"social": socialConn}),
+	)
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+
 	listener, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("Failed to listen on %s: %v", port, err)
@@ -65,4 +140,5 @@ func main() {
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
+	log.Println("Chat Service stopped")
 }
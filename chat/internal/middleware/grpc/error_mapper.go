@@ -1,47 +1,25 @@
 package grpc
 
 import (
-	"context"
-	"errors"
+	"log/slog"
 
 	"github.com/go-chat/chat/internal/domain"
-	"google.golang.org/grpc"
+	"github.com/go-chat/lib/grpc_middleware"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// ErrorMapperInterceptor returns a unary server interceptor that maps domain errors to gRPC status codes
-func ErrorMapperInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		resp, err := handler(ctx, req)
-		if err != nil {
-			return resp, mapDomainError(err)
-		}
-		return resp, nil
-	}
-}
+// ErrorDomain identifies chat errors in google.rpc.ErrorInfo details
+const ErrorDomain = "chat.go-chat"
 
-// mapDomainError converts domain errors to appropriate gRPC status codes
-func mapDomainError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrChatNotFound):
-		return status.Error(codes.NotFound, "chat not found")
-	case errors.Is(err, domain.ErrChatAlreadyExists):
-		return status.Error(codes.AlreadyExists, "chat already exists")
-	case errors.Is(err, domain.ErrPermissionDenied):
-		return status.Error(codes.PermissionDenied, "permission denied")
-	case errors.Is(err, domain.ErrInvalidMessage):
-		return status.Error(codes.InvalidArgument, "invalid message")
-	case errors.Is(err, domain.ErrInvalidChatID):
-		return status.Error(codes.InvalidArgument, "invalid chat ID")
-	case errors.Is(err, domain.ErrUsersNotFriends):
-		return status.Error(codes.PermissionDenied, "users are not friends")
-	case errors.Is(err, domain.ErrUserBlocked):
-		return status.Error(codes.PermissionDenied, "user is blocked")
-	default:
-		// Log internal error details here if needed
-		// For now, return a generic internal error
-		return status.Error(codes.Internal, "internal server error")
-	}
+// NewErrorRegistry maps chat domain errors to the gRPC statuses clients receive.
+// Unregistered errors are logged to logger and returned as Internal.
+func NewErrorRegistry(logger *slog.Logger) *grpc_middleware.ErrorRegistry {
+	return grpc_middleware.NewErrorRegistry(ErrorDomain, logger).
+		Register(domain.ErrChatNotFound, grpc_middleware.ErrorMapping{Code: codes.NotFound, Message: "chat not found", Reason: "CHAT_NOT_FOUND"}).
+		Register(domain.ErrChatAlreadyExists, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "chat already exists", Reason: "CHAT_ALREADY_EXISTS"}).
+		Register(domain.ErrPermissionDenied, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "permission denied", Reason: "PERMISSION_DENIED"}).
+		Register(domain.ErrInvalidMessage, grpc_middleware.ErrorMapping{Code: codes.InvalidArgument, Message: "invalid message", Reason: "INVALID_MESSAGE"}).
+		Register(domain.ErrInvalidChatID, grpc_middleware.ErrorMapping{Code: codes.InvalidArgument, Message: "invalid chat ID", Reason: "INVALID_CHAT_ID"}).
+		Register(domain.ErrUsersNotFriends, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "users are not friends", Reason: "USERS_NOT_FRIENDS"}).
+		Register(domain.ErrUserBlocked, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "user is blocked", Reason: "USER_BLOCKED"})
 }
-
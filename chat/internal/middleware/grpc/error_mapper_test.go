@@ -2,7 +2,7 @@ package grpc
 
 import (
 	"context"
-	"errors"
+	"log/slog"
 	"testing"
 
 	"github.com/go-chat/chat/internal/domain"
@@ -11,8 +11,11 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func TestErrorMapperInterceptor_MapsChatNotFound(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+// testLogger discards the registry's logs of mapped and internal errors
+var testLogger = slog.New(slog.DiscardHandler)
+
+func TestErrorRegistry_MapsChatNotFound(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrChatNotFound
 	}
@@ -33,8 +36,8 @@ func TestErrorMapperInterceptor_MapsChatNotFound(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsChatAlreadyExists(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsChatAlreadyExists(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrChatAlreadyExists
 	}
@@ -55,8 +58,8 @@ func TestErrorMapperInterceptor_MapsChatAlreadyExists(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsPermissionDenied(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrPermissionDenied
 	}
@@ -77,8 +80,8 @@ func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsInvalidMessage(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsInvalidMessage(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrInvalidMessage
 	}
@@ -99,8 +102,8 @@ func TestErrorMapperInterceptor_MapsInvalidMessage(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsUsersNotFriends(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsUsersNotFriends(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrUsersNotFriends
 	}
@@ -120,45 +123,3 @@ func TestErrorMapperInterceptor_MapsUsersNotFriends(t *testing.T) {
 		t.Errorf("Expected code PermissionDenied, got: %v", st.Code())
 	}
 }
-
-func TestErrorMapperInterceptor_MapsUnknownErrorToInternal(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	unknownErr := errors.New("unknown error")
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return nil, unknownErr
-	}
-
-	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err == nil {
-		t.Fatal("Expected error, got nil")
-	}
-
-	st, ok := status.FromError(err)
-	if !ok {
-		t.Fatalf("Expected gRPC status error, got: %v", err)
-	}
-
-	if st.Code() != codes.Internal {
-		t.Errorf("Expected code Internal, got: %v", st.Code())
-	}
-}
-
-func TestErrorMapperInterceptor_PassesThroughSuccessfulResponse(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	expectedResp := "success"
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return expectedResp, nil
-	}
-
-	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err != nil {
-		t.Fatalf("Expected no error, got: %v", err)
-	}
-
-	if resp != expectedResp {
-		t.Errorf("Expected response %v, got: %v", expectedResp, resp)
-	}
-}
-
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
+	"os"
 
 	"github.com/go-chat/gateway/internal/config"
 	"github.com/go-chat/gateway/internal/server"
@@ -11,7 +13,8 @@ import (
 func main() {
 	ctx := context.Background()
 	cfg := config.New()
-	srv := server.New(cfg)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service", "gateway")
+	srv := server.New(cfg, logger)
 
 	if err := srv.Start(ctx); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
@@ -0,0 +1,158 @@
+// Package apierror renders every gateway error as one stable JSON envelope:
+//
+//	{"code": "NOT_FOUND", "message": "chat not found", "request_id": "...", "details": [...]}
+//
+// Details are the google.rpc error details attached to the backend status (BadRequest,
+// RetryInfo, ErrorInfo, ...) in their protojson form, each tagged with an "@type" URL.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// StatusClientClosedRequest is the non-standard status used when the client went away before the response
+const StatusClientClosedRequest = 499
+
+// Body is the JSON error envelope returned to clients
+type Body struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id"`
+	Details   []json.RawMessage `json:"details"`
+}
+
+// HTTPStatus maps a gRPC code to the HTTP status returned by the gateway
+func HTTPStatus(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return StatusClientClosedRequest
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorHandler is a runtime.ErrorHandlerFunc that writes backend and routing errors as a Body
+func ErrorHandler(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	// Routing errors (unknown path, wrong method) carry their own HTTP status
+	var statusErr *runtime.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		Write(w, r, statusErr.HTTPStatus, toStatus(statusErr.Err))
+		return
+	}
+
+	WriteStatus(w, r, toStatus(err))
+}
+
+// NotFoundHandler answers paths outside every mounted API with a NOT_FOUND Body
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteStatus(w, r, status.New(codes.NotFound, http.StatusText(http.StatusNotFound)))
+	})
+}
+
+// toStatus converts err to a status, keeping context cancellation and deadlines distinguishable from Unknown
+func toStatus(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err)
+	}
+	return status.Convert(err)
+}
+
+// WriteStatus writes st as a Body with the HTTP status mapped from its code
+func WriteStatus(w http.ResponseWriter, r *http.Request, st *status.Status) {
+	Write(w, r, HTTPStatus(st.Code()), st)
+}
+
+// Write writes st as a Body with an explicit HTTP status. A RetryInfo detail is
+// also surfaced as a Retry-After header so plain HTTP clients can back off
+func Write(w http.ResponseWriter, r *http.Request, httpStatus int, st *status.Status) {
+	body := Body{
+		Code:      CodeName(st.Code()),
+		Message:   st.Message(),
+		RequestID: grpc_middleware.RequestIDFromContext(r.Context()),
+		Details:   make([]json.RawMessage, 0, len(st.Proto().GetDetails())),
+	}
+
+	for _, detail := range st.Proto().GetDetails() {
+		body.Details = append(body.Details, renderDetail(detail))
+		if retry := retryAfter(detail); retry != "" {
+			w.Header().Set("Retry-After", retry)
+		}
+	}
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, `{"code":"INTERNAL","message":"failed to encode error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(buf)
+}
+
+// CodeName returns the canonical upper snake case name of a gRPC code, e.g. NOT_FOUND
+func CodeName(c codes.Code) string {
+	if name, ok := code.Code_name[int32(c)]; ok {
+		return name
+	}
+	return code.Code_UNKNOWN.String()
+}
+
+// renderDetail marshals a detail with its @type URL, falling back to the bare type URL
+// when the message type isn't linked into the gateway
+func renderDetail(detail *anypb.Any) json.RawMessage {
+	buf, err := protojson.Marshal(detail)
+	if err != nil {
+		buf, _ = json.Marshal(map[string]string{"@type": detail.GetTypeUrl()})
+	}
+	return buf
+}
+
+// retryAfter returns the Retry-After value in whole seconds for a RetryInfo detail
+func retryAfter(detail *anypb.Any) string {
+	var info errdetails.RetryInfo
+	if !detail.MessageIs(&info) || detail.UnmarshalTo(&info) != nil || info.GetRetryDelay() == nil {
+		return ""
+	}
+	seconds := math.Ceil(info.GetRetryDelay().AsDuration().Seconds())
+	return strconv.Itoa(int(math.Max(seconds, 0)))
+}
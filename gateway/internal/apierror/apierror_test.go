@@ -0,0 +1,179 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.Aborted, http.StatusConflict},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Canceled, StatusClientClosedRequest},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unknown, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := HTTPStatus(tt.code); got != tt.want {
+				t.Errorf("Expected HTTP %d for %s, got %d", tt.want, tt.code, got)
+			}
+		})
+	}
+}
+
+func serveError(t *testing.T, err error) (*httptest.ResponseRecorder, Body) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats/1", nil)
+	req = req.WithContext(grpc_middleware.ContextWithRequestID(req.Context(), "req-123"))
+	rec := httptest.NewRecorder()
+
+	ErrorHandler(context.Background(), nil, &runtime.JSONPb{}, rec, req, err)
+
+	var body Body
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body %q: %v", rec.Body.String(), err)
+	}
+	return rec, body
+}
+
+func TestErrorHandler_WritesEnvelope(t *testing.T) {
+	rec, body := serveError(t, status.Error(codes.NotFound, "chat not found"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got '%s'", ct)
+	}
+	if body.Code != "NOT_FOUND" || body.Message != "chat not found" || body.RequestID != "req-123" {
+		t.Errorf("Unexpected envelope: %+v", body)
+	}
+	if body.Details == nil || len(body.Details) != 0 {
+		t.Errorf("Expected empty details array, got %v", body.Details)
+	}
+}
+
+func TestErrorHandler_RendersFieldViolations(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "validation failed").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "nickname", Reason: "string.pattern", Description: "value does not match regex pattern"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WithDetails() failed: %v", err)
+	}
+
+	rec, body := serveError(t, st.Err())
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+	if len(body.Details) != 1 {
+		t.Fatalf("Expected 1 detail, got %d", len(body.Details))
+	}
+
+	var detail struct {
+		Type            string `json:"@type"`
+		FieldViolations []struct {
+			Field       string `json:"field"`
+			Reason      string `json:"reason"`
+			Description string `json:"description"`
+		} `json:"fieldViolations"`
+	}
+	if err := json.Unmarshal(body.Details[0], &detail); err != nil {
+		t.Fatalf("Failed to decode detail: %v", err)
+	}
+	if detail.Type != "type.googleapis.com/google.rpc.BadRequest" {
+		t.Errorf("Unexpected detail type: %s", detail.Type)
+	}
+	if len(detail.FieldViolations) != 1 || detail.FieldViolations[0].Field != "nickname" ||
+		detail.FieldViolations[0].Reason != "string.pattern" || detail.FieldViolations[0].Description == "" {
+		t.Errorf("Unexpected field violations: %+v", detail.FieldViolations)
+	}
+}
+
+func TestErrorHandler_RetryInfo_SetsRetryAfter(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(1500 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("WithDetails() failed: %v", err)
+	}
+
+	rec, body := serveError(t, st.Err())
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After '2', got '%s'", got)
+	}
+	if body.Code != "RESOURCE_EXHAUSTED" || len(body.Details) != 1 {
+		t.Errorf("Unexpected envelope: %+v", body)
+	}
+}
+
+func TestErrorHandler_RoutingError_KeepsHTTPStatus(t *testing.T) {
+	err := &runtime.HTTPStatusError{
+		HTTPStatus: http.StatusMethodNotAllowed,
+		Err:        status.Error(codes.Unimplemented, http.StatusText(http.StatusMethodNotAllowed)),
+	}
+
+	rec, body := serveError(t, err)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+	if body.Code != "UNIMPLEMENTED" {
+		t.Errorf("Expected code UNIMPLEMENTED, got %s", body.Code)
+	}
+}
+
+func TestErrorHandler_ContextDeadline_IsDeadlineExceeded(t *testing.T) {
+	rec, body := serveError(t, context.DeadlineExceeded)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", rec.Code)
+	}
+	if body.Code != "DEADLINE_EXCEEDED" {
+		t.Errorf("Expected code DEADLINE_EXCEEDED, got %s", body.Code)
+	}
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFoundHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v9/unknown", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+
+	var body Body
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "NOT_FOUND" {
+		t.Errorf("Expected NOT_FOUND envelope, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,223 @@
+// Package authn verifies the access tokens issued by the auth service and relays the
+// authenticated user to the backends. Tokens are checked against the auth service's
+// signing keys, fetched with GetPublicKeys and cached; the backends trust the relayed
+// user only because the gateway calls them with the shared service token.
+package authn
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/go-chat/gateway/internal/middleware"
+	"github.com/go-chat/lib/admin"
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authv1 "github.com/go-chat/auth/pkg/api/auth/v1"
+)
+
+// fetchTimeout bounds a single GetPublicKeys call
+const fetchTimeout = 5 * time.Second
+
+// KeySource fetches the public keys access tokens are signed with
+type KeySource func(ctx context.Context) ([]*authv1.PublicKey, error)
+
+// AuthServiceKeys fetches the keys from the auth service over conn. GetPublicKeys is a
+// service-only method, so the call is made as the gateway itself, which the relay client
+// interceptors authenticate with the service token.
+func AuthServiceKeys(conn grpc.ClientConnInterface) KeySource {
+	client := authv1.NewAuthServiceClient(conn)
+	return func(ctx context.Context) ([]*authv1.PublicKey, error) {
+		ctx = grpc_middleware.ContextWithCaller(ctx, grpc_middleware.Caller{Kind: grpc_middleware.CallerService})
+		resp, err := client.GetPublicKeys(ctx, &authv1.GetPublicKeysRequest{})
+		if err != nil {
+			return nil, err
+		}
+		return resp.GetKeys(), nil
+	}
+}
+
+// Verifier checks access tokens against the cached signing keys
+type Verifier struct {
+	source             KeySource
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	now                func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	cachedAt    map[string]time.Time
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewVerifier creates a verifier refetching the keys every refreshInterval. A token signed
+// with an unknown key triggers an earlier refetch, at most once per minRefreshInterval,
+// so rotated keys are picked up without letting bad tokens hammer the auth service.
+func NewVerifier(source KeySource, refreshInterval, minRefreshInterval time.Duration) *Verifier {
+	return &Verifier{
+		source:             source,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		now:                time.Now,
+		keys:               make(map[string]*rsa.PublicKey),
+		cachedAt:           make(map[string]time.Time),
+	}
+}
+
+// Keys lists the cached keys with when each was first fetched, for the admin listener
+func (v *Verifier) Keys() []admin.Key {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]admin.Key, 0, len(v.keys))
+	for kid := range v.keys {
+		keys = append(keys, admin.Key{KeyID: kid, Algorithm: jwt.SigningMethodRS256.Alg(), CachedAt: v.cachedAt[kid]})
+	}
+	return keys
+}
+
+// Verify checks the signature, expiry and type of an access token and returns the
+// user it was issued to
+func (v *Verifier) Verify(ctx context.Context, token string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(v.now))
+	if err != nil {
+		return "", err
+	}
+
+	if tokenType, _ := claims["type"].(string); tokenType != "access" {
+		return "", errors.New("not an access token")
+	}
+	userID, err := claims.GetSubject()
+	if err != nil || userID == "" {
+		return "", errors.New("token has no subject")
+	}
+	return userID, nil
+}
+
+// key returns the key with the given ID, refetching the keys when they are stale or the
+// ID is unknown. A failed refetch keeps the cached keys.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, known := v.keys[kid]
+	stale := now.Sub(v.fetchedAt) >= v.refreshInterval
+	if (!known || stale) && now.Sub(v.attemptedAt) >= v.minRefreshInterval {
+		v.attemptedAt = now
+		if err := v.refresh(ctx, now); err != nil {
+			log.Printf("Failed to refresh token signing keys: %v", err)
+		}
+		key, known = v.keys[kid]
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *Verifier) refresh(ctx context.Context, now time.Time) error {
+	// The fetch outlives the request that triggered it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	defer cancel()
+
+	jwks, err := v.source(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks))
+	cachedAt := make(map[string]time.Time, len(jwks))
+	for _, jwk := range jwks {
+		if jwk.GetAlg() != jwt.SigningMethodRS256.Alg() || jwk.GetUse() != "sig" {
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", jwk.GetKid(), err)
+		}
+		keys[jwk.GetKid()] = key
+		cachedAt[jwk.GetKid()] = now
+		if first, ok := v.cachedAt[jwk.GetKid()]; ok {
+			cachedAt[jwk.GetKid()] = first
+		}
+	}
+
+	v.keys = keys
+	v.cachedAt = cachedAt
+	v.fetchedAt = now
+	return nil
+}
+
+// rsaPublicKey decodes the base64url modulus and exponent of a JWK
+func rsaPublicKey(jwk *authv1.PublicKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.GetN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.GetE())
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// Middleware authenticates requests bearing an access token. The user is recorded in
+// the request log line and becomes the grpc_middleware.Caller of the request context,
+// which the relay client interceptors pass to the backends as x-user-id.
+// Requests without a token continue anonymously, so public methods still work;
+// an invalid token is rejected with 401.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			reject(w, r, "authorization must be a bearer token")
+			return
+		}
+		userID, err := v.Verify(r.Context(), token)
+		if err != nil {
+			reject(w, r, "invalid or expired access token")
+			return
+		}
+
+		middleware.SetUserID(r.Context(), userID)
+		ctx := grpc_middleware.ContextWithCaller(r.Context(), grpc_middleware.Caller{
+			Kind:   grpc_middleware.CallerUser,
+			UserID: userID,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func reject(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	apierror.WriteStatus(w, r, status.New(codes.Unauthenticated, message))
+}
@@ -0,0 +1,218 @@
+package authn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/middleware"
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/golang-jwt/jwt/v5"
+
+	authv1 "github.com/go-chat/auth/pkg/api/auth/v1"
+)
+
+// testKeys stands in for the auth service, serving its public keys and counting fetches
+type testKeys struct {
+	t       *testing.T
+	private map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newTestKeys(t *testing.T, kids ...string) *testKeys {
+	t.Helper()
+
+	k := &testKeys{t: t, private: make(map[string]*rsa.PrivateKey)}
+	for _, kid := range kids {
+		k.add(kid)
+	}
+	return k
+}
+
+func (k *testKeys) add(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		k.t.Fatalf("Failed to generate key: %v", err)
+	}
+	k.private[kid] = key
+}
+
+func (k *testKeys) source(ctx context.Context) ([]*authv1.PublicKey, error) {
+	k.fetches++
+	var keys []*authv1.PublicKey
+	for kid, key := range k.private {
+		keys = append(keys, &authv1.PublicKey{
+			Kid: kid,
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	return keys, nil
+}
+
+func (k *testKeys) sign(kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(k.private[kid])
+	if err != nil {
+		k.t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func accessClaims(userID string, expires time.Time) jwt.MapClaims {
+	return jwt.MapClaims{"sub": userID, "type": "access", "iat": time.Now().Unix(), "exp": expires.Unix()}
+}
+
+func serve(v *Verifier, authorization string) (*httptest.ResponseRecorder, grpc_middleware.Caller, bool) {
+	var caller grpc_middleware.Caller
+	var authenticated bool
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, authenticated = grpc_middleware.CallerFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, caller, authenticated
+}
+
+func TestMiddleware_ValidToken_SetsUserCaller(t *testing.T) {
+	keys := newTestKeys(t, "key-1")
+	v := NewVerifier(keys.source, time.Hour, time.Minute)
+
+	rec, caller, ok := serve(v, "Bearer "+keys.sign("key-1", accessClaims("user-1", time.Now().Add(time.Minute))))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !ok || caller.Kind != grpc_middleware.CallerUser || caller.UserID != "user-1" {
+		t.Errorf("Expected user caller user-1, got %+v (ok=%v)", caller, ok)
+	}
+}
+
+func TestMiddleware_NoToken_ContinuesAnonymously(t *testing.T) {
+	v := NewVerifier(newTestKeys(t).source, time.Hour, time.Minute)
+
+	rec, _, ok := serve(v, "")
+
+	if rec.Code != http.StatusOK || ok {
+		t.Errorf("Expected anonymous request to pass without a caller, got status %d and caller %v", rec.Code, ok)
+	}
+}
+
+func TestMiddleware_InvalidToken_Returns401(t *testing.T) {
+	keys := newTestKeys(t, "key-1")
+	other := newTestKeys(t, "key-1")
+	v := NewVerifier(keys.source, time.Hour, time.Minute)
+
+	refresh := accessClaims("user-1", time.Now().Add(time.Minute))
+	refresh["type"] = "refresh"
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"expired", "Bearer " + keys.sign("key-1", accessClaims("user-1", time.Now().Add(-time.Minute)))},
+		{"refresh token", "Bearer " + keys.sign("key-1", refresh)},
+		{"wrong signer", "Bearer " + other.sign("key-1", accessClaims("user-1", time.Now().Add(time.Minute)))},
+		{"malformed", "Bearer not-a-jwt"},
+		{"not bearer", "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _, ok := serve(v, tt.authorization)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rec.Code)
+			}
+			if ok {
+				t.Error("Expected the handler not to run")
+			}
+			if rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestVerifier_RotatedKey_Refetches(t *testing.T) {
+	keys := newTestKeys(t, "key-1")
+	v := NewVerifier(keys.source, time.Hour, time.Minute)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	if _, err := v.Verify(context.Background(), keys.sign("key-1", accessClaims("user-1", now.Add(time.Minute)))); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+
+	keys.add("key-2")
+	token := keys.sign("key-2", accessClaims("user-1", now.Add(time.Hour)))
+
+	// Unknown key IDs refetch at most once per minimum interval
+	if _, err := v.Verify(context.Background(), token); err == nil {
+		t.Error("Expected key-2 to be unknown within the minimum refresh interval")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Errorf("Expected rotated key to be fetched, got %v", err)
+	}
+	if keys.fetches != 2 {
+		t.Errorf("Expected 2 fetches, got %d", keys.fetches)
+	}
+}
+
+func TestMiddleware_ValidToken_LogsUserID(t *testing.T) {
+	keys := newTestKeys(t, "key-1")
+	v := NewVerifier(keys.source, time.Hour, time.Minute)
+	var logs bytes.Buffer
+	handler := middleware.Logging(slog.New(slog.NewJSONHandler(&logs, nil)))(v.Middleware(http.NotFoundHandler()))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set("Authorization", "Bearer "+keys.sign("key-1", accessClaims("user-1", time.Now().Add(time.Minute))))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), `"user_id":"user-1"`) {
+		t.Errorf("Expected the log line to name user-1, got %s", logs.String())
+	}
+}
+
+func TestVerifier_Keys_KeepFirstFetchTime(t *testing.T) {
+	keys := newTestKeys(t, "key-1")
+	v := NewVerifier(keys.source, time.Hour, time.Minute)
+	start := time.Now()
+	now := start
+	v.now = func() time.Time { return now }
+
+	if _, err := v.Verify(context.Background(), keys.sign("key-1", accessClaims("user-1", now.Add(time.Minute)))); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	keys.add("key-2")
+	if _, err := v.Verify(context.Background(), keys.sign("key-2", accessClaims("user-1", now.Add(time.Minute)))); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+
+	cached := make(map[string]time.Time)
+	for _, key := range v.Keys() {
+		cached[key.KeyID] = key.CachedAt
+	}
+	if !cached["key-1"].Equal(start) || !cached["key-2"].Equal(now) {
+		t.Errorf("Expected key-1 cached at %v and key-2 at %v, got %v", start, now, cached)
+	}
+}
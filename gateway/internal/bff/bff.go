@@ -0,0 +1,185 @@
+// Package bff serves composite endpoints that fan out to several backend services
+// and merge the results into one JSON document, saving clients the round trips.
+// Secondary backend failures degrade the response instead of failing it: the document
+// is returned with "partial": true and a "degraded" entry naming the missing data.
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+// Backend names reported in degraded entries
+const (
+	backendChat   = "chat"
+	backendUsers  = "users"
+	backendSocial = "social"
+)
+
+// maxFanout bounds the number of concurrent calls a single request makes to one backend
+const maxFanout = 10
+
+// MetadataAnnotator derives outgoing gRPC metadata from the HTTP request, with the same
+// signature as the annotators given to runtime.WithMetadata
+type MetadataAnnotator func(context.Context, *http.Request) metadata.MD
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithMetadata forwards metadata from the annotators to every backend call,
+// so BFF requests carry the same request ID and trace context as proxied ones
+func WithMetadata(annotators ...MetadataAnnotator) Option {
+	return func(h *Handler) {
+		h.annotators = append(h.annotators, annotators...)
+	}
+}
+
+// Handler serves the composite endpoints
+type Handler struct {
+	chat        chatv1.ChatServiceClient
+	users       usersv1.UserServiceClient
+	social      socialv1.SocialServiceClient
+	callTimeout time.Duration
+	annotators  []MetadataAnnotator
+}
+
+// New creates a handler calling the chat, users and social services over the given
+// connections, with callTimeout as the deadline for each individual backend call
+func New(chat, users, social grpc.ClientConnInterface, callTimeout time.Duration, opts ...Option) *Handler {
+	return newHandler(
+		chatv1.NewChatServiceClient(chat),
+		usersv1.NewUserServiceClient(users),
+		socialv1.NewSocialServiceClient(social),
+		callTimeout,
+		opts...,
+	)
+}
+
+func newHandler(
+	chat chatv1.ChatServiceClient,
+	users usersv1.UserServiceClient,
+	social socialv1.SocialServiceClient,
+	callTimeout time.Duration,
+	opts ...Option,
+) *Handler {
+	h := &Handler{
+		chat:        chat,
+		users:       users,
+		social:      social,
+		callTimeout: callTimeout,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Mux is the part of http.ServeMux used to mount the endpoints
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Register mounts the composite endpoints on mux
+func (h *Handler) Register(mux Mux) {
+	mux.Handle("GET /v1/inbox", http.HandlerFunc(h.Inbox))
+	mux.Handle("GET /v1/users/{id}/card", http.HandlerFunc(h.Card))
+}
+
+// outgoingContext attaches the annotators' metadata to the request context. The caller's
+// Authorization header is never forwarded: backends trust the verified user the gateway
+// relays with its service token, not the end user's bearer token
+func (h *Handler) outgoingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	mds := make([]metadata.MD, 0, len(h.annotators))
+	for _, annotate := range h.annotators {
+		mds = append(mds, annotate(ctx, r))
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.Join(mds...))
+}
+
+// authenticatedUser returns the user the gateway verified the request's access token for
+func authenticatedUser(r *http.Request) (string, bool) {
+	caller, ok := grpc_middleware.CallerFromContext(r.Context())
+	if !ok || caller.Kind != grpc_middleware.CallerUser || caller.UserID == "" {
+		return "", false
+	}
+	return caller.UserID, true
+}
+
+// call runs fn under the per-call deadline
+func (h *Handler) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, h.callTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// Degradation names data missing from a partial response and why it is missing
+type Degradation struct {
+	Backend string `json:"backend"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// degradations collects backend failures from concurrent calls, keeping one entry per backend and code
+type degradations struct {
+	mu    sync.Mutex
+	items []Degradation
+}
+
+func (d *degradations) add(backend string, err error) {
+	st := status.Convert(err)
+	item := Degradation{
+		Backend: backend,
+		Code:    apierror.CodeName(st.Code()),
+		Message: st.Message(),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, existing := range d.items {
+		if existing.Backend == item.Backend && existing.Code == item.Code {
+			return
+		}
+	}
+	d.items = append(d.items, item)
+}
+
+func (d *degradations) list() []Degradation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.items
+}
+
+// marshalProto renders a backend message exactly as the proxied endpoints do
+func marshalProto(m proto.Message) json.RawMessage {
+	buf, err := protojson.Marshal(m)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return buf
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, `{"code":"INTERNAL","message":"failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf)
+}
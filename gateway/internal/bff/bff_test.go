@@ -0,0 +1,328 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+const (
+	aliceID = "11111111-1111-1111-1111-111111111111"
+	bobID   = "22222222-2222-2222-2222-222222222222"
+	chatID  = "33333333-3333-3333-3333-333333333333"
+)
+
+type fakeChat struct {
+	chatv1.ChatServiceClient
+	listChatsErr    error
+	listMessagesErr error
+	outgoing        metadata.MD
+	userID          string
+}
+
+func (f *fakeChat) ListUserChats(ctx context.Context, req *chatv1.ListUserChatsRequest, _ ...grpc.CallOption) (*chatv1.ListUserChatsResponse, error) {
+	f.outgoing, _ = metadata.FromOutgoingContext(ctx)
+	f.userID = req.UserId
+	if f.listChatsErr != nil {
+		return nil, f.listChatsErr
+	}
+	return &chatv1.ListUserChatsResponse{
+		Chats:      []*chatv1.Chat{{ChatId: chatID, ParticipantIds: []string{aliceID, bobID}}},
+		NextCursor: "next",
+	}, nil
+}
+
+func (f *fakeChat) ListMessages(ctx context.Context, req *chatv1.ListMessagesRequest, _ ...grpc.CallOption) (*chatv1.ListMessagesResponse, error) {
+	if f.listMessagesErr != nil {
+		return nil, f.listMessagesErr
+	}
+	return &chatv1.ListMessagesResponse{
+		Messages: []*chatv1.Message{{ChatId: req.ChatId, SenderId: bobID, Text: "hi"}},
+	}, nil
+}
+
+type fakeUsers struct {
+	usersv1.UserServiceClient
+	err   error
+	delay time.Duration
+}
+
+func (f *fakeUsers) GetProfilesByIDs(ctx context.Context, req *usersv1.GetProfilesByIDsRequest, _ ...grpc.CallOption) (*usersv1.GetProfilesByIDsResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	resp := &usersv1.GetProfilesByIDsResponse{}
+	for _, id := range req.UserIds {
+		resp.Profiles = append(resp.Profiles, &usersv1.UserProfile{UserId: id, Nickname: "nick-" + id[:4]})
+	}
+	return resp, nil
+}
+
+func (f *fakeUsers) GetProfileByID(ctx context.Context, req *usersv1.GetProfileByIDRequest, _ ...grpc.CallOption) (*usersv1.GetProfileByIDResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &usersv1.GetProfileByIDResponse{Profile: &usersv1.UserProfile{UserId: req.UserId, Nickname: "bob"}}, nil
+}
+
+// wait simulates a slow backend that honours the call deadline
+func (f *fakeUsers) wait(ctx context.Context) error {
+	if f.err != nil {
+		return f.err
+	}
+	select {
+	case <-time.After(f.delay):
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+type fakeSocial struct {
+	socialv1.SocialServiceClient
+	err error
+}
+
+func (f *fakeSocial) CheckRelationship(ctx context.Context, req *socialv1.CheckRelationshipRequest, _ ...grpc.CallOption) (*socialv1.CheckRelationshipResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &socialv1.CheckRelationshipResponse{Status: socialv1.RelationshipStatus_RELATIONSHIP_STATUS_FRIEND}, nil
+}
+
+func serve(t *testing.T, h *Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveAs(t, h, target, "")
+}
+
+// serveAs serves the request as if the gateway had verified an access token for userID
+func serveAs(t *testing.T, h *Handler, target, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	h.Register(mux)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if userID != "" {
+		req.Header.Set("Authorization", "Bearer token-"+userID)
+		req = req.WithContext(grpc_middleware.ContextWithCaller(req.Context(), grpc_middleware.Caller{
+			Kind:   grpc_middleware.CallerUser,
+			UserID: userID,
+		}))
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to decode body %q: %v", rec.Body.String(), err)
+	}
+}
+
+type inboxBody struct {
+	Chats []struct {
+		Chat         map[string]interface{}   `json:"chat"`
+		Participants []map[string]interface{} `json:"participants"`
+		LastMessage  map[string]interface{}   `json:"lastMessage"`
+	} `json:"chats"`
+	NextCursor string        `json:"nextCursor"`
+	Partial    bool          `json:"partial"`
+	Degraded   []Degradation `json:"degraded"`
+}
+
+func TestInbox_AllBackendsHealthy_MergesResults(t *testing.T) {
+	chat := &fakeChat{}
+	annotate := func(context.Context, *http.Request) metadata.MD { return metadata.Pairs("x-request-id", "req-1") }
+	h := newHandler(chat, &fakeUsers{}, &fakeSocial{}, time.Second, WithMetadata(annotate))
+
+	rec := serveAs(t, h, "/v1/inbox?limit=20", aliceID)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body inboxBody
+	decode(t, rec, &body)
+
+	if body.Partial || len(body.Degraded) != 0 {
+		t.Errorf("Expected complete response, got degraded %+v", body.Degraded)
+	}
+	if body.NextCursor != "next" || len(body.Chats) != 1 {
+		t.Fatalf("Unexpected inbox: %+v", body)
+	}
+	if got := len(body.Chats[0].Participants); got != 2 {
+		t.Errorf("Expected 2 participant profiles, got %d", got)
+	}
+	if body.Chats[0].LastMessage["text"] != "hi" {
+		t.Errorf("Expected last message preview, got %v", body.Chats[0].LastMessage)
+	}
+	if got := chat.outgoing.Get("x-request-id"); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("Expected annotator metadata on backend call, got %v", chat.outgoing)
+	}
+	if got := chat.outgoing.Get("authorization"); len(got) != 0 {
+		t.Errorf("Expected end-user credentials not to be forwarded, got %v", got)
+	}
+	if chat.userID != aliceID {
+		t.Errorf("Expected chats of the authenticated user, got %q", chat.userID)
+	}
+}
+
+func TestInbox_UsersUnavailable_ReturnsPartial(t *testing.T) {
+	users := &fakeUsers{err: status.Error(codes.Unavailable, "connection refused")}
+	h := newHandler(&fakeChat{}, users, &fakeSocial{}, time.Second)
+
+	rec := serveAs(t, h, "/v1/inbox", aliceID)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var body inboxBody
+	decode(t, rec, &body)
+
+	if !body.Partial {
+		t.Error("Expected partial response")
+	}
+	if len(body.Degraded) != 1 || body.Degraded[0].Backend != "users" || body.Degraded[0].Code != "UNAVAILABLE" {
+		t.Errorf("Unexpected degraded entries: %+v", body.Degraded)
+	}
+	if len(body.Chats) != 1 || len(body.Chats[0].Participants) != 0 || body.Chats[0].LastMessage == nil {
+		t.Errorf("Expected chats and previews without profiles, got %+v", body.Chats)
+	}
+}
+
+func TestInbox_SlowBackend_HitsCallDeadline(t *testing.T) {
+	users := &fakeUsers{delay: time.Second}
+	h := newHandler(&fakeChat{}, users, &fakeSocial{}, 20*time.Millisecond)
+
+	start := time.Now()
+	rec := serveAs(t, h, "/v1/inbox", aliceID)
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected call deadline to bound the request, took %v", elapsed)
+	}
+
+	var body inboxBody
+	decode(t, rec, &body)
+
+	if len(body.Degraded) != 1 || body.Degraded[0].Code != "DEADLINE_EXCEEDED" {
+		t.Errorf("Expected deadline degradation, got %+v", body.Degraded)
+	}
+}
+
+func TestInbox_ChatFailure_ReturnsError(t *testing.T) {
+	chat := &fakeChat{listChatsErr: status.Error(codes.Unavailable, "chat down")}
+	h := newHandler(chat, &fakeUsers{}, &fakeSocial{}, time.Second)
+
+	rec := serveAs(t, h, "/v1/inbox", aliceID)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestInbox_InvalidQuery_ReturnsBadRequest(t *testing.T) {
+	h := newHandler(&fakeChat{}, &fakeUsers{}, &fakeSocial{}, time.Second)
+
+	rec := serveAs(t, h, "/v1/inbox?limit=ten", aliceID)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestInbox_Anonymous_ReturnsUnauthenticated(t *testing.T) {
+	chat := &fakeChat{}
+	h := newHandler(chat, &fakeUsers{}, &fakeSocial{}, time.Second)
+
+	rec := serve(t, h, "/v1/inbox?user_id="+aliceID)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rec.Code)
+	}
+	if chat.userID != "" {
+		t.Errorf("Expected no backend call, got chats of %q", chat.userID)
+	}
+}
+
+type cardBody struct {
+	Profile      map[string]interface{} `json:"profile"`
+	Relationship string                 `json:"relationship"`
+	Partial      bool                   `json:"partial"`
+	Degraded     []Degradation          `json:"degraded"`
+}
+
+func TestCard_WithViewer_IncludesRelationship(t *testing.T) {
+	h := newHandler(&fakeChat{}, &fakeUsers{}, &fakeSocial{}, time.Second)
+
+	rec := serveAs(t, h, "/v1/users/"+bobID+"/card", aliceID)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var body cardBody
+	decode(t, rec, &body)
+
+	if body.Profile["nickname"] != "bob" {
+		t.Errorf("Expected profile, got %v", body.Profile)
+	}
+	if body.Relationship != "RELATIONSHIP_STATUS_FRIEND" || body.Partial {
+		t.Errorf("Unexpected card: %+v", body)
+	}
+}
+
+func TestCard_Anonymous_OmitsRelationship(t *testing.T) {
+	h := newHandler(&fakeChat{}, &fakeUsers{}, &fakeSocial{}, time.Second)
+
+	rec := serve(t, h, "/v1/users/"+bobID+"/card?viewer_id="+aliceID)
+
+	var body cardBody
+	decode(t, rec, &body)
+
+	if rec.Code != http.StatusOK || body.Relationship != "" || body.Partial {
+		t.Errorf("Expected profile only for anonymous viewer, got %d %+v", rec.Code, body)
+	}
+}
+
+func TestCard_SocialFailure_ReturnsPartial(t *testing.T) {
+	social := &fakeSocial{err: status.Error(codes.Internal, "internal server error")}
+	h := newHandler(&fakeChat{}, &fakeUsers{}, social, time.Second)
+
+	rec := serveAs(t, h, "/v1/users/"+bobID+"/card", aliceID)
+
+	var body cardBody
+	decode(t, rec, &body)
+
+	if rec.Code != http.StatusOK || !body.Partial || body.Relationship != "" {
+		t.Errorf("Expected partial card without relationship, got %d %+v", rec.Code, body)
+	}
+	if len(body.Degraded) != 1 || body.Degraded[0].Backend != "social" {
+		t.Errorf("Unexpected degraded entries: %+v", body.Degraded)
+	}
+}
+
+func TestCard_ProfileNotFound_ReturnsNotFound(t *testing.T) {
+	users := &fakeUsers{err: status.Error(codes.NotFound, "profile not found")}
+	h := newHandler(&fakeChat{}, users, &fakeSocial{}, time.Second)
+
+	rec := serve(t, h, "/v1/users/"+bobID+"/card")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
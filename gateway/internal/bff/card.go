@@ -0,0 +1,84 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"google.golang.org/grpc/status"
+
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+// cardResponse is the merged profile view document
+type cardResponse struct {
+	Profile      json.RawMessage `json:"profile"`
+	Relationship string          `json:"relationship,omitempty"`
+	Partial      bool            `json:"partial"`
+	Degraded     []Degradation   `json:"degraded,omitempty"`
+}
+
+// Card serves GET /v1/users/{id}/card, combining the user's profile with the
+// authenticated viewer's relationship to them. The profile is required; the relationship
+// is best effort and only looked up for an authenticated viewer other than the user.
+func (h *Handler) Card(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	viewerID, _ := authenticatedUser(r)
+	ctx := h.outgoingContext(r)
+
+	var (
+		degraded     degradations
+		wg           sync.WaitGroup
+		profile      *usersv1.GetProfileByIDResponse
+		profileErr   error
+		relationship *socialv1.CheckRelationshipResponse
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		profileErr = h.call(ctx, func(ctx context.Context) error {
+			var err error
+			profile, err = h.users.GetProfileByID(ctx, &usersv1.GetProfileByIDRequest{UserId: userID})
+			return err
+		})
+	}()
+
+	if viewerID != "" && viewerID != userID {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := h.call(ctx, func(ctx context.Context) error {
+				var err error
+				relationship, err = h.social.CheckRelationship(ctx, &socialv1.CheckRelationshipRequest{
+					UserId:       viewerID,
+					TargetUserId: userID,
+				})
+				return err
+			})
+			if err != nil {
+				degraded.add(backendSocial, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if profileErr != nil {
+		apierror.WriteStatus(w, r, status.Convert(profileErr))
+		return
+	}
+
+	resp := cardResponse{
+		Profile:  marshalProto(profile.GetProfile()),
+		Degraded: degraded.list(),
+	}
+	resp.Partial = len(resp.Degraded) > 0
+	if relationship != nil {
+		resp.Relationship = relationship.GetStatus().String()
+	}
+
+	writeJSON(w, resp)
+}
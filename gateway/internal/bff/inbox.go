@@ -0,0 +1,183 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+// maxProfilesPerBatch mirrors the GetProfilesByIDs request limit
+const maxProfilesPerBatch = 100
+
+// inboxResponse is the merged chat list document
+type inboxResponse struct {
+	Chats      []inboxChat   `json:"chats"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+	Partial    bool          `json:"partial"`
+	Degraded   []Degradation `json:"degraded,omitempty"`
+}
+
+// inboxChat is one chat with participant profiles and the latest message preview
+type inboxChat struct {
+	Chat         json.RawMessage   `json:"chat"`
+	Participants []json.RawMessage `json:"participants"`
+	LastMessage  json.RawMessage   `json:"lastMessage,omitempty"`
+}
+
+// Inbox serves GET /v1/inbox?cursor=&limit= for the authenticated user, combining
+// ListUserChats with participant profiles from users and one message preview per chat
+// from chat. The chat list itself is required; profiles and previews are best effort.
+func (h *Handler) Inbox(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUser(r)
+	if !ok {
+		apierror.WriteStatus(w, r, status.New(codes.Unauthenticated, "authentication required"))
+		return
+	}
+
+	query := r.URL.Query()
+	req := &chatv1.ListUserChatsRequest{
+		UserId: userID,
+		Cursor: query.Get("cursor"),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			apierror.WriteStatus(w, r, status.New(codes.InvalidArgument, "limit must be an integer"))
+			return
+		}
+		req.Limit = int32(limit)
+	}
+
+	ctx := h.outgoingContext(r)
+
+	var chats *chatv1.ListUserChatsResponse
+	err := h.call(ctx, func(ctx context.Context) error {
+		var err error
+		chats, err = h.chat.ListUserChats(ctx, req)
+		return err
+	})
+	if err != nil {
+		apierror.WriteStatus(w, r, status.Convert(err))
+		return
+	}
+
+	var (
+		degraded    degradations
+		wg          sync.WaitGroup
+		profiles    map[string]*usersv1.UserProfile
+		lastMessage = make([]*chatv1.Message, len(chats.GetChats()))
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		profiles = h.fetchProfiles(ctx, participantIDs(chats.GetChats()), &degraded)
+	}()
+
+	sem := make(chan struct{}, maxFanout)
+	for i, chat := range chats.GetChats() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := h.call(ctx, func(ctx context.Context) error {
+				resp, err := h.chat.ListMessages(ctx, &chatv1.ListMessagesRequest{ChatId: chat.GetChatId(), Limit: 1})
+				if err == nil && len(resp.GetMessages()) > 0 {
+					lastMessage[i] = resp.GetMessages()[0]
+				}
+				return err
+			})
+			if err != nil {
+				degraded.add(backendChat, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp := inboxResponse{
+		Chats:      make([]inboxChat, 0, len(chats.GetChats())),
+		NextCursor: chats.GetNextCursor(),
+		Degraded:   degraded.list(),
+	}
+	resp.Partial = len(resp.Degraded) > 0
+
+	for i, chat := range chats.GetChats() {
+		item := inboxChat{
+			Chat:         marshalProto(chat),
+			Participants: make([]json.RawMessage, 0, len(chat.GetParticipantIds())),
+		}
+		for _, id := range chat.GetParticipantIds() {
+			if profile, ok := profiles[id]; ok {
+				item.Participants = append(item.Participants, marshalProto(profile))
+			}
+		}
+		if lastMessage[i] != nil {
+			item.LastMessage = marshalProto(lastMessage[i])
+		}
+		resp.Chats = append(resp.Chats, item)
+	}
+
+	writeJSON(w, resp)
+}
+
+// fetchProfiles looks up profiles in batches the users service accepts, concurrently
+func (h *Handler) fetchProfiles(ctx context.Context, ids []string, degraded *degradations) map[string]*usersv1.UserProfile {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		profiles = make(map[string]*usersv1.UserProfile, len(ids))
+	)
+
+	for start := 0; start < len(ids); start += maxProfilesPerBatch {
+		batch := ids[start:min(start+maxProfilesPerBatch, len(ids))]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := h.call(ctx, func(ctx context.Context) error {
+				resp, err := h.users.GetProfilesByIDs(ctx, &usersv1.GetProfilesByIDsRequest{UserIds: batch})
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, profile := range resp.GetProfiles() {
+					profiles[profile.GetUserId()] = profile
+				}
+				return nil
+			})
+			if err != nil {
+				degraded.add(backendUsers, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return profiles
+}
+
+// participantIDs returns the distinct participants across chats, in first-seen order
+func participantIDs(chats []*chatv1.Chat) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, chat := range chats {
+		for _, id := range chat.GetParticipantIds() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
@@ -1,9 +1,37 @@
 package config
 
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chat/lib/admin"
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/go-chat/lib/telemetry"
+)
+
 // Config holds the gateway configuration
 type Config struct {
-	HTTPPort string
-	Services ServiceAddresses
+	HTTPPort    string
+	Server      ServerConfig
+	Services    ServiceAddresses
+	Resilience  ResilienceConfig
+	API         APIConfig
+	Cache       CacheConfig
+	Idempotency IdempotencyConfig
+	CORS        CORSConfig
+	Health      HealthConfig
+	BFF         BFFConfig
+	GraphQL     GraphQLConfig
+	Auth        AuthConfig
+	// Telemetry is read from the standard OTEL_* variables and METRICS_ADDR
+	Telemetry telemetry.Config
+	// AdminAddr is the internal listener for pprof and debug endpoints, read from ADMIN_ADDR.
+	// It defaults to localhost and must never be exposed publicly; empty disables it.
+	AdminAddr string
 }
 
 // ServiceAddresses contains addresses of backend gRPC services
@@ -15,16 +43,328 @@ type ServiceAddresses struct {
 	Notifications string
 }
 
-// New creates a new Config with default values
+// ServerConfig hardens the public HTTP listener
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// MaxBodyBytes caps request bodies on routes without a more specific limit
+	MaxBodyBytes int64
+	// BodyLimits overrides MaxBodyBytes for paths matching a path.Match pattern.
+	// The first matching pattern wins.
+	BodyLimits []BodyLimit
+
+	// H2C enables HTTP/2 over cleartext, for deployments where a proxy in front terminates TLS
+	H2C bool
+	// TLSCertFile and TLSKeyFile enable TLS termination when both are set.
+	// The files are re-read when they change, so certificates can be rotated without a restart.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSReloadInterval time.Duration
+}
+
+// BodyLimit caps request bodies for paths matching Pattern, e.g. "/v1/chats/*/messages"
+type BodyLimit struct {
+	Pattern  string
+	MaxBytes int64
+}
+
+// BackendTimeouts contains the default deadline for calls to each backend service.
+// A caller's shorter deadline still wins.
+type BackendTimeouts struct {
+	Auth          time.Duration
+	Users         time.Duration
+	Chat          time.Duration
+	Social        time.Duration
+	Notifications time.Duration
+}
+
+// ResilienceConfig controls retries, deadlines and circuit breaking for backend calls
+type ResilienceConfig struct {
+	Timeouts BackendTimeouts
+	// RetryMaxAttempts bounds attempts (including the first) for idempotent, GET-mapped RPCs.
+	// Values below 2 disable retries.
+	RetryMaxAttempts int
+	// BreakerFailureThreshold is the number of consecutive backend failures that opens a circuit
+	BreakerFailureThreshold int
+	// BreakerOpenTimeout is how long an open circuit fails fast before letting a probe through
+	BreakerOpenTimeout time.Duration
+}
+
+// APIConfig controls the lifecycle of API routes
+type APIConfig struct {
+	// Deprecations lists routes that still work but are scheduled for removal.
+	// Set as a JSON array in GATEWAY_DEPRECATED_ROUTES.
+	Deprecations []Deprecation
+	// PublicMethods lists full gRPC method names clients may call over gRPC-Web, Connect
+	// and GraphQL besides those with a google.api.http binding, for streaming RPCs that
+	// have no REST form. Set as a comma-separated list in GATEWAY_PUBLIC_METHODS.
+	PublicMethods []string
+}
+
+// Deprecation marks requests matching Method and Pattern as deprecated, e.g.
+//
+//	{"method": "GET", "pattern": "/v1/profile/*", "deprecated_at": "2026-01-01T00:00:00Z",
+//	 "sunset": "2026-07-01T00:00:00Z", "link": "https://docs.example.com/migrate-to-v2"}
+type Deprecation struct {
+	// Method is matched exactly; empty matches any method
+	Method string `json:"method"`
+	// Pattern is a path.Match pattern; the first matching deprecation wins
+	Pattern      string    `json:"pattern"`
+	DeprecatedAt time.Time `json:"deprecated_at"`
+	// Sunset is when the route will stop working; zero if not yet scheduled
+	Sunset time.Time `json:"sunset"`
+	// Link points to migration documentation
+	Link string `json:"link"`
+}
+
+// CacheConfig controls the optional in-process cache for read-heavy GET routes.
+// Entries are keyed by path, query and caller, so users never see each other's responses.
+type CacheConfig struct {
+	Enabled    bool
+	TTL        time.Duration
+	MaxEntries int
+	Routes     []CacheRoute
+	// ETagRoutes are the path.Match patterns of GET routes that get an ETag and
+	// answer If-None-Match; they are validated whether or not the cache is enabled
+	ETagRoutes []string
+}
+
+// CacheRoute whitelists GET paths matching a path.Match Pattern for caching.
+// A successful request matching any InvalidatedBy entry ("METHOD pattern") drops the route's entries.
+type CacheRoute struct {
+	Pattern       string   `json:"pattern"`
+	InvalidatedBy []string `json:"invalidated_by"`
+}
+
+// IdempotencyConfig controls how long responses to POST requests carrying an
+// Idempotency-Key header are kept for replay
+type IdempotencyConfig struct {
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// CORSConfig controls which browser origins may call the gateway.
+// Origins are matched exactly, or by subdomain when written as "https://*.example.com".
+// A single "*" allows any other origin without credentials: AllowCredentials only
+// applies to origins listed explicitly.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+
+	// Routes overrides AllowedMethods for paths starting with a given prefix.
+	// The longest matching prefix wins.
+	Routes []CORSRoute
+}
+
+// CORSRoute restricts the methods allowed for paths under PathPrefix
+type CORSRoute struct {
+	PathPrefix string
+	Methods    []string
+}
+
+// HealthConfig controls how the readiness endpoint probes backend services
+type HealthConfig struct {
+	// CacheTTL is how long an aggregated backend health result is reused
+	CacheTTL time.Duration
+	// ProbeTimeout bounds a single round of backend health checks
+	ProbeTimeout time.Duration
+}
+
+// BFFConfig controls the composite endpoints that fan out to several backends
+type BFFConfig struct {
+	// CallTimeout is the deadline for each individual backend call
+	CallTimeout time.Duration
+}
+
+// GraphQLConfig controls the /graphql endpoint
+type GraphQLConfig struct {
+	// CallTimeout is the deadline for each unary backend call; subscriptions have none
+	CallTimeout time.Duration
+	// MaxDepth and MaxFields bound how deeply a document may nest selections and how
+	// many fields it may select once fragments are expanded
+	MaxDepth  int
+	MaxFields int
+}
+
+// AuthConfig controls how the gateway authenticates users and itself to the backends
+type AuthConfig struct {
+	// ServiceToken is the shared secret from SERVICE_TOKEN sent with every backend call;
+	// the backends trust the relayed user only alongside it
+	ServiceToken string
+	// KeysRefreshInterval is how often the auth service's token signing keys are refetched.
+	// A token signed with an unknown key triggers an earlier refetch, at most once per
+	// KeysMinRefreshInterval.
+	KeysRefreshInterval    time.Duration
+	KeysMinRefreshInterval time.Duration
+}
+
+// New creates a new Config with default values, overridden by environment variables where set
 func New() *Config {
 	return &Config{
-		HTTPPort: ":8080",
+		HTTPPort: getEnv("GATEWAY_HTTP_PORT", ":8080"),
+		Server: ServerConfig{
+			ReadTimeout:       getEnvDuration("GATEWAY_READ_TIMEOUT", 15*time.Second),
+			ReadHeaderTimeout: getEnvDuration("GATEWAY_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      getEnvDuration("GATEWAY_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       getEnvDuration("GATEWAY_IDLE_TIMEOUT", 2*time.Minute),
+			MaxHeaderBytes:    getEnvInt("GATEWAY_MAX_HEADER_BYTES", 64<<10),
+			MaxBodyBytes:      int64(getEnvInt("GATEWAY_MAX_BODY_BYTES", 1<<20)),
+			BodyLimits: []BodyLimit{
+				{Pattern: "/v1/chats/*/messages", MaxBytes: int64(getEnvInt("GATEWAY_MAX_MESSAGE_BODY_BYTES", 64<<10))},
+			},
+			H2C:               getEnvBool("GATEWAY_H2C", false),
+			TLSCertFile:       getEnv("GATEWAY_TLS_CERT_FILE", ""),
+			TLSKeyFile:        getEnv("GATEWAY_TLS_KEY_FILE", ""),
+			TLSReloadInterval: getEnvDuration("GATEWAY_TLS_RELOAD_INTERVAL", time.Minute),
+		},
 		Services: ServiceAddresses{
-			Auth:          "auth:8080",
-			Users:         "users:8080",
-			Chat:          "chat:8080",
-			Social:        "social:8080",
-			Notifications: "notifications:8080",
+			Auth:          getEnv("GATEWAY_AUTH_ADDR", "auth:8080"),
+			Users:         getEnv("GATEWAY_USERS_ADDR", "users:8080"),
+			Chat:          getEnv("GATEWAY_CHAT_ADDR", "chat:8080"),
+			Social:        getEnv("GATEWAY_SOCIAL_ADDR", "social:8080"),
+			Notifications: getEnv("GATEWAY_NOTIFICATIONS_ADDR", "notifications:8080"),
+		},
+		Resilience: ResilienceConfig{
+			Timeouts: BackendTimeouts{
+				Auth:          getEnvDuration("GATEWAY_AUTH_TIMEOUT", 5*time.Second),
+				Users:         getEnvDuration("GATEWAY_USERS_TIMEOUT", 5*time.Second),
+				Chat:          getEnvDuration("GATEWAY_CHAT_TIMEOUT", 5*time.Second),
+				Social:        getEnvDuration("GATEWAY_SOCIAL_TIMEOUT", 5*time.Second),
+				Notifications: getEnvDuration("GATEWAY_NOTIFICATIONS_TIMEOUT", 5*time.Second),
+			},
+			RetryMaxAttempts:        getEnvInt("GATEWAY_RETRY_MAX_ATTEMPTS", 3),
+			BreakerFailureThreshold: getEnvInt("GATEWAY_BREAKER_FAILURE_THRESHOLD", 5),
+			BreakerOpenTimeout:      getEnvDuration("GATEWAY_BREAKER_OPEN_TIMEOUT", 10*time.Second),
+		},
+		API: APIConfig{
+			Deprecations:  getEnvJSON("GATEWAY_DEPRECATED_ROUTES", []Deprecation(nil)),
+			PublicMethods: getEnvList("GATEWAY_PUBLIC_METHODS", []string{"/api.chat.v1.ChatService/StreamMessages"}),
+		},
+		Cache: CacheConfig{
+			Enabled:    getEnvBool("GATEWAY_CACHE_ENABLED", false),
+			TTL:        getEnvDuration("GATEWAY_CACHE_TTL", 5*time.Second),
+			MaxEntries: getEnvInt("GATEWAY_CACHE_MAX_ENTRIES", 10000),
+			Routes: getEnvJSON("GATEWAY_CACHE_ROUTES", []CacheRoute{
+				{Pattern: "/v1/profile/*", InvalidatedBy: []string{"POST /v1/profile", "PUT /v1/profile"}},
+				{Pattern: "/v1/profile/by-nickname/*", InvalidatedBy: []string{"POST /v1/profile", "PUT /v1/profile"}},
+			}),
+			ETagRoutes: getEnvList("GATEWAY_ETAG_ROUTES", []string{"/v1/profile/*", "/v1/profile/by-nickname/*"}),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:        getEnvDuration("GATEWAY_IDEMPOTENCY_TTL", 24*time.Hour),
+			MaxEntries: getEnvInt("GATEWAY_IDEMPOTENCY_MAX_ENTRIES", 100000),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvList("GATEWAY_CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+			AllowedMethods: getEnvList("GATEWAY_CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE"}),
+			AllowedHeaders: getEnvList("GATEWAY_CORS_ALLOWED_HEADERS", []string{
+				"Content-Type", "Authorization", "X-Request-ID", "If-None-Match", "Idempotency-Key",
+				"X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Connect-Protocol-Version", "Connect-Timeout-Ms",
+			}),
+			ExposedHeaders: getEnvList("GATEWAY_CORS_EXPOSED_HEADERS", []string{
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Request-ID",
+				"Deprecation", "Sunset", "Link", "ETag", "Idempotent-Replayed",
+				"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin",
+			}),
+			AllowCredentials: getEnvBool("GATEWAY_CORS_ALLOW_CREDENTIALS", true),
+			MaxAge:           getEnvDuration("GATEWAY_CORS_MAX_AGE", 10*time.Minute),
+			Routes: []CORSRoute{
+				{PathPrefix: "/v1/auth/", Methods: []string{"POST"}},
+				{PathPrefix: "/v1/notifications", Methods: []string{"GET", "POST"}},
+			},
+		},
+		Health: HealthConfig{
+			CacheTTL:     getEnvDuration("GATEWAY_HEALTH_CACHE_TTL", 2*time.Second),
+			ProbeTimeout: getEnvDuration("GATEWAY_HEALTH_PROBE_TIMEOUT", time.Second),
+		},
+		BFF: BFFConfig{
+			CallTimeout: getEnvDuration("GATEWAY_BFF_CALL_TIMEOUT", 2*time.Second),
 		},
+		GraphQL: GraphQLConfig{
+			CallTimeout: getEnvDuration("GATEWAY_GRAPHQL_CALL_TIMEOUT", 2*time.Second),
+			MaxDepth:    getEnvInt("GATEWAY_GRAPHQL_MAX_DEPTH", 12),
+			MaxFields:   getEnvInt("GATEWAY_GRAPHQL_MAX_FIELDS", 500),
+		},
+		Auth: AuthConfig{
+			ServiceToken:           grpc_middleware.ServiceTokenFromEnv(),
+			KeysRefreshInterval:    getEnvDuration("GATEWAY_KEYS_REFRESH_INTERVAL", 10*time.Minute),
+			KeysMinRefreshInterval: getEnvDuration("GATEWAY_KEYS_MIN_REFRESH_INTERVAL", 30*time.Second),
+		},
+		Telemetry: telemetry.ConfigFromEnv("gateway"),
+		AdminAddr: admin.AddrFromEnv(),
+	}
+}
+
+// getEnv returns the value of the environment variable or the fallback if unset
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+// getEnvList parses a comma-separated environment variable, dropping empty items
+func getEnvList(key string, fallback []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvBool parses a boolean environment variable, falling back on unset or invalid values
+func getEnvBool(key string, fallback bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// getEnvJSON decodes a JSON environment variable, falling back on unset or invalid values
+func getEnvJSON[T any](key string, fallback T) T {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	var parsed T
+	if err := json.Unmarshal([]byte(v), &parsed); err != nil {
+		log.Printf("Ignoring invalid %s: %v", key, err)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvInt parses an integer environment variable, falling back on unset or invalid values
+func getEnvInt(key string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// getEnvDuration parses a duration environment variable such as "30s", falling back on unset or invalid values
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
 	}
+	return d
 }
@@ -0,0 +1,186 @@
+// Package docs serves the OpenAPI spec of every backend service, merged into one
+// document, and Swagger UI under /docs. The specs are generated by protoc-gen-openapiv2
+// into specs/ (make proto-gen) and embedded at build time, so the docs always describe
+// the routes of the running gateway build.
+package docs
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime/debug"
+	"sort"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed specs/*.swagger.json
+var specs embed.FS
+
+// publicSpecs lists the specs whose operations are called without an access token
+var publicSpecs = map[string]bool{
+	"auth.swagger.json": true,
+}
+
+// securityScheme is the name of the bearer JWT scheme in the merged spec
+const securityScheme = "bearer"
+
+// initializer replaces the Swagger UI distribution's default, which loads the petstore example
+const initializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "./openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    persistAuthorization: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// Mux is the part of http.ServeMux used to mount the endpoints
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Handler serves the merged spec and Swagger UI
+type Handler struct {
+	spec []byte
+}
+
+// New merges the embedded specs
+func New() (*Handler, error) {
+	files, err := fs.Glob(specs, "specs/*.swagger.json")
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(map[string][]byte, len(files))
+	for _, file := range files {
+		buf, err := specs.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		docs[path.Base(file)] = buf
+	}
+
+	spec, err := merge(docs, buildVersion())
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{spec: spec}, nil
+}
+
+// Register mounts the spec at /docs/openapi.json and Swagger UI at /docs/
+func (h *Handler) Register(mux Mux) {
+	mux.Handle("GET /docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently))
+	mux.Handle("GET /docs/openapi.json", http.HandlerFunc(h.serveSpec))
+	mux.Handle("GET /docs/swagger-initializer.js", http.HandlerFunc(serveInitializer))
+	mux.Handle("GET /docs/", http.StripPrefix("/docs/", http.FileServerFS(swaggerFiles.FS)))
+}
+
+func (h *Handler) serveSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(h.spec)
+}
+
+func serveInitializer(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	_, _ = w.Write([]byte(initializer))
+}
+
+// swagger is the subset of a Swagger 2.0 document that is merged; other top-level
+// fields of the service specs are dropped
+type swagger struct {
+	Swagger             string                            `json:"swagger"`
+	Info                map[string]interface{}            `json:"info"`
+	Tags                []map[string]interface{}          `json:"tags,omitempty"`
+	Consumes            []string                          `json:"consumes,omitempty"`
+	Produces            []string                          `json:"produces,omitempty"`
+	SecurityDefinitions map[string]interface{}            `json:"securityDefinitions,omitempty"`
+	Security            []map[string][]string             `json:"security,omitempty"`
+	Paths               map[string]map[string]interface{} `json:"paths"`
+	Definitions         map[string]interface{}            `json:"definitions"`
+}
+
+// merge combines the service specs, keyed by file name, into one document that requires
+// a bearer JWT on every operation except those of publicSpecs. The same path in two
+// specs, or the same definition name with different schemas, is an error.
+func merge(docs map[string][]byte, version string) ([]byte, error) {
+	merged := swagger{
+		Swagger:  "2.0",
+		Info:     map[string]interface{}{"title": "go-chat API", "version": version},
+		Consumes: []string{"application/json"},
+		Produces: []string{"application/json"},
+		SecurityDefinitions: map[string]interface{}{
+			securityScheme: map[string]interface{}{
+				"type":        "apiKey",
+				"name":        "Authorization",
+				"in":          "header",
+				"description": `JWT access token from /v1/auth/login, sent as "Bearer <token>"`,
+			},
+		},
+		Security:    []map[string][]string{{securityScheme: {}}},
+		Paths:       make(map[string]map[string]interface{}),
+		Definitions: make(map[string]interface{}),
+	}
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var doc swagger
+		if err := json.Unmarshal(docs[name], &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		merged.Tags = append(merged.Tags, doc.Tags...)
+		for p, item := range doc.Paths {
+			if _, ok := merged.Paths[p]; ok {
+				return nil, fmt.Errorf("path %s in %s is defined by another spec", p, name)
+			}
+			if publicSpecs[name] {
+				// An empty requirement overrides the document-wide one
+				for _, op := range item {
+					if op, ok := op.(map[string]interface{}); ok {
+						op["security"] = []interface{}{}
+					}
+				}
+			}
+			merged.Paths[p] = item
+		}
+		for def, schema := range doc.Definitions {
+			if existing, ok := merged.Definitions[def]; ok && !reflect.DeepEqual(existing, schema) {
+				return nil, fmt.Errorf("definition %s in %s conflicts with another spec", def, name)
+			}
+			merged.Definitions[def] = schema
+		}
+	}
+
+	return json.Marshal(merged)
+}
+
+// buildVersion identifies the gateway build in the spec, preferring the VCS revision
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			return setting.Value[:12]
+		}
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
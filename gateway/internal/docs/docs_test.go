@@ -0,0 +1,113 @@
+package docs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const authSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "api/auth/v1/messages.proto", "version": "version not set"},
+  "tags": [{"name": "AuthService"}],
+  "paths": {"/v1/auth/login": {"post": {"operationId": "AuthService_Login"}}},
+  "definitions": {"rpcStatus": {"type": "object"}}
+}`
+
+const chatSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "api/chat/v1/messages.proto", "version": "version not set"},
+  "tags": [{"name": "ChatService"}],
+  "paths": {"/v1/chats/{chat_id}": {"get": {"operationId": "ChatService_GetChat"}}},
+  "definitions": {"rpcStatus": {"type": "object"}, "v1Chat": {"type": "object"}}
+}`
+
+func TestMerge_CombinesSpecsWithBearerSecurity(t *testing.T) {
+	buf, err := merge(map[string][]byte{"auth.swagger.json": []byte(authSpec), "chat.swagger.json": []byte(chatSpec)}, "abc123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var spec swagger
+	if err := json.Unmarshal(buf, &spec); err != nil {
+		t.Fatalf("Failed to decode merged spec: %v", err)
+	}
+	if spec.Info["version"] != "abc123" {
+		t.Errorf("Expected build version in info, got %v", spec.Info)
+	}
+	if len(spec.Paths) != 2 || len(spec.Definitions) != 2 || len(spec.Tags) != 2 {
+		t.Errorf("Expected 2 paths, 2 definitions and 2 tags, got %d, %d and %d", len(spec.Paths), len(spec.Definitions), len(spec.Tags))
+	}
+	if _, ok := spec.SecurityDefinitions[securityScheme]; !ok || len(spec.Security) != 1 {
+		t.Errorf("Expected document-wide bearer security, got %v and %v", spec.SecurityDefinitions, spec.Security)
+	}
+
+	login := spec.Paths["/v1/auth/login"]["post"].(map[string]interface{})
+	if security, ok := login["security"].([]interface{}); !ok || len(security) != 0 {
+		t.Errorf("Expected login to override security with an empty list, got %v", login["security"])
+	}
+	getChat := spec.Paths["/v1/chats/{chat_id}"]["get"].(map[string]interface{})
+	if _, ok := getChat["security"]; ok {
+		t.Errorf("Expected chat operation to inherit bearer security, got %v", getChat["security"])
+	}
+}
+
+func TestMerge_Conflicts_ReturnError(t *testing.T) {
+	tests := []struct {
+		name  string
+		other string
+	}{
+		{"duplicate path", `{"paths": {"/v1/auth/login": {"post": {}}}}`},
+		{"conflicting definition", `{"definitions": {"rpcStatus": {"type": "string"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := merge(map[string][]byte{"auth.swagger.json": []byte(authSpec), "other.swagger.json": []byte(tt.other)}, "dev")
+			if err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestHandler_ServesSpecAndUI(t *testing.T) {
+	h, err := New()
+	if err != nil {
+		t.Fatalf("Failed to load embedded specs: %v", err)
+	}
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	tests := []struct {
+		path        string
+		status      int
+		contains    string
+		contentType string
+	}{
+		{"/docs", http.StatusMovedPermanently, "", ""},
+		{"/docs/", http.StatusOK, "swagger-ui", "text/html"},
+		{"/docs/openapi.json", http.StatusOK, `"/v1/chats"`, "application/json"},
+		{"/docs/swagger-initializer.js", http.StatusOK, "./openapi.json", "text/javascript"},
+		{"/docs/swagger-ui-bundle.js", http.StatusOK, "", "text/javascript"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("Expected body to contain %q", tt.contains)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Expected content type %s, got '%s'", tt.contentType, ct)
+			}
+		})
+	}
+}
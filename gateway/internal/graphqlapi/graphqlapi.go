@@ -0,0 +1,398 @@
+// Package graphqlapi serves a GraphQL endpoint over the backend gRPC services.
+// The schema is derived from the service descriptors: read RPCs become queries, the
+// rest mutations and server-streaming RPCs subscriptions, delivered over server-sent
+// events (the graphql-sse protocol). Relations such as Chat.participants join data across
+// services, with profile lookups batched onto GetProfilesByIDs.
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	notificationsv1 "github.com/go-chat/notifications/pkg/api/notifications/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+const (
+	// maxFanout bounds the number of concurrent backend calls a single request makes
+	maxFanout = 10
+
+	defaultMaxDepth  = 12
+	defaultMaxFields = 500
+)
+
+// MetadataAnnotator derives outgoing gRPC metadata from the HTTP request, with the same
+// signature as the annotators given to runtime.WithMetadata
+type MetadataAnnotator func(context.Context, *http.Request) metadata.MD
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithMetadata forwards metadata from the annotators to every backend call,
+// so GraphQL requests carry the same request ID and trace context as proxied ones
+func WithMetadata(annotators ...MetadataAnnotator) Option {
+	return func(h *Handler) {
+		h.annotators = append(h.annotators, annotators...)
+	}
+}
+
+// WithPublicMethods exposes the listed RPCs (/package.Service/Method) although they have
+// no google.api.http binding, such as streaming RPCs without a REST form
+func WithPublicMethods(methods ...string) Option {
+	return func(h *Handler) {
+		h.publicMethods = append(h.publicMethods, methods...)
+	}
+}
+
+// WithLimits rejects documents nesting selections deeper than maxDepth or selecting
+// more than maxFields fields once fragments are expanded
+func WithLimits(maxDepth, maxFields int) Option {
+	return func(h *Handler) {
+		h.maxDepth = maxDepth
+		h.maxFields = maxFields
+	}
+}
+
+// Handler serves GraphQL requests
+type Handler struct {
+	schema        graphql.Schema
+	users         usersv1.UserServiceClient
+	social        socialv1.SocialServiceClient
+	callTimeout   time.Duration
+	annotators    []MetadataAnnotator
+	publicMethods []string
+	maxDepth      int
+	maxFields     int
+}
+
+// New creates a handler exposing the public RPCs of the chat, users, social and
+// notifications services, with callTimeout as the deadline for each unary backend call
+func New(chat, users, social, notifications grpc.ClientConnInterface, callTimeout time.Duration, opts ...Option) (*Handler, error) {
+	h := &Handler{
+		users:       usersv1.NewUserServiceClient(users),
+		social:      socialv1.NewSocialServiceClient(social),
+		callTimeout: callTimeout,
+		maxDepth:    defaultMaxDepth,
+		maxFields:   defaultMaxFields,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	b := newSchemaBuilder(h)
+	b.addRelations()
+	schema, err := b.build([]backendService{
+		{name: chatv1.ChatService_ServiceDesc.ServiceName, conn: chat},
+		{name: usersv1.UserService_ServiceDesc.ServiceName, conn: users},
+		{name: socialv1.SocialService_ServiceDesc.ServiceName, conn: social},
+		{name: notificationsv1.NotificationService_ServiceDesc.ServiceName, conn: notifications},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// Mux is the part of http.ServeMux used to mount the endpoint
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Register mounts the endpoint on mux
+func (h *Handler) Register(mux Mux) {
+	mux.Handle("POST /graphql", h)
+}
+
+// request is a GraphQL-over-HTTP request body
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes a query or mutation and returns the result as JSON, or streams
+// a subscription's results as server-sent events
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteStatus(w, r, status.New(codes.InvalidArgument, "invalid GraphQL request body"))
+		return
+	}
+	if req.Query == "" {
+		apierror.WriteStatus(w, r, status.New(codes.InvalidArgument, "query is required"))
+		return
+	}
+	if err := h.checkComplexity(req.Query); err != nil {
+		apierror.WriteStatus(w, r, status.Convert(err))
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withRequestState(h.outgoingContext(r), h),
+	}
+
+	if isSubscription(req.Query, req.OperationName) {
+		h.serveSubscription(w, r, params)
+		return
+	}
+
+	result := graphql.Do(params)
+	annotateErrors(result.Errors)
+	writeJSON(w, result)
+}
+
+// serveSubscription streams results as graphql-sse "next" events until the backend
+// stream ends or the client goes away
+func (h *Handler) serveSubscription(w http.ResponseWriter, r *http.Request, params graphql.Params) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		apierror.Write(w, r, http.StatusNotAcceptable, status.New(codes.InvalidArgument,
+			"subscriptions are delivered as server-sent events; send Accept: text/event-stream"))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	// Drain until the channel closes, which happens once the request context is done
+	for result := range graphql.Subscribe(params) {
+		annotateErrors(result.Errors)
+		buf, err := json.Marshal(result)
+		if err != nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "event: next\ndata: %s\n\n", buf)
+		_ = rc.Flush()
+	}
+	_, _ = fmt.Fprint(w, "event: complete\ndata:\n\n")
+	_ = rc.Flush()
+}
+
+// outgoingContext attaches the annotators' metadata to the request context; the caller's
+// Authorization header stays at the gateway, which relays the verified user instead
+func (h *Handler) outgoingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	mds := make([]metadata.MD, 0, len(h.annotators))
+	for _, annotate := range h.annotators {
+		mds = append(mds, annotate(ctx, r))
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.Join(mds...))
+}
+
+// call runs fn under the per-call deadline
+func (h *Handler) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, h.callTimeout)
+	defer cancel()
+	if err := fn(ctx); err != nil {
+		return &statusError{st: status.Convert(err)}
+	}
+	return nil
+}
+
+// statusError reports a backend failure by its message alone;
+// annotateErrors puts the code in the error's extensions
+type statusError struct {
+	st *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.st.Message()
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.st
+}
+
+// annotateErrors adds the gRPC code of backend failures to the errors' extensions,
+// using the same code names as the REST error envelope. Validation failures also get
+// their field violations, so clients can highlight the invalid arguments
+func annotateErrors(errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		st, ok := backendStatus(errs[i].OriginalError())
+		if !ok {
+			continue
+		}
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = make(map[string]interface{})
+		}
+		errs[i].Extensions["code"] = apierror.CodeName(st.Code())
+		if violations := fieldViolations(st); len(violations) > 0 {
+			errs[i].Extensions["fieldViolations"] = violations
+		}
+	}
+}
+
+// fieldViolation mirrors google.rpc.BadRequest.FieldViolation as rendered in the REST envelope
+type fieldViolation struct {
+	Field       string `json:"field"`
+	Reason      string `json:"reason,omitempty"`
+	Description string `json:"description"`
+}
+
+func fieldViolations(st *status.Status) []fieldViolation {
+	var violations []fieldViolation
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, v := range badRequest.GetFieldViolations() {
+			violations = append(violations, fieldViolation{Field: v.GetField(), Reason: v.GetReason(), Description: v.GetDescription()})
+		}
+	}
+	return violations
+}
+
+// backendStatus unwraps the layers the executor puts around a resolver's error
+func backendStatus(err error) (*status.Status, bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return status.FromError(err)
+		}
+	}
+	return nil, false
+}
+
+// isSubscription reports whether the operation to execute is a subscription.
+// Unparseable documents report false and are left to the executor to reject.
+func isSubscription(query, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName != "" && (op.Name == nil || op.Name.Value != operationName) {
+			continue
+		}
+		return op.Operation == ast.OperationTypeSubscription
+	}
+	return false
+}
+
+// checkComplexity rejects documents exceeding the depth or field limits before any
+// backend is called. Every operation in the document is checked, not just the one
+// executed. Unparseable documents are left to the executor to reject.
+func (h *Handler) checkComplexity(query string) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	c := &complexity{
+		maxDepth:  h.maxDepth,
+		maxFields: h.maxFields,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		visiting:  make(map[string]bool),
+	}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok && frag.Name != nil {
+			c.fragments[frag.Name.Value] = frag
+		}
+	}
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if err := c.walk(op.SelectionSet, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// complexity counts the fields a document selects, expanding fragment spreads.
+// It stops at the first limit exceeded, so repeated spreads can't make it expensive.
+type complexity struct {
+	maxDepth  int
+	maxFields int
+	fields    int
+	fragments map[string]*ast.FragmentDefinition
+	// visiting guards against fragment cycles, which validation rejects later anyway
+	visiting map[string]bool
+}
+
+func (c *complexity) walk(set *ast.SelectionSet, depth int) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			c.fields++
+			if c.maxFields > 0 && c.fields > c.maxFields {
+				return status.Errorf(codes.InvalidArgument, "query selects more than %d fields", c.maxFields)
+			}
+			if sel.SelectionSet == nil {
+				continue
+			}
+			if c.maxDepth > 0 && depth+1 >= c.maxDepth {
+				return status.Errorf(codes.InvalidArgument, "query is nested deeper than %d levels", c.maxDepth)
+			}
+			if err := c.walk(sel.SelectionSet, depth+1); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := c.walk(sel.SelectionSet, depth); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := c.fragments[name]
+			if !ok || c.visiting[name] {
+				continue
+			}
+			c.visiting[name] = true
+			err := c.walk(frag.SelectionSet, depth)
+			delete(c.visiting, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, `{"code":"INTERNAL","message":"failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf)
+}
@@ -0,0 +1,331 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	notificationsv1 "github.com/go-chat/notifications/pkg/api/notifications/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+type fakeChat struct {
+	chatv1.UnimplementedChatServiceServer
+}
+
+func (fakeChat) GetChat(_ context.Context, req *chatv1.GetChatRequest) (*chatv1.GetChatResponse, error) {
+	return &chatv1.GetChatResponse{Chat: &chatv1.Chat{ChatId: req.GetChatId(), ParticipantIds: []string{"u1", "u2"}}}, nil
+}
+
+func (fakeChat) ListUserChats(context.Context, *chatv1.ListUserChatsRequest) (*chatv1.ListUserChatsResponse, error) {
+	return &chatv1.ListUserChatsResponse{Chats: []*chatv1.Chat{
+		{ChatId: "c1", ParticipantIds: []string{"u1", "u2"}},
+		{ChatId: "c2", ParticipantIds: []string{"u1", "u3"}},
+	}}, nil
+}
+
+func (fakeChat) StreamMessages(req *chatv1.StreamMessagesRequest, stream grpc.ServerStreamingServer[chatv1.StreamMessagesResponse]) error {
+	for _, text := range []string{"hello", "bye"} {
+		if err := stream.Send(&chatv1.StreamMessagesResponse{Message: &chatv1.Message{
+			ChatId:   req.GetChatId(),
+			SenderId: "u2",
+			Text:     text,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeUsers struct {
+	usersv1.UnimplementedUserServiceServer
+	batchCalls atomic.Int32
+}
+
+var nicknames = map[string]string{"u1": "alice", "u2": "bob", "u3": "carol"}
+
+func (f *fakeUsers) GetProfilesByIDs(_ context.Context, req *usersv1.GetProfilesByIDsRequest) (*usersv1.GetProfilesByIDsResponse, error) {
+	f.batchCalls.Add(1)
+	resp := &usersv1.GetProfilesByIDsResponse{}
+	for _, id := range req.GetUserIds() {
+		if nickname, ok := nicknames[id]; ok {
+			resp.Profiles = append(resp.Profiles, &usersv1.UserProfile{UserId: id, Nickname: nickname})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeUsers) GetProfileByID(context.Context, *usersv1.GetProfileByIDRequest) (*usersv1.GetProfileByIDResponse, error) {
+	return nil, status.Error(codes.NotFound, "profile not found")
+}
+
+type fakeSocial struct {
+	socialv1.UnimplementedSocialServiceServer
+}
+
+func (fakeSocial) CheckRelationship(_ context.Context, req *socialv1.CheckRelationshipRequest) (*socialv1.CheckRelationshipResponse, error) {
+	if req.GetUserId() == "u1" && req.GetTargetUserId() == "u2" {
+		return &socialv1.CheckRelationshipResponse{Status: socialv1.RelationshipStatus_RELATIONSHIP_STATUS_FRIEND}, nil
+	}
+	return &socialv1.CheckRelationshipResponse{Status: socialv1.RelationshipStatus_RELATIONSHIP_STATUS_NONE}, nil
+}
+
+func (fakeSocial) RemoveFriend(context.Context, *socialv1.RemoveFriendRequest) (*socialv1.RemoveFriendResponse, error) {
+	return &socialv1.RemoveFriendResponse{}, nil
+}
+
+// newTestHandler serves all backends from one in-memory gRPC server
+func newTestHandler(t *testing.T) (*Handler, *fakeUsers) {
+	t.Helper()
+
+	users := &fakeUsers{}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	chatv1.RegisterChatServiceServer(srv, fakeChat{})
+	usersv1.RegisterUserServiceServer(srv, users)
+	socialv1.RegisterSocialServiceServer(srv, fakeSocial{})
+	notificationsv1.RegisterNotificationServiceServer(srv, notificationsv1.UnimplementedNotificationServiceServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	h, err := New(conn, conn, conn, conn, time.Second,
+		WithPublicMethods("/api.chat.v1.ChatService/StreamMessages"), WithLimits(5, 20))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return h, users
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, h *Handler, query string) response {
+	t.Helper()
+	return executeAs(t, h, query, "")
+}
+
+// executeAs runs the query as if the gateway had verified an access token for userID
+func executeAs(t *testing.T, h *Handler, query, userID string) response {
+	t.Helper()
+
+	body, _ := json.Marshal(request{Query: query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	if userID != "" {
+		req = req.WithContext(grpc_middleware.ContextWithCaller(req.Context(), grpc_middleware.Caller{
+			Kind:   grpc_middleware.CallerUser,
+			UserID: userID,
+		}))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestHandler_Query_JoinsProfilesAndRelationships(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := executeAs(t, h, `{
+		getChat(chatId: "c1") {
+			chat { chatId participants { nickname relationship } }
+		}
+	}`, "u1")
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %+v", resp.Errors)
+	}
+
+	want := `{"getChat":{"chat":{"chatId":"c1","participants":[` +
+		`{"nickname":"alice","relationship":null},` +
+		`{"nickname":"bob","relationship":"RELATIONSHIP_STATUS_FRIEND"}]}}}`
+	if string(resp.Data) != want {
+		t.Errorf("Expected %s, got %s", want, resp.Data)
+	}
+}
+
+func TestHandler_Query_RelationshipNeedsAuthenticatedViewer(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, `{ getChat(chatId: "c1") { chat { participants { relationship } } } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %+v", resp.Errors)
+	}
+	if want := `{"getChat":{"chat":{"participants":[{"relationship":null},{"relationship":null}]}}}`; string(resp.Data) != want {
+		t.Errorf("Expected %s, got %s", want, resp.Data)
+	}
+
+	resp = executeAs(t, h, `{ getChat(chatId: "c1") { chat { participants { relationship(viewerId: "u1") } } } }`, "u3")
+	if len(resp.Errors) == 0 {
+		t.Error("Expected the viewerId argument to be rejected")
+	}
+}
+
+func TestHandler_Query_BatchesProfileLookups(t *testing.T) {
+	h, users := newTestHandler(t)
+
+	resp := execute(t, h, `{ listUserChats(userId: "u1") { chats { participants { userId } } } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %+v", resp.Errors)
+	}
+
+	if calls := users.batchCalls.Load(); calls != 1 {
+		t.Errorf("Expected 1 GetProfilesByIDs call for all chats, got %d", calls)
+	}
+}
+
+func TestHandler_BackendError_ReportsCode(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, `{ getProfileByID(userId: "missing") { profile { nickname } } }`)
+
+	if len(resp.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %+v", resp.Errors)
+	}
+	if resp.Errors[0].Message != "profile not found" || resp.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("Unexpected error: %+v", resp.Errors[0])
+	}
+}
+
+func TestAnnotateErrors_ValidationFailure_AddsFieldViolations(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "validation failed: nickname: value does not match regex pattern").
+		WithDetails(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "nickname", Reason: "string.pattern", Description: "value does not match regex pattern"},
+		}})
+	if err != nil {
+		t.Fatalf("WithDetails() failed: %v", err)
+	}
+	errs := gqlerrors.FormatErrors(&statusError{st: st})
+
+	annotateErrors(errs)
+
+	if errs[0].Extensions["code"] != "INVALID_ARGUMENT" {
+		t.Errorf("Expected code INVALID_ARGUMENT, got %v", errs[0].Extensions["code"])
+	}
+	expected := []fieldViolation{{Field: "nickname", Reason: "string.pattern", Description: "value does not match regex pattern"}}
+	if got, _ := errs[0].Extensions["fieldViolations"].([]fieldViolation); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected field violations %+v, got %+v", expected, errs[0].Extensions["fieldViolations"])
+	}
+}
+
+func TestHandler_Mutation_EmptyResponseIsTrue(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, `mutation { removeFriend(friendUserId: "u2") }`)
+
+	if string(resp.Data) != `{"removeFriend":true}` || len(resp.Errors) != 0 {
+		t.Errorf("Unexpected response: %s %+v", resp.Data, resp.Errors)
+	}
+}
+
+func TestHandler_Subscription_StreamsEvents(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	body, _ := json.Marshal(request{
+		Query: `subscription { streamMessages(chatId: "c1") { message { text sender { nickname } } } }`,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected event stream, got '%s': %s", ct, rec.Body.String())
+	}
+	events := rec.Body.String()
+	if n := strings.Count(events, "event: next"); n != 2 {
+		t.Errorf("Expected 2 events, got %d: %s", n, events)
+	}
+	if !strings.Contains(events, `{"data":{"streamMessages":{"message":{"sender":{"nickname":"bob"},"text":"hello"}}}}`) {
+		t.Errorf("Expected first message with sender profile, got %s", events)
+	}
+	if !strings.HasSuffix(events, "event: complete\ndata:\n\n") {
+		t.Errorf("Expected stream to end with complete event, got %s", events)
+	}
+}
+
+func TestHandler_Subscription_RequiresEventStream(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	body, _ := json.Marshal(request{Query: `subscription { streamMessages(chatId: "c1") { message { text } } }`})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected status 406, got %d", rec.Code)
+	}
+}
+
+func TestHandler_InternalRPC_NotInSchema(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, `{ checkRelationship(userId: "u1", targetUserId: "u2") { status } }`)
+
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "checkRelationship") {
+		t.Errorf("Expected checkRelationship to be unknown, got %s %+v", resp.Data, resp.Errors)
+	}
+}
+
+func TestHandler_ComplexityLimits_RejectQuery(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"too deep", `{ getChat(chatId: "c1") { chat { participants { relationship { a { b { c } } } } } } }`},
+		{"too many fields via fragments", `
+			query { a: getChat(chatId: "c1") { ...f } b: getChat(chatId: "c1") { ...f } c: getChat(chatId: "c1") { ...f } }
+			fragment f on GetChatResponse { chat { id type createdAt participants { id nickname } } }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(request{Query: tt.query})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
+)
+
+// maxProfilesPerBatch is the largest batch GetProfilesByIDs accepts
+const maxProfilesPerBatch = 100
+
+// addRelations registers fields resolving the user IDs in chat and social messages to
+// profiles, and the relationship between a profile and the authenticated viewer
+func (b *schemaBuilder) addRelations() {
+	profile := b.object((&usersv1.UserProfile{}).ProtoReflect().Descriptor())
+	profiles := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(profile)))
+	relationshipStatus := b.enum(socialv1.RelationshipStatus(0).Descriptor())
+
+	b.relations[fullName(&chatv1.Chat{})] = graphql.Fields{
+		"participants": {Type: profiles, Resolve: resolveProfiles("participant_ids")},
+	}
+	b.relations[fullName(&chatv1.Message{})] = graphql.Fields{
+		"sender": {Type: profile, Resolve: resolveProfile("sender_id")},
+	}
+	b.relations[fullName(&chatv1.ListChatMembersResponse{})] = graphql.Fields{
+		"members": {Type: profiles, Resolve: resolveProfiles("user_ids")},
+	}
+	b.relations[fullName(&socialv1.ListFriendsResponse{})] = graphql.Fields{
+		"friends": {Type: profiles, Resolve: resolveProfiles("user_ids")},
+	}
+	b.relations[fullName(&socialv1.FriendRequest{})] = graphql.Fields{
+		"requester": {Type: profile, Resolve: resolveProfile("requester_id")},
+		"target":    {Type: profile, Resolve: resolveProfile("target_id")},
+	}
+	b.relations[fullName(&usersv1.UserProfile{})] = graphql.Fields{
+		"relationship": {
+			Type:        relationshipStatus,
+			Description: "Relationship between the authenticated viewer and this profile's user, null for anonymous requests and the viewer's own profile",
+			Resolve:     async(b.h.resolveRelationship),
+		},
+	}
+}
+
+func (h *Handler) resolveRelationship(p graphql.ResolveParams) (interface{}, error) {
+	m, ok := p.Source.(protoreflect.Message)
+	if !ok {
+		return nil, nil
+	}
+	// The viewer is the verified caller, never an argument, so nobody can look up the
+	// relationship between two other users
+	caller, ok := grpc_middleware.CallerFromContext(p.Context)
+	targetID := stringField(m, "user_id")
+	if !ok || caller.UserID == "" || caller.UserID == targetID {
+		return nil, nil
+	}
+
+	var resp *socialv1.CheckRelationshipResponse
+	err := h.call(p.Context, func(ctx context.Context) error {
+		var err error
+		resp, err = h.social.CheckRelationship(ctx, &socialv1.CheckRelationshipRequest{
+			UserId:       caller.UserID,
+			TargetUserId: targetID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetStatus().String(), nil
+}
+
+// resolveProfile resolves a user ID field to the user's profile, or null if it has none
+func resolveProfile(idField protoreflect.Name) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		m, ok := p.Source.(protoreflect.Message)
+		state := requestStateFrom(p.Context)
+		if !ok || state == nil {
+			return nil, nil
+		}
+
+		load := state.profiles.load(p.Context, []string{stringField(m, idField)})
+		return func() (interface{}, error) {
+			profiles, err := load()
+			if err != nil || len(profiles) == 0 {
+				return nil, err
+			}
+			return profiles[0], nil
+		}, nil
+	}
+}
+
+// resolveProfiles resolves a repeated user ID field to profiles, skipping users without one
+func resolveProfiles(idsField protoreflect.Name) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		m, ok := p.Source.(protoreflect.Message)
+		state := requestStateFrom(p.Context)
+		if !ok || state == nil {
+			return []interface{}{}, nil
+		}
+
+		list := m.Get(m.Descriptor().Fields().ByName(idsField)).List()
+		ids := make([]string, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			ids = append(ids, list.Get(i).String())
+		}
+
+		load := state.profiles.load(p.Context, ids)
+		return func() (interface{}, error) {
+			profiles, err := load()
+			if err != nil {
+				return nil, err
+			}
+			return profiles, nil
+		}, nil
+	}
+}
+
+// profileLoader collects the profile lookups of one execution level into a single
+// GetProfilesByIDs batch. The executor resolves every field of a level before calling
+// any of the returned thunks, so the first thunk called fetches the whole batch.
+type profileLoader struct {
+	h *Handler
+
+	mu      sync.Mutex
+	pending *profileBatch
+	cache   map[string]*usersv1.UserProfile
+}
+
+type profileBatch struct {
+	ids  map[string]struct{}
+	once sync.Once
+	err  error
+}
+
+func newProfileLoader(h *Handler) *profileLoader {
+	return &profileLoader{h: h, cache: make(map[string]*usersv1.UserProfile)}
+}
+
+// load queues ids for the pending batch and returns a function that waits for the
+// batch and returns the profiles found, in the order of ids
+func (l *profileLoader) load(ctx context.Context, ids []string) func() ([]interface{}, error) {
+	l.mu.Lock()
+	if l.pending == nil {
+		l.pending = &profileBatch{ids: make(map[string]struct{})}
+	}
+	batch := l.pending
+	for _, id := range ids {
+		if _, cached := l.cache[id]; !cached && id != "" {
+			batch.ids[id] = struct{}{}
+		}
+	}
+	l.mu.Unlock()
+
+	return func() ([]interface{}, error) {
+		batch.once.Do(func() {
+			l.mu.Lock()
+			if l.pending == batch {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+			batch.err = l.fetch(ctx, batch.ids)
+		})
+		if batch.err != nil {
+			return nil, batch.err
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		profiles := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			if profile := l.cache[id]; profile != nil {
+				profiles = append(profiles, profile.ProtoReflect())
+			}
+		}
+		return profiles, nil
+	}
+}
+
+// fetch looks up ids in batches the users service accepts and caches the results.
+// IDs without a profile are cached as nil so they aren't looked up again.
+func (l *profileLoader) fetch(ctx context.Context, ids map[string]struct{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	batch := make([]string, 0, min(len(ids), maxProfilesPerBatch))
+	flush := func() error {
+		var resp *usersv1.GetProfilesByIDsResponse
+		err := l.h.call(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = l.h.users.GetProfilesByIDs(ctx, &usersv1.GetProfilesByIDsRequest{UserIds: batch})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, id := range batch {
+			l.cache[id] = nil
+		}
+		for _, profile := range resp.GetProfiles() {
+			l.cache[profile.GetUserId()] = profile
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for id := range ids {
+		batch = append(batch, id)
+		if len(batch) == maxProfilesPerBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// requestState holds what resolvers share within one GraphQL request
+type requestState struct {
+	profiles *profileLoader
+	// fanout bounds the backend calls started concurrently by async resolvers
+	fanout chan struct{}
+}
+
+type requestStateKey struct{}
+
+func withRequestState(ctx context.Context, h *Handler) context.Context {
+	return context.WithValue(ctx, requestStateKey{}, &requestState{
+		profiles: newProfileLoader(h),
+		fanout:   make(chan struct{}, maxFanout),
+	})
+}
+
+func requestStateFrom(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return state
+}
+
+// async starts resolve right away and returns a thunk for its result, so sibling
+// fields call their backends concurrently rather than one after another
+func async(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		state := requestStateFrom(p.Context)
+		if state == nil {
+			return resolve(p)
+		}
+
+		done := make(chan result, 1)
+		go func() {
+			state.fanout <- struct{}{}
+			defer func() { <-state.fanout }()
+
+			value, err := resolve(p)
+			done <- result{value: value, err: err}
+		}()
+
+		return func() (interface{}, error) {
+			r := <-done
+			return r.value, r.err
+		}, nil
+	}
+}
+
+func stringField(m protoreflect.Message, name protoreflect.Name) string {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil {
+		return ""
+	}
+	return m.Get(fd).String()
+}
+
+func fullName(m interface {
+	ProtoReflect() protoreflect.Message
+}) protoreflect.FullName {
+	return m.ProtoReflect().Descriptor().FullName()
+}
@@ -0,0 +1,525 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chat/gateway/internal/proxy"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// readPrefixes mark RPCs without side effects, which become Query fields;
+// every other unary RPC becomes a Mutation field
+var readPrefixes = []string{"Get", "List", "Check", "Search"}
+
+// backendService is a gRPC service exposed in the schema and the connection serving it
+type backendService struct {
+	name string
+	conn grpc.ClientConnInterface
+}
+
+// schemaBuilder derives GraphQL types from proto descriptors. Messages become object
+// types with their JSON field names, enums become enums and google.protobuf.Timestamp
+// becomes DateTime. 64-bit integers are strings, as in protojson.
+type schemaBuilder struct {
+	h       *Handler
+	objects map[protoreflect.FullName]*graphql.Object
+	enums   map[protoreflect.FullName]*graphql.Enum
+	// typeNames detects proto messages from different packages sharing a short name
+	typeNames map[string]protoreflect.FullName
+	// relations are hand-written fields joining a message to data from other services
+	relations map[protoreflect.FullName]graphql.Fields
+}
+
+func newSchemaBuilder(h *Handler) *schemaBuilder {
+	return &schemaBuilder{
+		h:         h,
+		objects:   make(map[protoreflect.FullName]*graphql.Object),
+		enums:     make(map[protoreflect.FullName]*graphql.Enum),
+		typeNames: make(map[string]protoreflect.FullName),
+		relations: make(map[protoreflect.FullName]graphql.Fields),
+	}
+}
+
+// build creates the schema with one root field per public RPC of the given services.
+// Internal RPCs such as CheckRelationship stay out of it, though relations may call them.
+func (b *schemaBuilder) build(services []backendService) (graphql.Schema, error) {
+	query := graphql.Fields{}
+	mutation := graphql.Fields{}
+	subscription := graphql.Fields{}
+
+	for _, svc := range services {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svc.name))
+		if err != nil {
+			return graphql.Schema{}, fmt.Errorf("failed to find descriptor for %s: %w", svc.name, err)
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return graphql.Schema{}, fmt.Errorf("%s is not a service", svc.name)
+		}
+
+		for i := 0; i < sd.Methods().Len(); i++ {
+			method := sd.Methods().Get(i)
+			if method.IsStreamingClient() || !proxy.IsPublic(method, b.h.publicMethods) {
+				continue
+			}
+
+			name := lowerFirst(string(method.Name()))
+			field := &graphql.Field{
+				Type: b.resultType(method.Output()),
+				Args: b.arguments(method.Input()),
+			}
+
+			var fields graphql.Fields
+			switch {
+			case method.IsStreamingServer():
+				field.Subscribe = b.subscriber(svc.conn, method)
+				field.Resolve = resolveEvent
+				fields = subscription
+			case isRead(method):
+				field.Resolve = async(b.invoker(svc.conn, method))
+				fields = query
+			default:
+				// Mutations run one after another, so they resolve synchronously
+				field.Resolve = b.invoker(svc.conn, method)
+				fields = mutation
+			}
+
+			if _, exists := fields[name]; exists {
+				return graphql.Schema{}, fmt.Errorf("duplicate GraphQL field %s from %s", name, method.FullName())
+			}
+			fields[name] = field
+		}
+	}
+
+	cfg := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: query}),
+	}
+	if len(mutation) > 0 {
+		cfg.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutation})
+	}
+	if len(subscription) > 0 {
+		cfg.Subscription = graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: subscription})
+	}
+	return graphql.NewSchema(cfg)
+}
+
+// invoker calls a unary RPC with a request built from the field arguments
+func (b *schemaBuilder) invoker(conn grpc.ClientConnInterface, method protoreflect.MethodDescriptor) graphql.FieldResolveFn {
+	fullMethod := fullMethodName(method)
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		req, err := newRequest(method.Input(), p.Args)
+		if err != nil {
+			return nil, err
+		}
+		resp := dynamicpb.NewMessage(method.Output())
+
+		if err := b.h.call(p.Context, func(ctx context.Context) error {
+			return conn.Invoke(ctx, fullMethod, req, resp)
+		}); err != nil {
+			return nil, err
+		}
+
+		if method.Output().Fields().Len() == 0 {
+			return true, nil
+		}
+		return resp, nil
+	}
+}
+
+// subscriber opens a server stream and forwards each response as a subscription event.
+// A stream error is forwarded as the last event, so the client sees why the stream ended.
+func (b *schemaBuilder) subscriber(conn grpc.ClientConnInterface, method protoreflect.MethodDescriptor) graphql.FieldResolveFn {
+	streamDesc := &grpc.StreamDesc{StreamName: string(method.Name()), ServerStreams: true}
+	fullMethod := fullMethodName(method)
+
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		req, err := newRequest(method.Input(), p.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := p.Context
+		stream, err := conn.NewStream(ctx, streamDesc, fullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.SendMsg(req); err != nil {
+			return nil, err
+		}
+		if err := stream.CloseSend(); err != nil {
+			return nil, err
+		}
+
+		events := make(chan interface{})
+		go func() {
+			defer close(events)
+			for {
+				var event interface{}
+				resp := dynamicpb.NewMessage(method.Output())
+				if err := stream.RecvMsg(resp); err != nil {
+					if err == io.EOF || ctx.Err() != nil {
+						return
+					}
+					event = err
+				} else {
+					event = resp
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				if _, failed := event.(error); failed {
+					return
+				}
+			}
+		}()
+		return events, nil
+	}
+}
+
+// resolveEvent resolves a subscription field to the event being delivered
+func resolveEvent(p graphql.ResolveParams) (interface{}, error) {
+	if err, ok := p.Source.(error); ok {
+		return nil, err
+	}
+	return p.Source, nil
+}
+
+// resultType is the type of a root field; RPCs returning an empty message resolve to true
+func (b *schemaBuilder) resultType(md protoreflect.MessageDescriptor) graphql.Output {
+	if md.Fields().Len() == 0 {
+		return graphql.NewNonNull(graphql.Boolean)
+	}
+	return b.object(md)
+}
+
+// object returns the object type for a message. Fields are resolved lazily, so
+// recursive messages and relations registered after the type work.
+func (b *schemaBuilder) object(md protoreflect.MessageDescriptor) *graphql.Object {
+	if obj, ok := b.objects[md.FullName()]; ok {
+		return obj
+	}
+
+	obj := graphql.NewObject(graphql.ObjectConfig{
+		Name: b.typeName(md),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := graphql.Fields{}
+			for i := 0; i < md.Fields().Len(); i++ {
+				fd := md.Fields().Get(i)
+				typ := b.outputType(fd)
+				if typ == nil {
+					continue
+				}
+				fields[fd.JSONName()] = &graphql.Field{Type: typ, Resolve: resolveProtoField(fd)}
+			}
+			for name, field := range b.relations[md.FullName()] {
+				fields[name] = field
+			}
+			return fields
+		}),
+	})
+	b.objects[md.FullName()] = obj
+	return obj
+}
+
+func (b *schemaBuilder) enum(ed protoreflect.EnumDescriptor) *graphql.Enum {
+	if enum, ok := b.enums[ed.FullName()]; ok {
+		return enum
+	}
+
+	values := graphql.EnumValueConfigMap{}
+	for i := 0; i < ed.Values().Len(); i++ {
+		name := string(ed.Values().Get(i).Name())
+		values[name] = &graphql.EnumValueConfig{Value: name}
+	}
+	enum := graphql.NewEnum(graphql.EnumConfig{Name: b.typeName(ed), Values: values})
+	b.enums[ed.FullName()] = enum
+	return enum
+}
+
+// typeName uses the short proto name unless another package already claimed it
+func (b *schemaBuilder) typeName(d protoreflect.Descriptor) string {
+	name := string(d.Name())
+	if owner, ok := b.typeNames[name]; ok && owner != d.FullName() {
+		name = strings.ReplaceAll(string(d.FullName()), ".", "_")
+	}
+	b.typeNames[name] = d.FullName()
+	return name
+}
+
+// outputType maps a proto field to a GraphQL output type, or nil if it can't be represented.
+// Proto3 scalars always have a value, so they are non-null; messages may be absent.
+func (b *schemaBuilder) outputType(fd protoreflect.FieldDescriptor) graphql.Output {
+	if fd.IsMap() {
+		return nil
+	}
+
+	var typ graphql.Output
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if isTimestamp(fd.Message()) {
+			typ = graphql.DateTime
+		} else {
+			typ = b.object(fd.Message())
+		}
+	case protoreflect.EnumKind:
+		typ = b.enum(fd.Enum())
+	default:
+		typ = scalarType(fd.Kind())
+	}
+
+	if fd.IsList() {
+		return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(typ)))
+	}
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return typ
+	}
+	return graphql.NewNonNull(typ)
+}
+
+// arguments maps a request message to field arguments. Fields marked REQUIRED with
+// google.api.field_behavior are non-null; nested messages other than Timestamp are not supported.
+func (b *schemaBuilder) arguments(md protoreflect.MessageDescriptor) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		if fd.IsMap() {
+			continue
+		}
+
+		var typ graphql.Input
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			if !isTimestamp(fd.Message()) {
+				continue
+			}
+			typ = graphql.DateTime
+		case protoreflect.EnumKind:
+			typ = b.enum(fd.Enum())
+		default:
+			typ = scalarType(fd.Kind())
+		}
+
+		if fd.IsList() {
+			typ = graphql.NewList(graphql.NewNonNull(typ))
+		}
+		if isRequired(fd) {
+			typ = graphql.NewNonNull(typ)
+		}
+		args[fd.JSONName()] = &graphql.ArgumentConfig{Type: typ}
+	}
+	return args
+}
+
+func scalarType(kind protoreflect.Kind) *graphql.Scalar {
+	switch kind {
+	case protoreflect.BoolKind:
+		return graphql.Boolean
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return graphql.Int
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return graphql.Float
+	default:
+		// Strings, bytes (base64) and 64-bit integers, which don't fit GraphQL's 32-bit Int
+		return graphql.String
+	}
+}
+
+// resolveProtoField reads a field from a proto message source
+func resolveProtoField(fd protoreflect.FieldDescriptor) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		m, ok := p.Source.(protoreflect.Message)
+		if !ok {
+			return nil, nil
+		}
+
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			values := make([]interface{}, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, outputValue(fd, list.Get(i)))
+			}
+			return values, nil
+		}
+		if fd.Message() != nil && !m.Has(fd) {
+			return nil, nil
+		}
+		return outputValue(fd, m.Get(fd)), nil
+	}
+}
+
+func outputValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if isTimestamp(fd.Message()) {
+			return timestampValue(v.Message())
+		}
+		return v.Message()
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(v.Int(), 10)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(v.Uint(), 10)
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	default:
+		return v.Interface()
+	}
+}
+
+// timestampValue converts a Timestamp message, generated or dynamic, to time.Time
+func timestampValue(m protoreflect.Message) time.Time {
+	fields := m.Descriptor().Fields()
+	seconds := m.Get(fields.ByName("seconds")).Int()
+	nanos := m.Get(fields.ByName("nanos")).Int()
+	return time.Unix(seconds, nanos).UTC()
+}
+
+// newRequest builds a request message from field arguments
+func newRequest(md protoreflect.MessageDescriptor, args map[string]interface{}) (*dynamicpb.Message, error) {
+	req := dynamicpb.NewMessage(md)
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		arg, ok := args[fd.JSONName()]
+		if !ok || arg == nil {
+			continue
+		}
+
+		if fd.IsList() {
+			items, _ := arg.([]interface{})
+			list := req.Mutable(fd).List()
+			for _, item := range items {
+				v, err := inputValue(fd, item)
+				if err != nil {
+					return nil, err
+				}
+				list.Append(v)
+			}
+			continue
+		}
+
+		v, err := inputValue(fd, arg)
+		if err != nil {
+			return nil, err
+		}
+		req.Set(fd, v)
+	}
+	return req, nil
+}
+
+func inputValue(fd protoreflect.FieldDescriptor, arg interface{}) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		t, ok := arg.(time.Time)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("argument %s: expected a DateTime", fd.JSONName())
+		}
+		ts := dynamicpb.NewMessage(fd.Message())
+		fields := ts.Descriptor().Fields()
+		ts.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+		ts.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+		return protoreflect.ValueOfMessage(ts), nil
+	case protoreflect.EnumKind:
+		name, _ := arg.(string)
+		value := fd.Enum().Values().ByName(protoreflect.Name(name))
+		if value == nil {
+			return protoreflect.Value{}, fmt.Errorf("argument %s: unknown value %q", fd.JSONName(), name)
+		}
+		return protoreflect.ValueOfEnum(value.Number()), nil
+	case protoreflect.BoolKind:
+		b, _ := arg.(bool)
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, _ := arg.(int)
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, _ := arg.(int)
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.FloatKind:
+		f, _ := arg.(float64)
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+	case protoreflect.DoubleKind:
+		f, _ := arg.(float64)
+		return protoreflect.ValueOfFloat64(f), nil
+	}
+
+	s, _ := arg.(string)
+	switch fd.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("argument %s: %w", fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("argument %s: %w", fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfUint64(n), nil
+	case protoreflect.BytesKind:
+		buf, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("argument %s: %w", fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfBytes(buf), nil
+	default:
+		return protoreflect.ValueOfString(s), nil
+	}
+}
+
+func isRead(method protoreflect.MethodDescriptor) bool {
+	for _, prefix := range readPrefixes {
+		if strings.HasPrefix(string(method.Name()), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRequired(fd protoreflect.FieldDescriptor) bool {
+	behaviors, _ := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	for _, behavior := range behaviors {
+		if behavior == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimestamp(md protoreflect.MessageDescriptor) bool {
+	return md.FullName() == "google.protobuf.Timestamp"
+}
+
+func fullMethodName(method protoreflect.MethodDescriptor) string {
+	return "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
@@ -0,0 +1,165 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+	libhealth "github.com/go-chat/lib/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// Report is the JSON document served by the readiness endpoint
+type Report struct {
+	Status   string            `json:"status"`
+	Backends map[string]string `json:"backends"`
+}
+
+// Prober aggregates the grpc.health.v1 status of all backend services.
+// Results are cached for a short TTL so frequent orchestrator probes
+// don't translate into a health RPC per backend per request.
+type Prober struct {
+	checks  map[string]libhealth.Checker
+	conns   []*grpc.ClientConn
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu        sync.Mutex
+	last      Report
+	checkedAt time.Time
+}
+
+// NewProber creates a prober with one health client per backend service
+func NewProber(cfg *config.Config) (*Prober, error) {
+	backends := map[string]string{
+		"auth":          cfg.Services.Auth,
+		"users":         cfg.Services.Users,
+		"chat":          cfg.Services.Chat,
+		"social":        cfg.Services.Social,
+		"notifications": cfg.Services.Notifications,
+	}
+
+	checks := make(map[string]libhealth.Checker, len(backends))
+	var conns []*grpc.ClientConn
+	for name, addr := range backends {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			closeAll(conns)
+			return nil, fmt.Errorf("failed to create health client for %s: %w", name, err)
+		}
+		conns = append(conns, conn)
+		checks[name] = libhealth.GRPCCheck(conn, "")
+	}
+
+	p := newProber(checks, cfg.Health.CacheTTL, cfg.Health.ProbeTimeout)
+	p.conns = conns
+	return p, nil
+}
+
+func newProber(checks map[string]libhealth.Checker, ttl, timeout time.Duration) *Prober {
+	return &Prober{
+		checks:  checks,
+		ttl:     ttl,
+		timeout: timeout,
+	}
+}
+
+// Report returns the aggregated backend health, probing backends only when the cached result has expired
+func (p *Prober) Report(ctx context.Context) Report {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checkedAt.IsZero() && time.Since(p.checkedAt) < p.ttl {
+		return p.last
+	}
+
+	p.last = p.probe(ctx)
+	p.checkedAt = time.Now()
+	return p.last
+}
+
+// probe checks every backend concurrently. The result is cached and shared with every
+// waiting caller, so it runs detached from the triggering request: a probe cancelled by
+// the orchestrator must not cache "unavailable" for the whole TTL.
+func (p *Prober) probe(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+	defer cancel()
+
+	report := Report{Status: statusOK, Backends: make(map[string]string, len(p.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, checker := range p.checks {
+		wg.Add(1)
+		go func(name string, checker libhealth.Checker) {
+			defer wg.Done()
+
+			status := statusOK
+			if err := checker.Check(ctx); err != nil {
+				status = statusUnavailable
+			}
+
+			mu.Lock()
+			report.Backends[name] = status
+			if status != statusOK {
+				report.Status = statusUnavailable
+			}
+			mu.Unlock()
+		}(name, checker)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// ReadinessHandler serves /readyz: 200 when every backend is serving, 503 otherwise
+func (p *Prober) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := p.Report(r.Context())
+
+		code := http.StatusOK
+		if report.Status != statusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+// Close releases the backend health connections
+func (p *Prober) Close() error {
+	return closeAll(p.conns)
+}
+
+// LivenessHandler serves /healthz: it only reports that the gateway process is able to serve HTTP
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": statusOK})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func closeAll(conns []*grpc.ClientConn) error {
+	var firstErr error
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
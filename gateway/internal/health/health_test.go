@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	libhealth "github.com/go-chat/lib/health"
+)
+
+func countingCheck(calls *atomic.Int32, err error) libhealth.Checker {
+	return libhealth.CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return err
+	})
+}
+
+func TestReadinessHandler_AllBackendsHealthy_Returns200(t *testing.T) {
+	var calls atomic.Int32
+	p := newProber(map[string]libhealth.Checker{
+		"auth": countingCheck(&calls, nil),
+		"chat": countingCheck(&calls, nil),
+	}, time.Minute, time.Second)
+
+	rec := httptest.NewRecorder()
+	p.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Status != statusOK || report.Backends["auth"] != statusOK || report.Backends["chat"] != statusOK {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestReadinessHandler_BackendDown_Returns503(t *testing.T) {
+	var calls atomic.Int32
+	p := newProber(map[string]libhealth.Checker{
+		"auth":   countingCheck(&calls, nil),
+		"social": countingCheck(&calls, errors.New("unavailable")),
+	}, time.Minute, time.Second)
+
+	rec := httptest.NewRecorder()
+	p.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Backends["social"] != statusUnavailable {
+		t.Errorf("Expected social to be unavailable, got '%s'", report.Backends["social"])
+	}
+	if report.Backends["auth"] != statusOK {
+		t.Errorf("Expected auth to be ok, got '%s'", report.Backends["auth"])
+	}
+}
+
+func TestProber_Report_CachesWithinTTL(t *testing.T) {
+	var calls atomic.Int32
+	p := newProber(map[string]libhealth.Checker{
+		"auth": countingCheck(&calls, nil),
+	}, time.Minute, time.Second)
+
+	p.Report(context.Background())
+	p.Report(context.Background())
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 backend probe within TTL, got %d", got)
+	}
+}
+
+func TestProber_Report_ReprobesAfterTTL(t *testing.T) {
+	var calls atomic.Int32
+	p := newProber(map[string]libhealth.Checker{
+		"auth": countingCheck(&calls, nil),
+	}, time.Millisecond, time.Second)
+
+	p.Report(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	p.Report(context.Background())
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 backend probes after TTL expiry, got %d", got)
+	}
+}
+
+func TestProber_Report_CancelledCallerDoesNotPoisonCache(t *testing.T) {
+	p := newProber(map[string]libhealth.Checker{
+		"chat": libhealth.CheckerFunc(func(ctx context.Context) error { return ctx.Err() }),
+	}, time.Minute, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if report := p.Report(ctx); report.Status != statusOK {
+		t.Errorf("Expected the probe to outlive the cancelled caller, got %+v", report)
+	}
+}
+
+func TestLivenessHandler_Returns200(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+}
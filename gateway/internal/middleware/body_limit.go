@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/go-chat/gateway/internal/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BodyLimit rejects request bodies larger than the limit for their path with 413.
+// Declared oversize bodies are rejected before reading; chunked bodies are read up to
+// the limit and buffered, so the handler never sees a truncated payload. grpc-gateway
+// decodes the whole JSON body into memory anyway, so buffering adds no peak memory.
+func BodyLimit(cfg config.ServerConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := bodyLimitFor(cfg, r.URL.Path)
+			if r.ContentLength > limit {
+				writeTooLarge(w, r, limit)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					writeTooLarge(w, r, limit)
+					return
+				}
+				apierror.WriteStatus(w, r, status.New(codes.InvalidArgument, "failed to read request body"))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bodyLimitFor returns the limit of the first route pattern matching p, or the default
+func bodyLimitFor(cfg config.ServerConfig, p string) int64 {
+	for _, limit := range cfg.BodyLimits {
+		if ok, _ := path.Match(limit.Pattern, p); ok {
+			return limit.MaxBytes
+		}
+	}
+	return cfg.MaxBodyBytes
+}
+
+func writeTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	// The connection may hold unread body bytes; don't reuse it
+	w.Header().Set("Connection", "close")
+	msg := fmt.Sprintf("request body exceeds %d bytes", limit)
+	apierror.Write(w, r, http.StatusRequestEntityTooLarge, status.New(codes.InvalidArgument, msg))
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chat/gateway/internal/config"
+)
+
+func TestBodyLimit(t *testing.T) {
+	cfg := config.ServerConfig{
+		MaxBodyBytes: 100,
+		BodyLimits:   []config.BodyLimit{{Pattern: "/v1/chats/*/messages", MaxBytes: 10}},
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{"default within limit", "/v1/profile", strings.Repeat("a", 100), false, http.StatusOK},
+		{"default over limit", "/v1/profile", strings.Repeat("a", 101), false, http.StatusRequestEntityTooLarge},
+		{"route within limit", "/v1/chats/42/messages", strings.Repeat("a", 10), false, http.StatusOK},
+		{"route over limit", "/v1/chats/42/messages", strings.Repeat("a", 11), false, http.StatusRequestEntityTooLarge},
+		{"chunked over limit", "/v1/chats/42/messages", strings.Repeat("a", 11), true, http.StatusRequestEntityTooLarge},
+		{"chunked within limit", "/v1/chats/42/messages", "hello", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			handler := BodyLimit(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusOK && received != tt.body {
+				t.Errorf("Expected handler to receive the full body, got %d bytes", len(received))
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), `"code":"INVALID_ARGUMENT"`) {
+				t.Errorf("Expected JSON error envelope, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBodyLimit_NoBody_PassesThrough(t *testing.T) {
+	called := false
+	handler := BodyLimit(config.ServerConfig{MaxBodyBytes: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chats", nil))
+
+	if !called {
+		t.Error("Expected bodiless request to reach the handler")
+	}
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+)
+
+// CacheStatusHeader reports whether a response was served from the gateway cache
+const CacheStatusHeader = "X-Cache"
+
+// ResponseCache is a short-TTL, size-bounded LRU cache for whitelisted GET routes
+type ResponseCache struct {
+	cfg config.CacheConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generations counts invalidations per route pattern; a response fetched
+	// across an invalidation is not stored, as it may already be stale
+	generations map[string]uint64
+}
+
+type cacheEntry struct {
+	key     string
+	route   string
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// NewResponseCache creates a cache for the configured routes
+func NewResponseCache(cfg config.CacheConfig) *ResponseCache {
+	return &ResponseCache{
+		cfg:         cfg,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		generations: make(map[string]uint64),
+	}
+}
+
+// Middleware serves cached responses for whitelisted GET routes and drops a route's
+// entries after a successful request to one of its invalidating routes
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	if !c.cfg.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			if sw.status >= 200 && sw.status < 300 {
+				c.invalidate(r)
+			}
+			return
+		}
+
+		route, ok := c.cachedRoute(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(route, r)
+		if entry, ok := c.get(key); ok {
+			copyHeader(w.Header(), entry.header)
+			w.Header().Set(CacheStatusHeader, "HIT")
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+
+		generation := c.generation(route)
+		buf := newBufferedResponse()
+		next.ServeHTTP(buf, r)
+
+		if buf.status == http.StatusOK && !strings.Contains(buf.header.Get("Cache-Control"), "no-store") {
+			c.put(&cacheEntry{
+				key:    key,
+				route:  route,
+				status: buf.status,
+				header: buf.header.Clone(),
+				body:   append([]byte(nil), buf.body.Bytes()...),
+			}, generation)
+		}
+
+		buf.header.Set(CacheStatusHeader, "MISS")
+		buf.writeTo(w)
+	})
+}
+
+// cachedRoute returns the whitelisted pattern matching p
+func (c *ResponseCache) cachedRoute(p string) (string, bool) {
+	for _, route := range c.cfg.Routes {
+		if ok, _ := path.Match(route.Pattern, p); ok {
+			return route.Pattern, true
+		}
+	}
+	return "", false
+}
+
+func (c *ResponseCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *ResponseCache) put(entry *cacheEntry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations[entry.route] != generation {
+		return
+	}
+
+	entry.expires = c.now().Add(c.cfg.TTL)
+	if elem, ok := c.entries[entry.key]; ok {
+		c.remove(elem)
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	for c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ResponseCache) generation(route string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[route]
+}
+
+// invalidate drops the entries of every route invalidated by the request
+func (c *ResponseCache) invalidate(r *http.Request) {
+	var routes []string
+	for _, route := range c.cfg.Routes {
+		for _, trigger := range route.InvalidatedBy {
+			method, pattern, _ := strings.Cut(trigger, " ")
+			if ok, _ := path.Match(pattern, r.URL.Path); ok && method == r.Method {
+				routes = append(routes, route.Pattern)
+				break
+			}
+		}
+	}
+	if len(routes) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, route := range routes {
+		c.generations[route]++
+	}
+	for elem := c.lru.Front(); elem != nil; {
+		nextElem := elem.Next()
+		for _, route := range routes {
+			if elem.Value.(*cacheEntry).route == route {
+				c.remove(elem)
+				break
+			}
+		}
+		elem = nextElem
+	}
+}
+
+func (c *ResponseCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// cacheKey scopes an entry to the route, the full request URI and the user verified by the
+// gateway, so a refreshed access token keeps hitting the same entries
+func cacheKey(route string, r *http.Request) string {
+	return route + "\x00" + r.URL.RequestURI() + "\x00" + callerScope(r)
+}
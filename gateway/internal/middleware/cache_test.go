@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/lib/grpc_middleware"
+)
+
+func newTestCache(calls *int) (*ResponseCache, http.Handler) {
+	cache := NewResponseCache(config.CacheConfig{
+		Enabled:    true,
+		TTL:        time.Minute,
+		MaxEntries: 2,
+		Routes: []config.CacheRoute{{
+			Pattern:       "/v1/profile/*",
+			InvalidatedBy: []string{"PUT /v1/profile"},
+		}},
+	})
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		_, _ = fmt.Fprintf(w, `{"call":%d}`, *calls)
+	}))
+	return cache, handler
+}
+
+// get sends a request as if the gateway had verified an access token for user
+func get(handler http.Handler, path, user string) *httptest.ResponseRecorder {
+	return getWithToken(handler, path, user, "token-"+user)
+}
+
+func getWithToken(handler http.Handler, path, user, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = req.WithContext(grpc_middleware.ContextWithCaller(req.Context(), grpc_middleware.Caller{
+		Kind:   grpc_middleware.CallerUser,
+		UserID: user,
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestResponseCache_RepeatedGet_ServedFromCache(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	first := get(handler, "/v1/profile/42", "alice")
+	second := get(handler, "/v1/profile/42", "alice")
+
+	if calls != 1 {
+		t.Errorf("Expected backend to be called once, got %d", calls)
+	}
+	if first.Header().Get(CacheStatusHeader) != "MISS" || second.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("Expected MISS then HIT, got '%s' then '%s'",
+			first.Header().Get(CacheStatusHeader), second.Header().Get(CacheStatusHeader))
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected identical bodies, got %q and %q", first.Body.String(), second.Body.String())
+	}
+}
+
+func TestResponseCache_KeyedPerCaller(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	get(handler, "/v1/profile/42", "alice")
+	get(handler, "/v1/profile/42", "bob")
+
+	if calls != 2 {
+		t.Errorf("Expected each caller to reach the backend, got %d calls", calls)
+	}
+}
+
+func TestResponseCache_RefreshedToken_ServedFromCache(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	getWithToken(handler, "/v1/profile/42", "alice", "expired-token")
+	rec := getWithToken(handler, "/v1/profile/42", "alice", "refreshed-token")
+
+	if calls != 1 || rec.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("Expected the same user to hit the cache after a token refresh, got %d calls and %q", calls, rec.Header().Get(CacheStatusHeader))
+	}
+}
+
+func TestResponseCache_UncachedRoute_PassesThrough(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	get(handler, "/v1/chats", "alice")
+	rec := get(handler, "/v1/chats", "alice")
+
+	if calls != 2 {
+		t.Errorf("Expected 2 backend calls, got %d", calls)
+	}
+	if got := rec.Header().Get(CacheStatusHeader); got != "" {
+		t.Errorf("Expected no cache header, got '%s'", got)
+	}
+}
+
+func TestResponseCache_SuccessfulWrite_Invalidates(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	get(handler, "/v1/profile/42", "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v1/profile", nil))
+	rec := get(handler, "/v1/profile/42", "alice")
+
+	if rec.Header().Get(CacheStatusHeader) != "MISS" {
+		t.Errorf("Expected MISS after invalidation, got '%s'", rec.Header().Get(CacheStatusHeader))
+	}
+}
+
+func TestResponseCache_Expired_Refetches(t *testing.T) {
+	var calls int
+	cache, handler := newTestCache(&calls)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	get(handler, "/v1/profile/42", "alice")
+
+	now = now.Add(2 * time.Minute)
+	get(handler, "/v1/profile/42", "alice")
+
+	if calls != 2 {
+		t.Errorf("Expected expired entry to be refetched, got %d calls", calls)
+	}
+}
+
+func TestResponseCache_MaxEntries_EvictsLeastRecentlyUsed(t *testing.T) {
+	var calls int
+	_, handler := newTestCache(&calls)
+
+	get(handler, "/v1/profile/1", "")
+	get(handler, "/v1/profile/2", "")
+	get(handler, "/v1/profile/1", "")
+	get(handler, "/v1/profile/3", "")
+
+	if rec := get(handler, "/v1/profile/1", ""); rec.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("Expected recently used entry to stay cached")
+	}
+	if rec := get(handler, "/v1/profile/2", ""); rec.Header().Get(CacheStatusHeader) != "MISS" {
+		t.Errorf("Expected least recently used entry to be evicted")
+	}
+}
@@ -1,20 +1,220 @@
 package middleware
 
-import "net/http"
-
-// CORS adds CORS headers for browser access
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chat/gateway/internal/config"
+)
+
+// CORS returns middleware that applies the configured cross-origin policy.
+// Requests from origins outside the allowlist are passed through without CORS headers,
+// so the browser blocks them; disallowed preflights are rejected with 403.
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	p := newCORSPolicy(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isPreflight(r) {
+				p.handlePreflight(w, r)
+				return
+			}
+
+			p.handleActual(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsPolicy is the normalized form of config.CORSConfig used at request time
+type corsPolicy struct {
+	allowAnyOrigin   bool
+	exactOrigins     map[string]struct{}
+	wildcardOrigins  []wildcardOrigin
+	methods          []string
+	headers          map[string]struct{}
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+	routes           []config.CORSRoute
+}
+
+// wildcardOrigin matches "scheme://*.domain" against any subdomain of domain
+type wildcardOrigin struct {
+	prefix string // e.g. "https://"
+	suffix string // e.g. ".example.com"
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		exactOrigins:     make(map[string]struct{}),
+		methods:          upperAll(cfg.AllowedMethods),
+		headers:          make(map[string]struct{}),
+		allowedHeaders:   strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders:   strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			p.allowAnyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "*")
+			p.wildcardOrigins = append(p.wildcardOrigins, wildcardOrigin{prefix: scheme, suffix: host})
+		default:
+			p.exactOrigins[origin] = struct{}{}
 		}
+	}
+
+	for _, h := range cfg.AllowedHeaders {
+		p.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	for _, route := range cfg.Routes {
+		p.routes = append(p.routes, config.CORSRoute{
+			PathPrefix: route.PathPrefix,
+			Methods:    upperAll(route.Methods),
+		})
+	}
+
+	return p
 }
 
+// handleActual decorates a non-preflight response with CORS headers when the origin is allowed
+func (p *corsPolicy) handleActual(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+
+	if !p.isOriginAllowed(origin) || !slices.Contains(p.methodsFor(r.URL.Path), r.Method) {
+		return
+	}
+
+	p.setAllowOrigin(h, origin)
+	if p.exposedHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+	}
+}
+
+// handlePreflight answers an OPTIONS preflight request without calling the backend
+func (p *corsPolicy) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	methods := p.methodsFor(r.URL.Path)
+	reqMethod := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+
+	if !p.isOriginAllowed(origin) || !slices.Contains(methods, reqMethod) || !p.areHeadersAllowed(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p.setAllowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if p.allowedHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", p.allowedHeaders)
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAllowOrigin writes the allowed origin. Origins listed explicitly are echoed back,
+// with credentials when allowed; an origin admitted only by "*" gets a plain "*" and never
+// credentials, as that would let any site make credentialed requests.
+func (p *corsPolicy) setAllowOrigin(h http.Header, origin string) {
+	if !p.isOriginListed(origin) {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *corsPolicy) isOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	return p.allowAnyOrigin || p.isOriginListed(origin)
+}
+
+// isOriginListed reports whether origin matches an exact or subdomain entry, rather than "*"
+func (p *corsPolicy) isOriginListed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if _, ok := p.exactOrigins[origin]; ok {
+		return true
+	}
+
+	for _, w := range p.wildcardOrigins {
+		if strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) &&
+			len(origin) > len(w.prefix)+len(w.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// areHeadersAllowed checks every header listed in Access-Control-Request-Headers against the allowlist
+func (p *corsPolicy) areHeadersAllowed(r *http.Request) bool {
+	for _, line := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(line, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := p.headers[http.CanonicalHeaderKey(name)]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// methodsFor returns the methods allowed for path, using the longest matching route prefix
+func (p *corsPolicy) methodsFor(path string) []string {
+	methods := p.methods
+	longest := -1
+	for _, route := range p.routes {
+		if strings.HasPrefix(path, route.PathPrefix) && len(route.PathPrefix) > longest {
+			methods = route.Methods
+			longest = len(route.PathPrefix)
+		}
+	}
+	return methods
+}
+
+// isPreflight reports whether r is a CORS preflight rather than a plain OPTIONS request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func upperAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToUpper(v)
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+)
+
+func testCORSConfig() config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"RateLimit-Limit", "RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+		Routes: []config.CORSRoute{
+			{PathPrefix: "/v1/auth/", Methods: []string{"POST"}},
+		},
+	}
+}
+
+func serveCORS(cfg config.CORSConfig, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	nextCalled := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	CORS(cfg)(next).ServeHTTP(rec, req)
+	return rec, nextCalled
+}
+
+func TestCORS_AllowedOrigin_EchoesOriginWithCredentials(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+
+	rec, nextCalled := serveCORS(testCORSConfig(), req)
+
+	if !nextCalled {
+		t.Fatal("Expected next handler to be called")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected echoed origin, got '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials to be allowed, got '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "RateLimit-Limit, RateLimit-Remaining" {
+		t.Errorf("Unexpected exposed headers: '%s'", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected 'Vary: Origin', got '%s'", got)
+	}
+}
+
+func TestCORS_DisallowedOrigin_OmitsCORSHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://evil.example.net")
+
+	rec, nextCalled := serveCORS(testCORSConfig(), req)
+
+	if !nextCalled {
+		t.Fatal("Expected next handler to be called for simple request")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin header, got '%s'", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected 'Vary: Origin' even for disallowed origin, got '%s'", got)
+	}
+}
+
+func TestCORS_NoOrigin_PassesThrough(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+
+	rec, nextCalled := serveCORS(testCORSConfig(), req)
+
+	if !nextCalled {
+		t.Fatal("Expected next handler to be called")
+	}
+	if len(rec.Header()) != 0 {
+		t.Errorf("Expected no headers for same-origin request, got %v", rec.Header())
+	}
+}
+
+func TestCORS_WildcardSubdomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{"subdomain", "https://web.example.org", true},
+		{"nested subdomain", "https://a.b.example.org", true},
+		{"bare domain", "https://example.org", false},
+		{"wrong scheme", "http://web.example.org", false},
+		{"suffix attack", "https://webexample.org", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+			req.Header.Set("Origin", tt.origin)
+
+			rec, _ := serveCORS(testCORSConfig(), req)
+
+			got := rec.Header().Get("Access-Control-Allow-Origin") != ""
+			if got != tt.allowed {
+				t.Errorf("Origin %s: expected allowed=%v, got %v", tt.origin, tt.allowed, got)
+			}
+		})
+	}
+}
+
+func TestCORS_Preflight_AllowedRequest_Returns204(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, authorization")
+
+	rec, nextCalled := serveCORS(testCORSConfig(), req)
+
+	if nextCalled {
+		t.Error("Preflight should not reach the backend")
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE" {
+		t.Errorf("Unexpected allowed methods: '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected max-age 600, got '%s'", got)
+	}
+}
+
+func TestCORS_Preflight_RouteMethods(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		expectedCode int
+	}{
+		{"allowed method", "POST", http.StatusNoContent},
+		{"method not allowed on route", "DELETE", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/v1/auth/login", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+
+			rec, _ := serveCORS(testCORSConfig(), req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCORS_Preflight_DisallowedHeader_Returns403(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+
+	rec, _ := serveCORS(testCORSConfig(), req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCORS_Preflight_DisallowedOrigin_Returns403(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://evil.example.net")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+
+	rec, _ := serveCORS(testCORSConfig(), req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin header, got '%s'", got)
+	}
+}
+
+func TestCORS_PlainOptions_PassesThrough(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chats", nil)
+
+	_, nextCalled := serveCORS(testCORSConfig(), req)
+
+	if !nextCalled {
+		t.Error("Non-preflight OPTIONS should reach the next handler")
+	}
+}
+
+func TestCORS_AnyOriginWithoutCredentials_ReturnsWildcard(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	cfg.AllowCredentials = false
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set("Origin", "https://anything.test")
+
+	rec, _ := serveCORS(cfg, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected '*', got '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Expected no credentials header, got '%s'", got)
+	}
+}
+
+func TestCORS_AnyOriginWithCredentials_NeverSendsCredentials(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowedOrigins = []string{"https://app.example.com", "*"}
+	cfg.AllowCredentials = true
+
+	tests := []struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://app.example.com", "https://app.example.com", "true"},
+		{"https://evil.test", "*", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+			req.Header.Set("Origin", tt.origin)
+
+			rec, _ := serveCORS(cfg, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Expected '%s', got '%s'", tt.allowOrigin, got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Expected credentials '%s', got '%s'", tt.credentials, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ETag adds a strong ETag computed over the marshalled body to successful GET responses
+// on routes matching one of the path.Match patterns, and answers a matching If-None-Match
+// with 304, so clients re-rendering the same profiles don't download them again.
+// Other routes, non-200 responses and responses the handler flushes are passed through.
+func ETag(routes []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || !matchesAny(routes, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			if ew.passthrough {
+				return
+			}
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				etag = computeETag(ew.body.Bytes())
+				w.Header().Set("ETag", etag)
+			}
+			if w.Header().Get("Cache-Control") == "" {
+				// Let clients keep the response but revalidate it on every use
+				w.Header().Set("Cache-Control", "private, no-cache")
+			}
+
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(ew.body.Bytes())
+		})
+	}
+}
+
+// matchesAny reports whether p matches one of the path.Match patterns
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter holds back a 200 body until the handler returns so it can be hashed.
+// Any other status, or a flush, switches it to writing straight through.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (e *etagWriter) WriteHeader(code int) {
+	if e.status != 0 {
+		return
+	}
+	e.status = code
+	if code != http.StatusOK {
+		e.passthrough = true
+		e.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (e *etagWriter) Write(p []byte) (int, error) {
+	if e.status == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.passthrough {
+		return e.ResponseWriter.Write(p)
+	}
+	return e.body.Write(p)
+}
+
+// Flush sends whatever was held back and streams the rest of the response unmodified
+func (e *etagWriter) Flush() {
+	if !e.passthrough {
+		e.passthrough = true
+		if e.status == 0 {
+			e.status = http.StatusOK
+		}
+		e.ResponseWriter.WriteHeader(e.status)
+		_, _ = e.ResponseWriter.Write(e.body.Bytes())
+		e.body.Reset()
+	}
+	_ = http.NewResponseController(e.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (e *etagWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// computeETag returns a strong validator derived from the response bytes
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison If-None-Match requires (RFC 9110 13.1.2)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse captures a handler's response so it can be inspected, stored or
+// replaced before anything is sent. Its header map starts empty, so it only holds
+// the headers set by the wrapped handler.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header)}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// writeTo sends the captured response
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) {
+	copyHeader(w.Header(), b.header)
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, values := range src {
+		dst[k] = append([]string(nil), values...)
+	}
+}
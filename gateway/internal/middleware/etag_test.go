@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// profileRoutes scopes ETags to profile lookups as the default config does
+var profileRoutes = []string{"/v1/profile/*"}
+
+func TestETag_MatchingIfNoneMatch_Returns304(t *testing.T) {
+	handler := ETag(profileRoutes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"nickname":"alice"}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/profile/42", nil))
+
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d and '%s'", rec.Code, etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Expected Cache-Control 'private, no-cache', got '%s'", cc)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"same tag", etag, http.StatusNotModified},
+		{"weak same tag", "W/" + etag, http.StatusNotModified},
+		{"tag in list", `"other", ` + etag, http.StatusNotModified},
+		{"wildcard", "*", http.StatusNotModified},
+		{"other tag", `"other"`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/profile/42", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("Expected empty body on 304, got %q", rec.Body.String())
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("Expected ETag '%s', got '%s'", etag, rec.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestETag_ErrorsAndWrites_AreUntouched(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"error response", http.MethodGet, http.StatusNotFound},
+		{"write method", http.MethodPost, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ETag(profileRoutes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/v1/profile/42", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if etag := rec.Header().Get("ETag"); etag != "" {
+				t.Errorf("Expected no ETag, got '%s'", etag)
+			}
+		})
+	}
+}
+
+func TestETag_UnlistedRoute_IsUntouched(t *testing.T) {
+	handler := ETag(profileRoutes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"chats":[]}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/chats", nil))
+
+	if etag := rec.Header().Get("ETag"); etag != "" {
+		t.Errorf("Expected no ETag, got '%s'", etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "" {
+		t.Errorf("Expected no Cache-Control, got '%s'", cc)
+	}
+}
+
+func TestETag_FlushedResponse_StreamsThrough(t *testing.T) {
+	handler := ETag(profileRoutes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Expected flush to reach the recorder, got %v", err)
+		}
+		_, _ = w.Write([]byte(" second"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/profile/42", nil))
+
+	if !rec.Flushed {
+		t.Error("Expected the response to be flushed")
+	}
+	if rec.Body.String() != "first second" {
+		t.Errorf("Expected body 'first second', got %q", rec.Body.String())
+	}
+	if etag := rec.Header().Get("ETag"); etag != "" {
+		t.Errorf("Expected no ETag on a streamed response, got '%s'", etag)
+	}
+}
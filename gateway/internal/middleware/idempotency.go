@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key identifying a logical request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from an earlier request with the same key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response for a caller and key is stored and replayed to retries with the
+// same payload; a retry with a different payload is rejected with 422.
+type Idempotency struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type idempotencyEntry struct {
+	key         string
+	requestHash [sha256.Size]byte
+	// completed is false while the first request is still being handled
+	completed bool
+	expires   time.Time
+	status    int
+	header    http.Header
+	body      []byte
+}
+
+// NewIdempotency creates the idempotency middleware
+func NewIdempotency(cfg config.IdempotencyConfig) *Idempotency {
+	return &Idempotency{
+		ttl:        cfg.TTL,
+		maxEntries: cfg.MaxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Middleware replays stored responses for retried POST requests.
+// It reads the whole request body, so it must run inside BodyLimit.
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			apierror.WriteStatus(w, r, status.Newf(codes.InvalidArgument,
+				"%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+			if body, err = io.ReadAll(r.Body); err != nil {
+				apierror.WriteStatus(w, r, status.New(codes.InvalidArgument, "failed to read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		key := idempotencyStoreKey(r, idempotencyKey)
+		hash := requestHash(r, body)
+
+		entry, existing := i.acquire(key, hash)
+		if existing {
+			switch {
+			case entry.requestHash != hash:
+				apierror.Write(w, r, http.StatusUnprocessableEntity, status.Newf(codes.InvalidArgument,
+					"%s was already used with a different request", IdempotencyKeyHeader))
+			case !entry.completed:
+				apierror.WriteStatus(w, r, status.Newf(codes.Aborted,
+					"a request with this %s is still in progress", IdempotencyKeyHeader))
+			default:
+				copyHeader(w.Header(), entry.header)
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(entry.status)
+				_, _ = w.Write(entry.body)
+			}
+			return
+		}
+
+		// If the handler panics (http.ErrAbortHandler included) the entry is released,
+		// otherwise every retry with this key would be told it is still in progress
+		completed := false
+		defer func() {
+			if !completed {
+				i.release(entry)
+			}
+		}()
+
+		buf := newBufferedResponse()
+		next.ServeHTTP(buf, r)
+		i.complete(entry, buf)
+		completed = true
+		buf.writeTo(w)
+	})
+}
+
+// acquire returns the live entry for key, or registers an in-progress entry for the
+// caller to complete. existing reports which of the two happened.
+func (i *Idempotency) acquire(key string, hash [sha256.Size]byte) (entry *idempotencyEntry, existing bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if elem, ok := i.entries[key]; ok {
+		entry := elem.Value.(*idempotencyEntry)
+		if !entry.completed || i.now().Before(entry.expires) {
+			i.lru.MoveToFront(elem)
+			return entry, true
+		}
+		i.remove(elem)
+	}
+
+	entry = &idempotencyEntry{key: key, requestHash: hash}
+	i.entries[key] = i.lru.PushFront(entry)
+	for i.maxEntries > 0 && i.lru.Len() > i.maxEntries {
+		i.remove(i.lru.Back())
+	}
+	return entry, false
+}
+
+// complete stores the response for replay. Server errors and rate-limit rejections
+// are dropped instead, so the client's retry is handled afresh.
+func (i *Idempotency) complete(entry *idempotencyEntry, buf *bufferedResponse) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	elem, ok := i.entries[entry.key]
+	if !ok || elem.Value != entry {
+		// Evicted while in progress
+		return
+	}
+
+	code := buf.status
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		i.remove(elem)
+		return
+	}
+
+	entry.completed = true
+	entry.expires = i.now().Add(i.ttl)
+	entry.status = code
+	entry.header = buf.header.Clone()
+	entry.body = append([]byte(nil), buf.body.Bytes()...)
+}
+
+// release drops an in-progress entry whose request never produced a response
+func (i *Idempotency) release(entry *idempotencyEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if elem, ok := i.entries[entry.key]; ok && elem.Value == entry {
+		i.remove(elem)
+	}
+}
+
+func (i *Idempotency) remove(elem *list.Element) {
+	i.lru.Remove(elem)
+	delete(i.entries, elem.Value.(*idempotencyEntry).key)
+}
+
+// idempotencyStoreKey scopes a key to the caller, so clients can't replay each other's responses.
+// The caller is the user verified by the gateway rather than their token, so a retry made
+// after refreshing the access token still finds the stored response.
+func idempotencyStoreKey(r *http.Request, idempotencyKey string) string {
+	return callerScope(r) + "\x00" + idempotencyKey
+}
+
+// callerScope identifies the user the gateway verified the request's access token for,
+// or is empty for anonymous requests
+func callerScope(r *http.Request) string {
+	if caller, ok := grpc_middleware.CallerFromContext(r.Context()); ok && caller.UserID != "" {
+		return "user:" + caller.UserID
+	}
+	return ""
+}
+
+// requestHash fingerprints the parts of a request that determine its effect
+func requestHash(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\x00")
+	_, _ = h.Write(body)
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/lib/grpc_middleware"
+)
+
+// post sends a request as if the gateway had verified an access token for user
+func post(handler http.Handler, key, user, body string) *httptest.ResponseRecorder {
+	return postWithToken(handler, key, user, "token-"+user, body)
+}
+
+func postWithToken(handler http.Handler, key, user, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chats", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = req.WithContext(grpc_middleware.ContextWithCaller(req.Context(), grpc_middleware.Caller{
+		Kind:   grpc_middleware.CallerUser,
+		UserID: user,
+	}))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestIdempotency(status *int, calls *int) (*Idempotency, http.Handler) {
+	idem := NewIdempotency(config.IdempotencyConfig{TTL: time.Hour, MaxEntries: 100})
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(*status)
+		_, _ = fmt.Fprintf(w, `{"call":%d,"echo":%q}`, *calls, body)
+	}))
+	return idem, handler
+}
+
+func TestIdempotency_Retry_ReplaysResponse(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	first := post(handler, "key-1", "alice", `{"name":"team"}`)
+	second := post(handler, "key-1", "alice", `{"name":"team"}`)
+
+	if calls != 1 {
+		t.Errorf("Expected backend to be called once, got %d", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replay of %d %q, got %d %q", first.Code, first.Body.String(), second.Code, second.Body.String())
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("Expected replayed response to be marked")
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Expected first response not to be marked as replayed")
+	}
+}
+
+func TestIdempotency_DifferentPayload_Returns422(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	post(handler, "key-1", "alice", `{"name":"team"}`)
+	rec := post(handler, "key-1", "alice", `{"name":"other"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", rec.Code)
+	}
+	if calls != 1 {
+		t.Errorf("Expected backend to be called once, got %d", calls)
+	}
+}
+
+func TestIdempotency_KeysScopedPerCaller(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	post(handler, "key-1", "alice", `{}`)
+	post(handler, "key-1", "bob", `{}`)
+
+	if calls != 2 {
+		t.Errorf("Expected each caller to reach the backend, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_ServerError_NotStored(t *testing.T) {
+	status, calls := http.StatusServiceUnavailable, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	post(handler, "key-1", "alice", `{}`)
+	status = http.StatusOK
+	rec := post(handler, "key-1", "alice", `{}`)
+
+	if calls != 2 || rec.Code != http.StatusOK {
+		t.Errorf("Expected retry after a server error to reach the backend, got %d calls and status %d", calls, rec.Code)
+	}
+}
+
+func TestIdempotency_Expired_HandledAfresh(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	idem, handler := newTestIdempotency(&status, &calls)
+
+	now := time.Now()
+	idem.now = func() time.Time { return now }
+	post(handler, "key-1", "alice", `{}`)
+
+	now = now.Add(2 * time.Hour)
+	post(handler, "key-1", "alice", `{"changed":true}`)
+
+	if calls != 2 {
+		t.Errorf("Expected expired key to be reusable, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_InProgress_Returns409(t *testing.T) {
+	idem := NewIdempotency(config.IdempotencyConfig{TTL: time.Hour})
+	var inner http.Handler
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A retry arriving while the first request is still being handled
+		rec := post(inner, "key-1", "alice", `{}`)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 for concurrent retry, got %d", rec.Code)
+		}
+	}))
+	inner = handler
+
+	post(handler, "key-1", "alice", `{}`)
+}
+
+func TestIdempotency_HandlerPanic_ReleasesKey(t *testing.T) {
+	idem := NewIdempotency(config.IdempotencyConfig{TTL: time.Hour})
+	calls := 0
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic(http.ErrAbortHandler)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("Expected the handler panic to propagate, got %v", recovered)
+			}
+		}()
+		post(handler, "key-1", "alice", `{}`)
+	}()
+	rec := post(handler, "key-1", "alice", `{}`)
+
+	if calls != 2 || rec.Code != http.StatusCreated {
+		t.Errorf("Expected retry after a panic to reach the backend, got %d calls and status %d", calls, rec.Code)
+	}
+}
+
+func TestIdempotency_WithoutKey_PassesThrough(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	post(handler, "", "alice", `{}`)
+	rec := post(handler, "", "alice", `{}`)
+
+	if calls != 2 || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Expected requests without a key to reach the backend, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_RetryAfterTokenRefresh_ReplaysResponse(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	_, handler := newTestIdempotency(&status, &calls)
+
+	postWithToken(handler, "key-1", "alice", "expired-token", `{}`)
+	rec := postWithToken(handler, "key-1", "alice", "refreshed-token", `{}`)
+
+	if calls != 1 {
+		t.Errorf("Expected the retry with a refreshed token to be replayed, handler ran %d times", calls)
+	}
+	if rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("Expected replayed response")
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestLogKey struct{}
+
+// requestLog collects fields discovered by inner handlers, such as the authenticated user
+type requestLog struct {
+	userID string
+}
+
+// SetUserID records the authenticated user ID for the request log line.
+// It is a no-op outside of the Logging middleware.
+func SetUserID(ctx context.Context, userID string) {
+	if l, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		l.userID = userID
+	}
+}
+
+// Logging returns middleware that writes one structured log line per HTTP request
+// with method, path, status code, latency, user ID, peer, request ID and trace ID
+func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &requestLog{}
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			ctx := context.WithValue(r.Context(), requestLogKey{}, entry)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", sw.status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", sw.bytes),
+				slog.String("request_id", grpc_middleware.RequestIDFromContext(ctx)),
+				slog.String("user_id", entry.userID),
+				slog.String("peer", r.RemoteAddr),
+			}
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+			}
+
+			logger.LogAttrs(ctx, levelForStatus(sw.status), "http request", attrs...)
+		})
+	}
+}
+
+// levelForStatus logs server-side failures at error level and client mistakes at warn level
+func levelForStatus(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// statusWriter records the status code and body size written by the wrapped handler
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so streaming handlers can still flush
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush implements http.Flusher for handlers that type-assert rather than use http.ResponseController
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
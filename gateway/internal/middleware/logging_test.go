@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogging_LogsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), "user-1")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats/123", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.RemoteAddr = "10.0.0.1:5000"
+	rec := httptest.NewRecorder()
+
+	RequestID(Logging(logger)(next)).ServeHTTP(rec, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log line %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"level":      "WARN",
+		"method":     "GET",
+		"path":       "/v1/chats/123",
+		"status":     float64(http.StatusNotFound),
+		"bytes":      float64(len("not found")),
+		"request_id": "req-1",
+		"user_id":    "user-1",
+		"peer":       "10.0.0.1:5000",
+	}
+	for key, want := range expected {
+		if got := entry[key]; got != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestLogging_ImplicitOK(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	rec := httptest.NewRecorder()
+	Logging(logger)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/chats", nil))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log line: %v", err)
+	}
+	if entry["status"] != float64(http.StatusOK) || entry["level"] != "INFO" {
+		t.Errorf("Expected status 200 at INFO, got %v at %v", entry["status"], entry["level"])
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the HTTP header carrying the correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they can't bloat logs and metadata
+const maxRequestIDLength = 128
+
+// RequestID propagates the client's X-Request-ID (or generates one), stores it in the
+// request context and echoes it in the response so clients can quote it in bug reports
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = grpc_middleware.NewRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := grpc_middleware.ContextWithRequestID(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDMetadata is a grpc-gateway metadata annotator that forwards the request ID to backend services
+func RequestIDMetadata(ctx context.Context, _ *http.Request) metadata.MD {
+	id := grpc_middleware.RequestIDFromContext(ctx)
+	if id == "" {
+		return nil
+	}
+	return metadata.Pairs(grpc_middleware.RequestIDMetadataKey, id)
+}
+
+// isValidRequestID accepts non-empty printable ASCII IDs of bounded length
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chat/lib/grpc_middleware"
+)
+
+func TestRequestID_PropagatesClientID(t *testing.T) {
+	var ctxID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = grpc_middleware.RequestIDFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set(RequestIDHeader, "client-req-1")
+	rec := httptest.NewRecorder()
+
+	RequestID(next).ServeHTTP(rec, req)
+
+	if ctxID != "client-req-1" {
+		t.Errorf("Expected context request ID 'client-req-1', got '%s'", ctxID)
+	}
+	if got := rec.Header().Get(RequestIDHeader); got != "client-req-1" {
+		t.Errorf("Expected echoed request ID 'client-req-1', got '%s'", got)
+	}
+}
+
+func TestRequestID_InvalidOrMissingID_GeneratesNew(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"missing", ""},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1)},
+		{"control characters", "id\nwith-newline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+			if tt.id != "" {
+				req.Header.Set(RequestIDHeader, tt.id)
+			}
+			rec := httptest.NewRecorder()
+
+			RequestID(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got == tt.id {
+				t.Errorf("Expected a newly generated request ID, got '%s'", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDMetadata(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+
+	if md := RequestIDMetadata(context.Background(), req); md != nil {
+		t.Errorf("Expected no metadata without request ID, got %v", md)
+	}
+
+	ctx := grpc_middleware.ContextWithRequestID(context.Background(), "req-1")
+	md := RequestIDMetadata(ctx, req)
+	if got := md.Get(grpc_middleware.RequestIDMetadataKey); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("Expected request ID in metadata, got %v", got)
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	instrumentationName = "github.com/go-chat/gateway/internal/middleware"
+
+	// unmatchedRoute labels requests that never reached a grpc-gateway route,
+	// keeping metric cardinality bounded for 404s and non-API paths
+	unmatchedRoute = "unmatched"
+)
+
+type routeKey struct{}
+
+// routeInfo is filled in by the grpc-gateway metadata annotator once the route is known
+type routeInfo struct {
+	pattern string
+}
+
+// Telemetry traces HTTP requests and records RED metrics labelled by route pattern
+type Telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	requests   metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// NewTelemetry creates HTTP telemetry from the given providers, using the globally installed propagator
+func NewTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*Telemetry, error) {
+	meter := mp.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("http.server.requests",
+		metric.WithDescription("Number of HTTP requests, by method, route and status code"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requests counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("http.server.duration",
+		metric.WithDescription("Duration of HTTP requests, by method, route and status code"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &Telemetry{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: otel.GetTextMapPropagator(),
+		requests:   requests,
+		duration:   duration,
+	}, nil
+}
+
+// Middleware starts a server span per request, continuing any W3C traceparent sent by the client
+func (t *Telemetry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := t.tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("request_id", grpc_middleware.RequestIDFromContext(ctx)),
+			),
+		)
+		defer span.End()
+
+		route := &routeInfo{pattern: unmatchedRoute}
+		ctx = context.WithValue(ctx, routeKey{}, route)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if route.pattern != unmatchedRoute {
+			span.SetName(r.Method + " " + route.pattern)
+		}
+		span.SetAttributes(
+			attribute.String("http.route", route.pattern),
+			attribute.Int("http.response.status_code", sw.status),
+		)
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(sw.status))
+		}
+
+		attrs := metric.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route.pattern),
+			attribute.String("http.response.status_code", strconv.Itoa(sw.status)),
+		)
+		t.requests.Add(ctx, 1, attrs)
+		t.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+	})
+}
+
+// Metadata is a grpc-gateway metadata annotator that injects the W3C trace context into
+// backend gRPC metadata and records the matched route pattern for span names and metrics
+func (t *Telemetry) Metadata(ctx context.Context, r *http.Request) metadata.MD {
+	if route, ok := ctx.Value(routeKey{}).(*routeInfo); ok {
+		if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+			route.pattern = pattern
+		} else if r != nil && r.Pattern != "" {
+			// Handlers mounted directly on the http.ServeMux, such as the BFF endpoints
+			route.pattern = r.Pattern
+			if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+				route.pattern = path
+			}
+		}
+	}
+
+	md := metadata.MD{}
+	t.propagator.Inject(ctx, grpc_middleware.MetadataCarrier(md))
+	return md
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTelemetry(t *testing.T) (*Telemetry, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewManualReader()))
+
+	tm, err := NewTelemetry(tp, mp)
+	if err != nil {
+		t.Fatalf("NewTelemetry() failed: %v", err)
+	}
+	return tm, exporter
+}
+
+func TestTelemetry_Middleware_ContinuesTraceparent(t *testing.T) {
+	tm, exporter := newTestTelemetry(t)
+
+	handler := tm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chats", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected span to continue incoming trace, got trace ID %s", got)
+	}
+	if got := spans[0].Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected parent span ID from traceparent, got %s", got)
+	}
+}
+
+func TestTelemetry_Middleware_MarksServerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantError bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"not found", http.StatusNotFound, false},
+		{"bad gateway", http.StatusBadGateway, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, exporter := newTestTelemetry(t)
+
+			handler := tm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chats", nil))
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			if got := spans[0].Status.Code == otelcodes.Error; got != tt.wantError {
+				t.Errorf("Expected error status %v, got %v", tt.wantError, got)
+			}
+		})
+	}
+}
+
+func TestTelemetry_Metadata_InjectsTraceparent(t *testing.T) {
+	tm, _ := newTestTelemetry(t)
+
+	var injected []string
+	handler := tm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected = tm.Metadata(r.Context(), r).Get("traceparent")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chats", nil))
+
+	if len(injected) != 1 || len(injected[0]) != 55 {
+		t.Errorf("Expected a W3C traceparent in backend metadata, got %v", injected)
+	}
+}
+
+func TestTelemetry_Metadata_WithoutSpan_InjectsNothing(t *testing.T) {
+	tm, _ := newTestTelemetry(t)
+
+	md := tm.Metadata(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(md) != 0 {
+		t.Errorf("Expected empty metadata without an active span, got %v", md)
+	}
+}
+
+func TestTelemetry_Metadata_ServeMuxPattern_NamesSpan(t *testing.T) {
+	tm, exporter := newTestTelemetry(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/users/{id}/card", func(w http.ResponseWriter, r *http.Request) {
+		tm.Metadata(r.Context(), r)
+	})
+	tm.Middleware(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/users/42/card", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /v1/users/{id}/card" {
+		t.Errorf("Expected span named after the route pattern, got '%s'", spans[0].Name)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chat/gateway/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Versioning signals deprecated routes to clients and counts requests per API version,
+// so a version or endpoint can be retired once its usage drops to zero
+type Versioning struct {
+	deprecations []config.Deprecation
+	requests     metric.Int64Counter
+}
+
+// NewVersioning creates the versioning middleware for the configured deprecations
+func NewVersioning(cfg config.APIConfig, mp metric.MeterProvider) (*Versioning, error) {
+	requests, err := mp.Meter(instrumentationName).Int64Counter("http.server.api_requests",
+		metric.WithDescription("Number of API requests, by API version, route and whether the route is deprecated"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API requests counter: %w", err)
+	}
+
+	return &Versioning{
+		deprecations: cfg.Deprecations,
+		requests:     requests,
+	}, nil
+}
+
+// Middleware adds Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers to deprecated
+// routes and records usage for requests under a version prefix such as /v1/.
+// It must run inside the telemetry middleware to label usage by route pattern.
+func (v *Versioning) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, ok := apiVersion(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		deprecation, deprecated := v.match(r)
+		if deprecated {
+			setDeprecationHeaders(w.Header(), deprecation)
+		}
+
+		next.ServeHTTP(w, r)
+
+		route := unmatchedRoute
+		if info, ok := r.Context().Value(routeKey{}).(*routeInfo); ok {
+			route = info.pattern
+		}
+		v.requests.Add(r.Context(), 1, metric.WithAttributes(
+			attribute.String("api.version", version),
+			attribute.String("http.route", route),
+			attribute.Bool("api.deprecated", deprecated),
+		))
+	})
+}
+
+// match returns the first deprecation covering the request
+func (v *Versioning) match(r *http.Request) (config.Deprecation, bool) {
+	for _, d := range v.deprecations {
+		if d.Method != "" && d.Method != r.Method {
+			continue
+		}
+		if ok, _ := path.Match(d.Pattern, r.URL.Path); ok {
+			return d, true
+		}
+	}
+	return config.Deprecation{}, false
+}
+
+func setDeprecationHeaders(h http.Header, d config.Deprecation) {
+	if d.DeprecatedAt.IsZero() {
+		// Deprecated without a recorded date; the epoch still reads as "already deprecated"
+		h.Set("Deprecation", "@0")
+	} else {
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.DeprecatedAt.Unix()))
+	}
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, d.Link))
+	}
+}
+
+// apiVersion extracts the version from paths like /v2/chats
+func apiVersion(p string) (string, bool) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	if len(segment) < 2 || segment[0] != 'v' {
+		return "", false
+	}
+	for _, c := range segment[1:] {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return segment, true
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newTestVersioning(t *testing.T, cfg config.APIConfig) (*Versioning, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	v, err := NewVersioning(cfg, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("NewVersioning() failed: %v", err)
+	}
+	return v, reader
+}
+
+func TestVersioning_DeprecatedRoute_SetsHeaders(t *testing.T) {
+	deprecatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	v, _ := newTestVersioning(t, config.APIConfig{
+		Deprecations: []config.Deprecation{{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/profile/*",
+			DeprecatedAt: deprecatedAt,
+			Sunset:       sunset,
+			Link:         "https://docs.example.com/migrate",
+		}},
+	})
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantDeprecated bool
+	}{
+		{"matching route", http.MethodGet, "/v1/profile/42", true},
+		{"other method", http.MethodPut, "/v1/profile/42", false},
+		{"other route", http.MethodGet, "/v1/chats", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			got := rec.Header().Get("Deprecation")
+			if !tt.wantDeprecated {
+				if got != "" {
+					t.Errorf("Expected no Deprecation header, got '%s'", got)
+				}
+				return
+			}
+			if got != "@1767225600" {
+				t.Errorf("Expected Deprecation '@1767225600', got '%s'", got)
+			}
+			if sunsetHeader := rec.Header().Get("Sunset"); sunsetHeader != "Wed, 01 Jul 2026 00:00:00 GMT" {
+				t.Errorf("Unexpected Sunset header: '%s'", sunsetHeader)
+			}
+			if link := rec.Header().Get("Link"); link != `<https://docs.example.com/migrate>; rel="deprecation"; type="text/html"` {
+				t.Errorf("Unexpected Link header: '%s'", link)
+			}
+		})
+	}
+}
+
+func TestVersioning_RecordsUsagePerVersion(t *testing.T) {
+	v, reader := newTestVersioning(t, config.APIConfig{
+		Deprecations: []config.Deprecation{{Pattern: "/v1/chats"}},
+	})
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, p := range []string{"/v1/chats", "/v1/chats", "/v2/chats", "/healthz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "http.server.api_requests" || !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				version, _ := dp.Attributes.Value(attribute.Key("api.version"))
+				deprecated, _ := dp.Attributes.Value(attribute.Key("api.deprecated"))
+				key := version.AsString()
+				if deprecated.AsBool() {
+					key += " deprecated"
+				}
+				counts[key] += dp.Value
+			}
+		}
+	}
+
+	if counts["v1 deprecated"] != 2 || counts["v2"] != 1 || len(counts) != 2 {
+		t.Errorf("Unexpected usage counts: %v", counts)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/v1/chats", "v1", true},
+		{"/v12/chats", "v12", true},
+		{"/v1", "v1", true},
+		{"/healthz", "", false},
+		{"/vx/chats", "", false},
+		{"/", "", false},
+	}
+
+	for _, tt := range tests {
+		version, ok := apiVersion(tt.path)
+		if version != tt.wantVersion || ok != tt.wantOK {
+			t.Errorf("apiVersion(%q): expected (%q, %v), got (%q, %v)", tt.path, tt.wantVersion, tt.wantOK, version, ok)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// breakerCountsInterval clears closed-circuit counts periodically so the debug view reflects recent traffic
+const breakerCountsInterval = time.Minute
+
+// BreakerState is the debug view of one backend's circuit breaker
+type BreakerState struct {
+	State               string `json:"state"`
+	Requests            uint32 `json:"requests"`
+	TotalFailures       uint32 `json:"total_failures"`
+	ConsecutiveFailures uint32 `json:"consecutive_failures"`
+}
+
+// Breakers holds one circuit breaker per backend. An open circuit fails calls fast with
+// Unavailable, which the gateway renders as 503, instead of queueing them behind a dead backend.
+type Breakers struct {
+	failureThreshold uint32
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*gobreaker.CircuitBreaker[struct{}]
+}
+
+// NewBreakers creates a breaker registry that opens a circuit after failureThreshold
+// consecutive backend failures and keeps it open for openTimeout
+func NewBreakers(failureThreshold int, openTimeout time.Duration) *Breakers {
+	return &Breakers{
+		failureThreshold: uint32(max(failureThreshold, 1)),
+		openTimeout:      openTimeout,
+		breakers:         make(map[string]*gobreaker.CircuitBreaker[struct{}]),
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor guarding calls to the named backend.
+// It wraps the whole call, retries included, so one logical request counts once.
+func (b *Breakers) UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	cb := b.breaker(backend)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		_, err := cb.Execute(func() (struct{}, error) {
+			return struct{}{}, invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			return b.openError(backend)
+		}
+		return err
+	}
+}
+
+// States returns the current state of every breaker, keyed by backend name
+func (b *Breakers) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make(map[string]BreakerState, len(b.breakers))
+	for name, cb := range b.breakers {
+		counts := cb.Counts()
+		states[name] = BreakerState{
+			State:               cb.State().String(),
+			Requests:            counts.Requests,
+			TotalFailures:       counts.TotalFailures,
+			ConsecutiveFailures: counts.ConsecutiveFailures,
+		}
+	}
+	return states
+}
+
+// Handler serves the breaker states as JSON for debugging
+func (b *Breakers) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(b.States())
+	})
+}
+
+func (b *Breakers) breaker(backend string) *gobreaker.CircuitBreaker[struct{}] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cb, ok := b.breakers[backend]; ok {
+		return cb
+	}
+
+	cb := gobreaker.NewCircuitBreaker[struct{}](gobreaker.Settings{
+		Name:     backend,
+		Interval: breakerCountsInterval,
+		Timeout:  b.openTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= b.failureThreshold
+		},
+		IsSuccessful: func(err error) bool {
+			return !isBackendFailure(status.Code(err))
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Printf("Circuit breaker for %s: %s -> %s", name, from, to)
+		},
+	})
+	b.breakers[backend] = cb
+	return cb
+}
+
+// openError tells the client when the circuit will next let a probe through
+func (b *Breakers) openError(backend string) error {
+	st := status.New(codes.Unavailable, backend+" is unavailable: circuit breaker open")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(b.openTimeout)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// isBackendFailure reports whether a code says the backend is unhealthy, as opposed to
+// rejecting the request (NotFound, InvalidArgument, ...) or the caller giving up (Canceled)
+func isBackendFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
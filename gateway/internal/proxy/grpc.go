@@ -2,9 +2,14 @@ package proxy
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"time"
 
 	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/lib/grpc_client"
+	"github.com/go-chat/lib/grpc_middleware"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -16,34 +21,172 @@ import (
 	usersv1 "github.com/go-chat/users/pkg/api/users/v1"
 )
 
-func RegisterServices(ctx context.Context, mux *runtime.ServeMux, cfg *config.Config) error {
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+// serviceName identifies the gateway to the backends in x-service-name
+const serviceName = "gateway"
 
-	if err := authv1.RegisterAuthServiceHandlerFromEndpoint(ctx, mux, cfg.Services.Auth, opts); err != nil {
-		return err
+// Backend names, used as circuit breaker names and connection keys
+const (
+	Auth          = "auth"
+	Users         = "users"
+	Chat          = "chat"
+	Social        = "social"
+	Notifications = "notifications"
+)
+
+// api is one version of a backend's proto API. Versions are separate proto packages
+// (api.chat.v1, api.chat.v2, ...) served side by side over the same connection.
+type api struct {
+	version  string
+	service  string
+	register func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error
+}
+
+// backend describes one proxied gRPC service
+type backend struct {
+	name    string
+	addr    string
+	timeout time.Duration
+	apis    []api
+}
+
+func backendsFromConfig(cfg *config.Config) []backend {
+	timeouts := cfg.Resilience.Timeouts
+	return []backend{
+		{Auth, cfg.Services.Auth, timeouts.Auth, []api{
+			{"v1", authv1.AuthService_ServiceDesc.ServiceName, authv1.RegisterAuthServiceHandler},
+		}},
+		{Users, cfg.Services.Users, timeouts.Users, []api{
+			{"v1", usersv1.UserService_ServiceDesc.ServiceName, usersv1.RegisterUserServiceHandler},
+		}},
+		{Chat, cfg.Services.Chat, timeouts.Chat, []api{
+			{"v1", chatv1.ChatService_ServiceDesc.ServiceName, chatv1.RegisterChatServiceHandler},
+		}},
+		{Social, cfg.Services.Social, timeouts.Social, []api{
+			{"v1", socialv1.SocialService_ServiceDesc.ServiceName, socialv1.RegisterSocialServiceHandler},
+		}},
+		{Notifications, cfg.Services.Notifications, timeouts.Notifications, []api{
+			{"v1", notificationsv1.NotificationService_ServiceDesc.ServiceName, notificationsv1.RegisterNotificationServiceHandler},
+		}},
 	}
-	log.Println("Registered Auth Service")
+}
 
-	if err := usersv1.RegisterUserServiceHandlerFromEndpoint(ctx, mux, cfg.Services.Users, opts); err != nil {
-		return err
+// Backends holds one client connection per backend service. Every connection balances
+// over all DNS-resolved replicas, applies the backend's default deadline, retries
+// GET-mapped RPCs on Unavailable and is guarded by a circuit breaker.
+type Backends struct {
+	backends []backend
+	conns    map[string]*grpc.ClientConn
+	breakers *Breakers
+}
+
+// Dial creates the backend connections. Connections are lazy, so a backend that is
+// down at startup doesn't prevent the gateway from starting.
+func Dial(cfg *config.Config) (*Backends, error) {
+	b := &Backends{
+		backends: backendsFromConfig(cfg),
+		conns:    make(map[string]*grpc.ClientConn),
+		breakers: NewBreakers(cfg.Resilience.BreakerFailureThreshold, cfg.Resilience.BreakerOpenTimeout),
 	}
-	log.Println("Registered Users Service")
 
-	if err := chatv1.RegisterChatServiceHandlerFromEndpoint(ctx, mux, cfg.Services.Chat, opts); err != nil {
-		return err
+	for _, be := range b.backends {
+		services := make([]string, 0, len(be.apis))
+		for _, a := range be.apis {
+			services = append(services, a.service)
+		}
+
+		serviceConfig, err := buildServiceConfig(services, be.timeout, cfg.Resilience.RetryMaxAttempts)
+		if err != nil {
+			_ = b.Close()
+			return nil, err
+		}
+
+		conn, err := grpc.NewClient(grpc_client.DNSTarget(be.addr),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultServiceConfig(serviceConfig),
+			// Calls for a user authenticated by the gateway present the service token and relay
+			// the user; anonymous calls go without credentials, so backends see them as anonymous
+			grpc.WithChainUnaryInterceptor(
+				grpc_middleware.RelayUnaryClientInterceptor(serviceName, cfg.Auth.ServiceToken),
+				b.breakers.UnaryClientInterceptor(be.name),
+			),
+			grpc.WithChainStreamInterceptor(grpc_middleware.RelayStreamClientInterceptor(serviceName, cfg.Auth.ServiceToken)),
+		)
+		if err != nil {
+			_ = b.Close()
+			return nil, fmt.Errorf("failed to create client for %s: %w", be.name, err)
+		}
+		b.conns[be.name] = conn
 	}
-	log.Println("Registered Chat Service")
 
-	if err := socialv1.RegisterSocialServiceHandlerFromEndpoint(ctx, mux, cfg.Services.Social, opts); err != nil {
-		return err
+	return b, nil
+}
+
+// Conn returns the connection to the named backend, or nil if there is none
+func (b *Backends) Conn(name string) *grpc.ClientConn {
+	return b.conns[name]
+}
+
+// Services returns the connection serving each backend proto service, keyed by full service name
+func (b *Backends) Services() map[string]grpc.ClientConnInterface {
+	services := make(map[string]grpc.ClientConnInterface)
+	for _, be := range b.backends {
+		for _, a := range be.apis {
+			services[a.service] = b.conns[be.name]
+		}
 	}
-	log.Println("Registered Social Service")
+	return services
+}
 
-	if err := notificationsv1.RegisterNotificationServiceHandlerFromEndpoint(ctx, mux, cfg.Services.Notifications, opts); err != nil {
-		return err
+// Conns returns the backend connections keyed by backend name
+func (b *Backends) Conns() map[string]*grpc.ClientConn {
+	conns := make(map[string]*grpc.ClientConn, len(b.conns))
+	for name, conn := range b.conns {
+		conns[name] = conn
 	}
-	log.Println("Registered Notifications Service")
+	return conns
+}
 
+// Breakers returns the circuit breakers guarding the backend connections
+func (b *Backends) Breakers() *Breakers {
+	return b.breakers
+}
+
+// Close closes all backend connections
+func (b *Backends) Close() error {
+	for _, conn := range b.conns {
+		_ = conn.Close()
+	}
 	return nil
 }
 
+// Versions returns the API versions served by any backend, sorted
+func (b *Backends) Versions() []string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, be := range b.backends {
+		for _, a := range be.apis {
+			if !seen[a.version] {
+				seen[a.version] = true
+				versions = append(versions, a.version)
+			}
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// RegisterServices registers the grpc-gateway handlers of every backend API of the given version on mux
+func RegisterServices(ctx context.Context, mux *runtime.ServeMux, version string, backends *Backends) error {
+	for _, be := range backends.backends {
+		for _, a := range be.apis {
+			if a.version != version {
+				continue
+			}
+			if err := a.register(ctx, mux, backends.Conn(be.name)); err != nil {
+				return fmt.Errorf("failed to register %s %s handlers: %w", be.name, version, err)
+			}
+			log.Printf("Registered %s service %s", be.name, version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+)
+
+// methodConfigs decodes a service config into the method configs naming each method
+func methodConfigs(t *testing.T, cfgJSON string) map[string][]map[string]interface{} {
+	t.Helper()
+
+	var cfg struct {
+		MethodConfig []map[string]interface{} `json:"methodConfig"`
+	}
+	if err := json.Unmarshal([]byte(cfgJSON), &cfg); err != nil {
+		t.Fatalf("Invalid service config JSON: %v", err)
+	}
+	configs := make(map[string][]map[string]interface{})
+	for _, mc := range cfg.MethodConfig {
+		for _, name := range mc["name"].([]interface{}) {
+			method, _ := name.(map[string]interface{})["method"].(string)
+			configs[method] = append(configs[method], mc)
+		}
+	}
+	return configs
+}
+
+func TestBuildServiceConfig_RetriesOnlyGetMapped(t *testing.T) {
+	cfgJSON, err := buildServiceConfig([]string{chatv1.ChatService_ServiceDesc.ServiceName}, 5*time.Second, 3)
+	if err != nil {
+		t.Fatalf("buildServiceConfig() failed: %v", err)
+	}
+	configs := methodConfigs(t, cfgJSON)
+
+	for _, method := range []string{"GetChat", "ListUserChats", "ListChatMembers", "ListMessages"} {
+		if got := configs[method]; len(got) != 1 || got[0]["retryPolicy"] == nil {
+			t.Errorf("Expected GET-mapped %s to be retried, got %v", method, got)
+		}
+	}
+	for _, method := range []string{"CreateDirectChat", "SendMessage", "StreamMessages"} {
+		for _, mc := range configs[method] {
+			if mc["retryPolicy"] != nil {
+				t.Errorf("Expected %s not to be retried", method)
+			}
+		}
+	}
+	// The unnamed default applies the backend timeout to every other method
+	if got := configs[""]; len(got) != 1 || got[0]["timeout"] != "5s" {
+		t.Errorf("Expected default timeout '5s', got %v", got)
+	}
+}
+
+func TestBuildServiceConfig_StreamsHaveNoTimeout(t *testing.T) {
+	cfgJSON, err := buildServiceConfig([]string{chatv1.ChatService_ServiceDesc.ServiceName}, 5*time.Second, 3)
+	if err != nil {
+		t.Fatalf("buildServiceConfig() failed: %v", err)
+	}
+
+	got := methodConfigs(t, cfgJSON)["StreamMessages"]
+	if len(got) != 1 || got[0]["timeout"] != nil {
+		t.Errorf("Expected StreamMessages to override the default timeout, got %v", got)
+	}
+}
+
+func TestBuildServiceConfig_RetriesDisabled(t *testing.T) {
+	cfgJSON, err := buildServiceConfig([]string{chatv1.ChatService_ServiceDesc.ServiceName}, time.Second, 1)
+	if err != nil {
+		t.Fatalf("buildServiceConfig() failed: %v", err)
+	}
+
+	for method, configs := range methodConfigs(t, cfgJSON) {
+		for _, mc := range configs {
+			if mc["retryPolicy"] != nil {
+				t.Errorf("Expected no retry policy, got one for %q", method)
+			}
+		}
+	}
+}
+
+func TestBuildServiceConfig_UnknownService_ReturnsError(t *testing.T) {
+	if _, err := buildServiceConfig([]string{"api.missing.v1.MissingService"}, time.Second, 3); err == nil {
+		t.Error("Expected error for unknown service")
+	}
+}
+
+// flakyChat fails every call with a fixed number of Unavailable errors before succeeding
+type flakyChat struct {
+	chatv1.UnimplementedChatServiceServer
+	failures int32
+	calls    atomic.Int32
+}
+
+func (f *flakyChat) GetChat(ctx context.Context, req *chatv1.GetChatRequest) (*chatv1.GetChatResponse, error) {
+	if f.calls.Add(1) <= f.failures {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &chatv1.GetChatResponse{}, nil
+}
+
+func (f *flakyChat) SendMessage(ctx context.Context, req *chatv1.SendMessageRequest) (*chatv1.SendMessageResponse, error) {
+	f.calls.Add(1)
+	return nil, status.Error(codes.Unavailable, "try again")
+}
+
+func dialFlakyChat(t *testing.T, srv *flakyChat, breakers *Breakers) chatv1.ChatServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	chatv1.RegisterChatServiceServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	serviceConfig, err := buildServiceConfig([]string{chatv1.ChatService_ServiceDesc.ServiceName}, time.Second, 3)
+	if err != nil {
+		t.Fatalf("buildServiceConfig() failed: %v", err)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(breakers.UnaryClientInterceptor(Chat)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return chatv1.NewChatServiceClient(conn)
+}
+
+func TestServiceConfig_RetriesIdempotentMethods(t *testing.T) {
+	srv := &flakyChat{failures: 2}
+	client := dialFlakyChat(t, srv, NewBreakers(5, time.Second))
+
+	if _, err := client.GetChat(context.Background(), &chatv1.GetChatRequest{}); err != nil {
+		t.Fatalf("Expected GetChat to succeed after retries, got: %v", err)
+	}
+	if got := srv.calls.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestServiceConfig_DoesNotRetryMutations(t *testing.T) {
+	srv := &flakyChat{}
+	client := dialFlakyChat(t, srv, NewBreakers(5, time.Second))
+
+	if _, err := client.SendMessage(context.Background(), &chatv1.SendMessageRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable, got: %v", err)
+	}
+	if got := srv.calls.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestBreakers_OpensAfterConsecutiveFailures(t *testing.T) {
+	srv := &flakyChat{failures: 1000}
+	breakers := NewBreakers(2, time.Minute)
+	client := dialFlakyChat(t, srv, breakers)
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.GetChat(context.Background(), &chatv1.GetChatRequest{})
+	}
+	callsBeforeOpen := srv.calls.Load()
+
+	_, err := client.GetChat(context.Background(), &chatv1.GetChatRequest{})
+
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable from open circuit, got: %v", err)
+	}
+	if srv.calls.Load() != callsBeforeOpen {
+		t.Error("Expected open circuit to fail fast without calling the backend")
+	}
+
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry == nil || retry.GetRetryDelay().AsDuration() != time.Minute {
+		t.Errorf("Expected RetryInfo with the open timeout, got %v", retry)
+	}
+
+	if state := breakers.States()[Chat].State; state != "open" {
+		t.Errorf("Expected breaker state 'open', got '%s'", state)
+	}
+}
+
+func TestBreakers_ClientErrorsDoNotTrip(t *testing.T) {
+	breakers := NewBreakers(1, time.Minute)
+	interceptor := breakers.UnaryClientInterceptor(Users)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.NotFound, "profile not found")
+	}
+
+	for i := 0; i < 3; i++ {
+		err := interceptor(context.Background(), "/api.users.v1.UserService/GetProfileByID", nil, nil, nil, invoker)
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("Expected NotFound to pass through, got: %v", err)
+		}
+	}
+
+	if state := breakers.States()[Users].State; state != "closed" {
+		t.Errorf("Expected breaker state 'closed', got '%s'", state)
+	}
+}
+
+func TestBreakers_Handler_ServesStates(t *testing.T) {
+	breakers := NewBreakers(5, time.Second)
+	breakers.UnaryClientInterceptor(Social)
+
+	rec := httptest.NewRecorder()
+	breakers.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/breakers", nil))
+
+	var states map[string]BreakerState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if states[Social].State != "closed" {
+		t.Errorf("Expected social breaker to be listed as closed, got %+v", states)
+	}
+}
+
+func TestBackends_Versions(t *testing.T) {
+	b := &Backends{backends: []backend{
+		{name: Chat, apis: []api{{version: "v1"}, {version: "v2"}}},
+		{name: Users, apis: []api{{version: "v1"}}},
+	}}
+
+	versions := b.Versions()
+
+	if len(versions) != 2 || versions[0] != "v1" || versions[1] != "v2" {
+		t.Errorf("Expected [v1 v2], got %v", versions)
+	}
+}
+
+func TestBackends_Services(t *testing.T) {
+	b := &Backends{
+		backends: []backend{
+			{name: Chat, apis: []api{{version: "v1", service: "api.chat.v1.ChatService"}, {version: "v2", service: "api.chat.v2.ChatService"}}},
+			{name: Users, apis: []api{{version: "v1", service: "api.users.v1.UserService"}}},
+		},
+		conns: map[string]*grpc.ClientConn{},
+	}
+
+	services := b.Services()
+
+	for _, service := range []string{"api.chat.v1.ChatService", "api.chat.v2.ChatService", "api.users.v1.UserService"} {
+		if _, ok := services[service]; !ok {
+			t.Errorf("Expected connection for %s", service)
+		}
+	}
+	if len(services) != 3 {
+		t.Errorf("Expected 3 services, got %d", len(services))
+	}
+}
+
+func TestBackends_Routes_FromHTTPAnnotations(t *testing.T) {
+	b := &Backends{backends: []backend{
+		{name: Chat, apis: []api{{version: "v1", service: "api.chat.v1.ChatService"}}},
+		{name: Users, apis: []api{{version: "v1", service: "api.unknown.v1.Service"}}},
+	}}
+
+	routes := b.Routes()
+
+	found := false
+	for _, r := range routes {
+		if r.Target == "/api.chat.v1.ChatService/GetChat" {
+			found = true
+			if r.Method != "GET" || r.Path != "/v1/chats/{chat_id}" {
+				t.Errorf("Expected GET /v1/chats/{chat_id}, got %s %s", r.Method, r.Path)
+			}
+		}
+		if !strings.HasPrefix(r.Target, "/api.chat.v1.ChatService/") {
+			t.Errorf("Unexpected route for unknown service: %+v", r)
+		}
+	}
+	if !found {
+		t.Errorf("Expected a route for GetChat, got %v", routes)
+	}
+}
+
+// identityChat records the identity metadata of the calls it receives
+type identityChat struct {
+	chatv1.UnimplementedChatServiceServer
+	md chan metadata.MD
+}
+
+func (c *identityChat) GetChat(ctx context.Context, req *chatv1.GetChatRequest) (*chatv1.GetChatResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.md <- md
+	return &chatv1.GetChatResponse{}, nil
+}
+
+func TestDial_RelaysServiceTokenAndUser(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := &identityChat{md: make(chan metadata.MD, 1)}
+	server := grpc.NewServer()
+	chatv1.RegisterChatServiceServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	cfg := config.New()
+	cfg.Services.Chat = lis.Addr().String()
+	cfg.Auth.ServiceToken = "secret"
+	backends, err := Dial(cfg)
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = backends.Close() })
+
+	ctx := grpc_middleware.ContextWithCaller(context.Background(), grpc_middleware.Caller{Kind: grpc_middleware.CallerUser, UserID: "user-1"})
+	if _, err := chatv1.NewChatServiceClient(backends.Conn(Chat)).GetChat(ctx, &chatv1.GetChatRequest{ChatId: "c1"}); err != nil {
+		t.Fatalf("GetChat() failed: %v", err)
+	}
+
+	md := <-srv.md
+	if got := md.Get(grpc_middleware.ServiceTokenMetadataKey); len(got) != 1 || got[0] != "secret" {
+		t.Errorf("Expected service token 'secret', got %v", got)
+	}
+	if got := md.Get(grpc_middleware.UserIDMetadataKey); len(got) != 1 || got[0] != "user-1" {
+		t.Errorf("Expected relayed user 'user-1', got %v", got)
+	}
+
+	// Anonymous clients must not borrow the gateway's service identity
+	if _, err := chatv1.NewChatServiceClient(backends.Conn(Chat)).GetChat(context.Background(), &chatv1.GetChatRequest{ChatId: "c1"}); err != nil {
+		t.Fatalf("GetChat() failed: %v", err)
+	}
+	md = <-srv.md
+	if got := md.Get(grpc_middleware.ServiceTokenMetadataKey); len(got) != 0 {
+		t.Errorf("Expected no service token on an anonymous call, got %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"github.com/go-chat/lib/admin"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Routes lists the REST routes proxied to the backends, read from the google.api.http
+// annotations the grpc-gateway handlers are generated from
+func (b *Backends) Routes() []admin.Route {
+	var routes []admin.Route
+	for _, be := range b.backends {
+		for _, a := range be.apis {
+			desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(a.service))
+			if err != nil {
+				continue
+			}
+			sd, ok := desc.(protoreflect.ServiceDescriptor)
+			if !ok {
+				continue
+			}
+			routes = append(routes, serviceRoutes(sd)...)
+		}
+	}
+	return routes
+}
+
+func serviceRoutes(sd protoreflect.ServiceDescriptor) []admin.Route {
+	var routes []admin.Route
+	for i := 0; i < sd.Methods().Len(); i++ {
+		method := sd.Methods().Get(i)
+		rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		target := "/" + string(sd.FullName()) + "/" + string(method.Name())
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			if httpMethod, path := httpRulePattern(r); path != "" {
+				routes = append(routes, admin.Route{Method: httpMethod, Path: path, Target: target})
+			}
+		}
+	}
+	return routes
+}
+
+// IsPublic reports whether clients may call method directly over gRPC-Web, Connect or
+// GraphQL: it must have a google.api.http binding, or its full method name
+// (/package.Service/Method) must be listed in allow. Everything else, such as
+// service-to-service RPCs, stays internal.
+func IsPublic(method protoreflect.MethodDescriptor, allow []string) bool {
+	if rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule); ok && rule != nil {
+		return true
+	}
+	fullMethod := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	for _, allowed := range allow {
+		if allowed == fullMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// httpRulePattern returns the HTTP method and path template of a binding
+func httpRulePattern(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "GET", p.Get
+	case *annotations.HttpRule_Put:
+		return "PUT", p.Put
+	case *annotations.HttpRule_Post:
+		return "POST", p.Post
+	case *annotations.HttpRule_Delete:
+		return "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		return "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		return p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return "", ""
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-chat/lib/grpc_client"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// buildServiceConfig returns the service config JSON for a backend connection serving the given
+// services, built by grpc_client from the services' descriptors: round-robin balancing over all
+// resolved addresses, a default deadline for every method but server streams and retries for
+// GET-mapped methods
+func buildServiceConfig(services []string, timeout time.Duration, maxAttempts int) (string, error) {
+	cfg := grpc_client.Config{
+		Timeout: timeout,
+		Retry:   grpc_client.RetryConfig{MaxAttempts: maxAttempts},
+	}
+
+	for _, service := range services {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+		if err != nil {
+			return "", fmt.Errorf("failed to find descriptor for %s: %w", service, err)
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return "", fmt.Errorf("%s is not a service", service)
+		}
+
+		for i := 0; i < sd.Methods().Len(); i++ {
+			method := sd.Methods().Get(i)
+			fullMethod := "/" + service + "/" + string(method.Name())
+			switch {
+			case method.IsStreamingServer():
+				cfg.Streams = append(cfg.Streams, fullMethod)
+			case isIdempotent(method):
+				cfg.Retry.Methods = append(cfg.Retry.Methods, fullMethod)
+			}
+		}
+	}
+
+	return grpc_client.ServiceConfig(cfg)
+}
+
+// isIdempotent reports whether a method is exposed over HTTP GET, which by REST semantics
+// makes it safe to retry; everything else may have side effects and is never retried
+func isIdempotent(method protoreflect.MethodDescriptor) bool {
+	rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+	return ok && rule.GetGet() != ""
+}
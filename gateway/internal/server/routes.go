@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chat/lib/admin"
+)
+
+// routeMux records the patterns mounted on the gateway's top-level mux for the admin
+// route table; http.ServeMux can't list its own routes
+type routeMux struct {
+	*http.ServeMux
+	routes []admin.Route
+}
+
+func newRouteMux() *routeMux {
+	return &routeMux{ServeMux: http.NewServeMux()}
+}
+
+// Handle registers handler for pattern and records the route
+func (m *routeMux) Handle(pattern string, handler http.Handler) {
+	m.ServeMux.Handle(pattern, handler)
+
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	m.routes = append(m.routes, admin.Route{Method: method, Path: path})
+}
+
+// Routes returns the recorded routes
+func (m *routeMux) Routes() []admin.Route {
+	return m.routes
+}
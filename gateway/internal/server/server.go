@@ -2,54 +2,243 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/go-chat/gateway/internal/apierror"
+	"github.com/go-chat/gateway/internal/authn"
+	"github.com/go-chat/gateway/internal/bff"
 	"github.com/go-chat/gateway/internal/config"
+	"github.com/go-chat/gateway/internal/docs"
+	"github.com/go-chat/gateway/internal/graphqlapi"
+	"github.com/go-chat/gateway/internal/health"
 	"github.com/go-chat/gateway/internal/middleware"
 	"github.com/go-chat/gateway/internal/proxy"
+	"github.com/go-chat/gateway/internal/webrpc"
+	"github.com/go-chat/lib/admin"
+	"github.com/go-chat/lib/telemetry"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 )
 
 // Server represents the Gateway HTTP server
 type Server struct {
 	cfg        *config.Config
+	logger     *slog.Logger
 	httpServer *http.Server
+	prober     *health.Prober
+	backends   *proxy.Backends
+	telemetry  *telemetry.Provider
+	admin      *admin.Server
+
+	stopCertReload context.CancelFunc
 }
 
 // New creates a new Gateway server
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config, logger *slog.Logger) *Server {
 	return &Server{
-		cfg: cfg,
+		cfg:    cfg,
+		logger: logger,
 	}
 }
 
 func (s *Server) Start(ctx context.Context) error {
 	log.Println("Gateway starting...")
 
-	grpcMux := runtime.NewServeMux(
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{}),
+	tel, err := telemetry.New(ctx, s.cfg.Telemetry)
+	if err != nil {
+		return err
+	}
+	s.telemetry = tel
+	go func() {
+		if err := tel.ServeMetrics(); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	httpTelemetry, err := middleware.NewTelemetry(tel.TracerProvider(), tel.MeterProvider())
+	if err != nil {
+		return err
+	}
+
+	backends, err := proxy.Dial(s.cfg)
+	if err != nil {
+		return err
+	}
+	s.backends = backends
+	if s.cfg.Auth.ServiceToken == "" {
+		log.Printf("SERVICE_TOKEN is not set; backends will reject every call made for a user")
+	}
+
+	// Access tokens are verified here so only an authenticated user is relayed to the backends
+	verifier := authn.NewVerifier(authn.AuthServiceKeys(backends.Conn(proxy.Auth)),
+		s.cfg.Auth.KeysRefreshInterval, s.cfg.Auth.KeysMinRefreshInterval)
+
+	// Each API version gets its own grpc-gateway mux under its path prefix,
+	// so a new proto version can be added without touching existing routes
+	versionMuxes := make(map[string]*runtime.ServeMux)
+	for _, version := range backends.Versions() {
+		gatewayMux := runtime.NewServeMux(
+			runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{}),
+			runtime.WithErrorHandler(apierror.ErrorHandler),
+			runtime.WithMetadata(middleware.RequestIDMetadata),
+			runtime.WithMetadata(httpTelemetry.Metadata),
+		)
+		if err := proxy.RegisterServices(ctx, gatewayMux, version, backends); err != nil {
+			return err
+		}
+		versionMuxes[version] = gatewayMux
+	}
+
+	versioning, err := middleware.NewVersioning(s.cfg.API, tel.MeterProvider())
+	if err != nil {
+		return err
+	}
+
+	prober, err := health.NewProber(s.cfg)
+	if err != nil {
+		return err
+	}
+	s.prober = prober
+
+	bffHandler := bff.New(
+		backends.Conn(proxy.Chat),
+		backends.Conn(proxy.Users),
+		backends.Conn(proxy.Social),
+		s.cfg.BFF.CallTimeout,
+		bff.WithMetadata(middleware.RequestIDMetadata, httpTelemetry.Metadata),
 	)
 
-	if err := proxy.RegisterServices(ctx, grpcMux, s.cfg); err != nil {
+	graphqlHandler, err := graphqlapi.New(
+		backends.Conn(proxy.Chat),
+		backends.Conn(proxy.Users),
+		backends.Conn(proxy.Social),
+		backends.Conn(proxy.Notifications),
+		s.cfg.GraphQL.CallTimeout,
+		graphqlapi.WithMetadata(middleware.RequestIDMetadata, httpTelemetry.Metadata),
+		graphqlapi.WithPublicMethods(s.cfg.API.PublicMethods...),
+		graphqlapi.WithLimits(s.cfg.GraphQL.MaxDepth, s.cfg.GraphQL.MaxFields),
+	)
+	if err != nil {
 		return err
 	}
 
-	handler := middleware.CORS(grpcMux)
+	docsHandler, err := docs.New()
+	if err != nil {
+		return err
+	}
+
+	mux := newRouteMux()
+	mux.Handle("GET /healthz", health.LivenessHandler())
+	mux.Handle("GET /readyz", prober.ReadinessHandler())
+	bffHandler.Register(mux)
+	graphqlHandler.Register(mux)
+	docsHandler.Register(mux)
+	webrpc.New(
+		backends.Services(),
+		webrpc.WithMetadata(middleware.RequestIDMetadata, httpTelemetry.Metadata),
+		webrpc.WithPublicMethods(s.cfg.API.PublicMethods...),
+	).Register(mux)
+	for version, gatewayMux := range versionMuxes {
+		mux.Handle("/"+version+"/", gatewayMux)
+	}
+	mux.Handle("/", apierror.NotFoundHandler())
+
+	// Debug endpoints live on a separate internal listener, never on the public one
+	s.admin = admin.New(s.cfg.AdminAddr,
+		admin.WithConfig(s.cfg),
+		admin.WithRoutes(mux.Routes),
+		admin.WithRoutes(backends.Routes),
+		admin.WithConnections(backends.Conns()),
+		admin.WithKeys(verifier.Keys),
+		admin.WithHandler("GET /debug/breakers", backends.Breakers().Handler()),
+	)
+	go func() {
+		if err := s.admin.ListenAndServe(); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+
+	// Request ID is outermost so every span, log line and backend call carries it;
+	// telemetry wraps logging so log lines include the trace ID
+	var handler http.Handler = mux
+	handler = middleware.NewResponseCache(s.cfg.Cache).Middleware(handler)
+	handler = middleware.ETag(s.cfg.Cache.ETagRoutes)(handler)
+	handler = middleware.NewIdempotency(s.cfg.Idempotency).Middleware(handler)
+	handler = middleware.BodyLimit(s.cfg.Server)(handler)
+	handler = verifier.Middleware(handler)
+	handler = middleware.CORS(s.cfg.CORS)(handler)
+	handler = versioning.Middleware(handler)
+	handler = middleware.Logging(s.logger)(handler)
+	handler = httpTelemetry.Middleware(handler)
+	handler = middleware.RequestID(handler)
 
 	s.httpServer = &http.Server{
-		Addr:    s.cfg.HTTPPort,
-		Handler: handler,
+		Addr:              s.cfg.HTTPPort,
+		Handler:           handler,
+		ReadTimeout:       s.cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: s.cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      s.cfg.Server.WriteTimeout,
+		IdleTimeout:       s.cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    s.cfg.Server.MaxHeaderBytes,
+	}
+
+	if s.cfg.Server.H2C {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.httpServer.Protocols = &protocols
 	}
 
-	log.Printf("Gateway listening on %s", s.cfg.HTTPPort)
 	log.Printf("Gateway ready to proxy requests to backend services")
 
+	if s.cfg.Server.TLSCertFile != "" && s.cfg.Server.TLSKeyFile != "" {
+		return s.listenAndServeTLS(ctx)
+	}
+
+	log.Printf("Gateway listening on %s", s.cfg.HTTPPort)
 	return s.httpServer.ListenAndServe()
 }
 
+// listenAndServeTLS terminates TLS with a certificate that is reloaded when its files change
+func (s *Server) listenAndServeTLS(ctx context.Context) error {
+	reloader, err := newCertReloader(s.cfg.Server.TLSCertFile, s.cfg.Server.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	s.stopCertReload = cancel
+	go reloader.Watch(watchCtx, s.cfg.Server.TLSReloadInterval)
+
+	s.httpServer.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	log.Printf("Gateway listening on %s (TLS)", s.cfg.HTTPPort)
+	return s.httpServer.ListenAndServeTLS("", "")
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopCertReload != nil {
+		s.stopCertReload()
+	}
+	if s.prober != nil {
+		defer s.prober.Close()
+	}
+	if s.backends != nil {
+		defer s.backends.Close()
+	}
+	if s.telemetry != nil {
+		defer s.telemetry.Shutdown(ctx)
+	}
+	if s.admin != nil {
+		defer s.admin.Shutdown(ctx)
+	}
 	if s.httpServer != nil {
 		return s.httpServer.Shutdown(ctx)
 	}
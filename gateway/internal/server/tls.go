@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the TLS certificate from disk, picking up rotated files
+// (e.g. by cert-manager or certbot) without restarting the gateway
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Watch reloads the certificate every interval while either file has changed, until ctx is done.
+// A failed reload keeps serving the previous certificate.
+func (c *certReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := c.changed()
+			if err != nil {
+				log.Printf("Failed to stat TLS certificate: %v", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := c.reload(); err != nil {
+				log.Printf("Failed to reload TLS certificate, keeping the previous one: %v", err)
+				continue
+			}
+			log.Printf("Reloaded TLS certificate from %s", c.certFile)
+		}
+	}
+}
+
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+func (c *certReloader) changed() (bool, error) {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return modTime.After(c.modTime), nil
+}
+
+// latestModTime returns the newer modification time of the certificate and key files
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for commonName and returns the file paths
+func writeCert(t *testing.T, dir, commonName string, modTime time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mtime on %s: %v", name, err)
+	}
+}
+
+func commonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader_Watch_PicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCert(t, dir, "old", start)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	writeCert(t, dir, "new", start.Add(time.Second))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for commonName(t, reloader) != "new" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected rotated certificate to be served")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCertReloader_InvalidRotation_KeepsPreviousCertificate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCert(t, dir, "old", start)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader() failed: %v", err)
+	}
+
+	writeFile(t, certFile, []byte("not a certificate"), start.Add(time.Second))
+
+	if changed, err := reloader.changed(); err != nil || !changed {
+		t.Fatalf("Expected change to be detected, got %v, %v", changed, err)
+	}
+	if err := reloader.reload(); err == nil {
+		t.Fatal("Expected reload of an invalid certificate to fail")
+	}
+	if got := commonName(t, reloader); got != "old" {
+		t.Errorf("Expected previous certificate to be kept, got '%s'", got)
+	}
+}
+
+func TestNewCertReloader_MissingFiles_ReturnsError(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := newCertReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("Expected error for missing certificate files")
+	}
+}
@@ -0,0 +1,130 @@
+package webrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chat/gateway/internal/apierror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// connectError is the JSON error of the Connect protocol
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	// Type is the fully qualified message name, without the type URL prefix
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newConnectError(st *status.Status) *connectError {
+	e := &connectError{Code: connectCode(st.Code()), Message: st.Message()}
+	for _, detail := range st.Proto().GetDetails() {
+		e.Details = append(e.Details, connectErrorDetail{
+			Type:  string(detail.MessageName()),
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+	return e
+}
+
+// connectCode returns the Connect name of a gRPC code, e.g. not_found
+func connectCode(c codes.Code) string {
+	if c == codes.Canceled {
+		// Connect uses the American spelling
+		return "canceled"
+	}
+	return strings.ToLower(apierror.CodeName(c))
+}
+
+// connectUnaryWriter writes the Connect unary protocol: a bare message with HTTP 200,
+// or a JSON error with the HTTP status mapped from the code. Trailers travel as
+// headers prefixed with "Trailer-", so the response is held until the call ends.
+type connectUnaryWriter struct {
+	w     http.ResponseWriter
+	codec codec
+	body  []byte
+}
+
+func (c *connectUnaryWriter) writeHeader(md metadata.MD) {
+	setMetadataHeaders(c.w.Header(), md, "")
+}
+
+func (c *connectUnaryWriter) writeMessage(msg proto.Message) error {
+	buf, err := c.codec.marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.body = buf
+	return nil
+}
+
+func (c *connectUnaryWriter) writeEnd(st *status.Status, trailer metadata.MD) {
+	setMetadataHeaders(c.w.Header(), trailer, "Trailer-")
+
+	if st.Code() == codes.OK {
+		c.w.Header().Set("Content-Type", "application/"+c.codec.name())
+		c.w.WriteHeader(http.StatusOK)
+		_, _ = c.w.Write(c.body)
+		return
+	}
+
+	buf, err := json.Marshal(newConnectError(st))
+	if err != nil {
+		buf = []byte(`{"code":"internal"}`)
+	}
+	c.w.Header().Set("Content-Type", "application/json")
+	c.w.WriteHeader(apierror.HTTPStatus(st.Code()))
+	_, _ = c.w.Write(buf)
+}
+
+// connectStreamWriter writes the Connect streaming protocol: one envelope per message
+// and a final end-stream envelope holding the error, if any, and the trailers as JSON
+type connectStreamWriter struct {
+	streamWriter
+	codec codec
+}
+
+type connectEndStream struct {
+	Error    *connectError `json:"error,omitempty"`
+	Metadata metadata.MD   `json:"metadata,omitempty"`
+}
+
+func (c *connectStreamWriter) writeHeader(md metadata.MD) {
+	c.writeHeaderWith(md)
+}
+
+func (c *connectStreamWriter) writeMessage(msg proto.Message) error {
+	buf, err := c.codec.marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeEnvelope(0, buf)
+	return nil
+}
+
+func (c *connectStreamWriter) writeEnd(st *status.Status, trailer metadata.MD) {
+	end := connectEndStream{}
+	if st.Code() != codes.OK {
+		end.Error = newConnectError(st)
+	}
+	if len(trailer) > 0 {
+		end.Metadata = metadata.MD{}
+		setMetadataHeaders(http.Header(end.Metadata), trailer, "")
+	}
+
+	buf, err := json.Marshal(end)
+	if err != nil {
+		buf = []byte(`{"error":{"code":"internal"}}`)
+	}
+	c.writeEnvelope(flagConnectEndStream, buf)
+}
@@ -0,0 +1,57 @@
+package webrpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcWebWriter writes the gRPC-Web protocol: headers, one envelope per message and
+// a final envelope carrying the status and trailers as HTTP/1-style header lines
+type grpcWebWriter struct {
+	streamWriter
+	codec codec
+}
+
+func (g *grpcWebWriter) writeHeader(md metadata.MD) {
+	g.writeHeaderWith(md)
+}
+
+func (g *grpcWebWriter) writeMessage(msg proto.Message) error {
+	buf, err := g.codec.marshal(msg)
+	if err != nil {
+		return err
+	}
+	g.writeEnvelope(0, buf)
+	return nil
+}
+
+func (g *grpcWebWriter) writeEnd(st *status.Status, trailer metadata.MD) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeGRPCMessage(st.Message()))
+	}
+	if len(st.Proto().GetDetails()) > 0 {
+		if details, err := proto.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(&b, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(details))
+		}
+	}
+	for key, values := range trailer {
+		if strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		for _, v := range values {
+			if strings.HasSuffix(key, "-bin") {
+				v = encodeBinaryHeader(v)
+			}
+			fmt.Fprintf(&b, "%s: %s\r\n", key, v)
+		}
+	}
+
+	g.writeEnvelope(flagGRPCWebTrailer, []byte(b.String()))
+}
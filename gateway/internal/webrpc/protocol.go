@@ -0,0 +1,252 @@
+package webrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Envelope flags shared by gRPC-Web and the Connect streaming protocol
+const (
+	flagCompressed       = 0x01
+	flagConnectEndStream = 0x02
+	flagGRPCWebTrailer   = 0x80
+)
+
+// maxMessageBytes caps a single request message; BodyLimit already bounds the whole body
+const maxMessageBytes = 4 << 20
+
+// protocol is a wire protocol and message codec negotiated from the request's content type
+type protocol struct {
+	kind      protocolKind
+	codec     codec
+	streaming bool
+}
+
+type protocolKind int
+
+const (
+	grpcWeb protocolKind = iota
+	grpcWebText
+	connectUnary
+	connectStream
+)
+
+// negotiate maps a request content type to its protocol
+func negotiate(contentType string) (protocol, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return protocol{}, false
+	}
+
+	switch mediaType {
+	case "application/grpc-web", "application/grpc-web+proto":
+		return protocol{kind: grpcWeb, codec: protoCodec{}, streaming: true}, true
+	case "application/grpc-web+json":
+		return protocol{kind: grpcWeb, codec: jsonCodec{}, streaming: true}, true
+	case "application/grpc-web-text", "application/grpc-web-text+proto":
+		return protocol{kind: grpcWebText, codec: protoCodec{}, streaming: true}, true
+	case "application/proto":
+		return protocol{kind: connectUnary, codec: protoCodec{}}, true
+	case "application/json":
+		return protocol{kind: connectUnary, codec: jsonCodec{}}, true
+	case "application/connect+proto":
+		return protocol{kind: connectStream, codec: protoCodec{}, streaming: true}, true
+	case "application/connect+json":
+		return protocol{kind: connectStream, codec: jsonCodec{}, streaming: true}, true
+	}
+	return protocol{}, false
+}
+
+// readRequest decodes the single request message from the body
+func (p protocol) readRequest(body io.Reader, msg proto.Message) error {
+	buf, err := io.ReadAll(io.LimitReader(body, maxMessageBytes+5))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "failed to read request body")
+	}
+
+	switch p.kind {
+	case grpcWebText:
+		decoded, err := base64.StdEncoding.DecodeString(string(buf))
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "invalid base64 request body")
+		}
+		if buf, err = unwrapEnvelope(decoded); err != nil {
+			return err
+		}
+	case grpcWeb, connectStream:
+		if buf, err = unwrapEnvelope(buf); err != nil {
+			return err
+		}
+	}
+
+	if len(buf) > maxMessageBytes {
+		return status.Errorf(codes.ResourceExhausted, "request message exceeds %d bytes", maxMessageBytes)
+	}
+	if err := p.codec.unmarshal(buf, msg); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to decode request: %v", err)
+	}
+	return nil
+}
+
+// timeout returns the client's deadline for the call, if it set one
+func (p protocol) timeout(h http.Header) (time.Duration, bool, error) {
+	switch p.kind {
+	case connectUnary, connectStream:
+		v := h.Get("Connect-Timeout-Ms")
+		if v == "" {
+			return 0, false, nil
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			return 0, false, status.Errorf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", v)
+		}
+		return time.Duration(ms) * time.Millisecond, true, nil
+	default:
+		v := h.Get("Grpc-Timeout")
+		if v == "" {
+			return 0, false, nil
+		}
+		d, err := grpcTimeout(v)
+		return d, err == nil, err
+	}
+}
+
+func (p protocol) newWriter(w http.ResponseWriter) responseWriter {
+	switch p.kind {
+	case connectUnary:
+		return &connectUnaryWriter{w: w, codec: p.codec}
+	case connectStream:
+		return &connectStreamWriter{streamWriter: newStreamWriter(w, "application/connect+"+p.codec.name(), false), codec: p.codec}
+	case grpcWebText:
+		return &grpcWebWriter{streamWriter: newStreamWriter(w, "application/grpc-web-text+"+p.codec.name(), true), codec: p.codec}
+	default:
+		return &grpcWebWriter{streamWriter: newStreamWriter(w, "application/grpc-web+"+p.codec.name(), false), codec: p.codec}
+	}
+}
+
+// responseWriter writes a call's outcome in the caller's protocol. writeEnd is always
+// called last, exactly once; writeHeader and writeMessage may be skipped.
+type responseWriter interface {
+	writeHeader(md metadata.MD)
+	writeMessage(msg proto.Message) error
+	writeEnd(st *status.Status, trailer metadata.MD)
+}
+
+// streamWriter writes enveloped messages, flushing each so streamed messages reach the
+// client as they arrive
+type streamWriter struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	contentType string
+	// text base64-encodes each envelope, for gRPC-Web clients that can't read binary bodies
+	text        bool
+	wroteHeader bool
+}
+
+func newStreamWriter(w http.ResponseWriter, contentType string, text bool) streamWriter {
+	return streamWriter{w: w, rc: http.NewResponseController(w), contentType: contentType, text: text}
+}
+
+func (s *streamWriter) writeHeaderWith(md metadata.MD) {
+	if s.wroteHeader {
+		return
+	}
+	s.wroteHeader = true
+	setMetadataHeaders(s.w.Header(), md, "")
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func (s *streamWriter) writeEnvelope(flags byte, payload []byte) {
+	s.writeHeaderWith(nil)
+
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	if s.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	_, _ = s.w.Write(frame)
+	_ = s.rc.Flush()
+}
+
+// unwrapEnvelope returns the message in a body holding exactly one envelope
+func unwrapEnvelope(buf []byte) ([]byte, error) {
+	if len(buf) < 5 {
+		return nil, status.Error(codes.InvalidArgument, "request body is not an enveloped message")
+	}
+	if buf[0]&flagCompressed != 0 {
+		return nil, status.Error(codes.Unimplemented, "compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(buf[1:5])
+	if size > maxMessageBytes {
+		return nil, status.Errorf(codes.ResourceExhausted, "request message exceeds %d bytes", maxMessageBytes)
+	}
+	if int(size) != len(buf)-5 {
+		return nil, status.Error(codes.InvalidArgument, "request body must hold exactly one message")
+	}
+	return buf[5:], nil
+}
+
+// codec encodes messages in the negotiated format
+type codec interface {
+	name() string
+	marshal(proto.Message) ([]byte, error)
+	unmarshal([]byte, proto.Message) error
+}
+
+type protoCodec struct{}
+
+func (protoCodec) name() string { return "proto" }
+
+func (protoCodec) marshal(m proto.Message) ([]byte, error) { return proto.Marshal(m) }
+
+func (protoCodec) unmarshal(b []byte, m proto.Message) error { return proto.Unmarshal(b, m) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) name() string { return "json" }
+
+func (jsonCodec) marshal(m proto.Message) ([]byte, error) { return protojson.Marshal(m) }
+
+func (jsonCodec) unmarshal(b []byte, m proto.Message) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		// An empty JSON body is the empty message, as in proto
+		return nil
+	}
+	return protojson.Unmarshal(b, m)
+}
+
+// encodeGRPCMessage percent-encodes a status message for the grpc-message trailer
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func encodeBinaryHeader(v string) string {
+	return base64.RawStdEncoding.EncodeToString([]byte(v))
+}
@@ -0,0 +1,278 @@
+// Package webrpc lets browser and mobile clients call the backend services directly with
+// generated gRPC-Web or Connect clients. Requests are served at /<package>.<Service>/<Method>
+// on the gateway's own listener, so they share the REST middleware, and are forwarded
+// to the same backend connections as proxied REST calls. Only methods with a REST
+// binding or explicitly listed as public are served. Unary and server-streaming
+// methods are supported; client and bidirectional streaming need HTTP/2 end to end
+// and are rejected.
+package webrpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chat/gateway/internal/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MetadataAnnotator derives outgoing gRPC metadata from the HTTP request, with the same
+// signature as the annotators given to runtime.WithMetadata
+type MetadataAnnotator func(context.Context, *http.Request) metadata.MD
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithMetadata forwards metadata from the annotators to every backend call,
+// so RPC requests carry the same request ID and trace context as proxied ones
+func WithMetadata(annotators ...MetadataAnnotator) Option {
+	return func(h *Handler) {
+		h.annotators = append(h.annotators, annotators...)
+	}
+}
+
+// WithPublicMethods exposes the listed RPCs (/package.Service/Method) although they have
+// no google.api.http binding, such as streaming RPCs without a REST form
+func WithPublicMethods(methods ...string) Option {
+	return func(h *Handler) {
+		h.publicMethods = append(h.publicMethods, methods...)
+	}
+}
+
+// Handler serves gRPC-Web and Connect requests
+type Handler struct {
+	services      map[string]grpc.ClientConnInterface
+	annotators    []MetadataAnnotator
+	publicMethods []string
+}
+
+// New creates a handler for the given services, keyed by full service name
+func New(services map[string]grpc.ClientConnInterface, opts ...Option) *Handler {
+	h := &Handler{services: services}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Mux is the part of http.ServeMux used to mount the services
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Register mounts the public methods of every service on mux. Internal methods such as
+// GetPublicKeys and CheckRelationship are not mounted, so they get the mux's 404.
+func (h *Handler) Register(mux Mux) {
+	for service := range h.services {
+		sd, ok := serviceDescriptor(service)
+		if !ok {
+			continue
+		}
+		for i := 0; i < sd.Methods().Len(); i++ {
+			if method := sd.Methods().Get(i); proxy.IsPublic(method, h.publicMethods) {
+				mux.Handle("POST /"+service+"/"+string(method.Name()), h)
+			}
+		}
+	}
+}
+
+// ServeHTTP forwards one RPC to its backend and writes the response in the caller's protocol
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, ok := negotiate(r.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	rw := p.newWriter(w)
+
+	service, methodName, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	conn, method, err := h.lookup(service, methodName)
+	if err != nil {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+	if method.IsStreamingClient() {
+		rw.writeEnd(status.New(codes.Unimplemented, "client and bidirectional streaming are not supported over this protocol"), nil)
+		return
+	}
+	if method.IsStreamingServer() && !p.streaming {
+		rw.writeEnd(status.New(codes.Unimplemented, "server-streaming methods require a streaming protocol"), nil)
+		return
+	}
+
+	req := dynamicpb.NewMessage(method.Input())
+	if err := p.readRequest(r.Body, req); err != nil {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+
+	ctx, cancel, err := h.callContext(r, p)
+	if err != nil {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+	defer cancel()
+
+	fullMethod := "/" + service + "/" + methodName
+	if method.IsStreamingServer() {
+		// The stream outlives the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		h.stream(ctx, rw, conn, method, fullMethod, req)
+		return
+	}
+	h.unary(ctx, rw, conn, method, fullMethod, req)
+}
+
+// unary goes through Invoke, so the connection's unary interceptors such as the
+// circuit breaker apply as they do to REST calls
+func (h *Handler) unary(ctx context.Context, rw responseWriter, conn grpc.ClientConnInterface,
+	method protoreflect.MethodDescriptor, fullMethod string, req *dynamicpb.Message) {
+	var header, trailer metadata.MD
+	resp := dynamicpb.NewMessage(method.Output())
+	err := conn.Invoke(ctx, fullMethod, req, resp, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	rw.writeHeader(header)
+	if err == nil {
+		if err := rw.writeMessage(resp); err != nil {
+			rw.writeEnd(status.New(codes.Internal, "failed to encode response"), trailer)
+			return
+		}
+	}
+	rw.writeEnd(status.Convert(err), trailer)
+}
+
+func (h *Handler) stream(ctx context.Context, rw responseWriter, conn grpc.ClientConnInterface,
+	method protoreflect.MethodDescriptor, fullMethod string, req *dynamicpb.Message) {
+	desc := &grpc.StreamDesc{StreamName: string(method.Name()), ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, fullMethod)
+	if err != nil {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+	if err := stream.SendMsg(req); err != nil && err != io.EOF {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+	if err := stream.CloseSend(); err != nil {
+		rw.writeEnd(status.Convert(err), nil)
+		return
+	}
+
+	// Header fails only if the stream failed; RecvMsg then reports the status
+	if header, err := stream.Header(); err == nil {
+		rw.writeHeader(header)
+	}
+	for {
+		resp := dynamicpb.NewMessage(method.Output())
+		if err := stream.RecvMsg(resp); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			rw.writeEnd(status.Convert(err), stream.Trailer())
+			return
+		}
+		if err := rw.writeMessage(resp); err != nil {
+			rw.writeEnd(status.New(codes.Internal, "failed to encode response"), stream.Trailer())
+			return
+		}
+	}
+}
+
+// lookup finds the connection and descriptor for a method
+func (h *Handler) lookup(service, method string) (grpc.ClientConnInterface, protoreflect.MethodDescriptor, error) {
+	conn, ok := h.services[service]
+	if !ok {
+		return nil, nil, status.Errorf(codes.Unimplemented, "unknown service %s", service)
+	}
+	sd, ok := serviceDescriptor(service)
+	if !ok {
+		return nil, nil, status.Errorf(codes.Unimplemented, "unknown service %s", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil || !proxy.IsPublic(md, h.publicMethods) {
+		return nil, nil, status.Errorf(codes.Unimplemented, "unknown method %s for service %s", method, service)
+	}
+	return conn, md, nil
+}
+
+func serviceDescriptor(service string) (protoreflect.ServiceDescriptor, bool) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, false
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	return sd, ok
+}
+
+// callContext applies the client's timeout and attaches the annotators' metadata; the
+// caller's Authorization header stays at the gateway, which relays the verified user instead
+func (h *Handler) callContext(r *http.Request, p protocol) (context.Context, context.CancelFunc, error) {
+	ctx := r.Context()
+
+	mds := make([]metadata.MD, 0, len(h.annotators))
+	for _, annotate := range h.annotators {
+		mds = append(mds, annotate(ctx, r))
+	}
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Join(mds...))
+
+	timeout, ok, err := p.timeout(r.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// grpcTimeout parses a grpc-timeout header value such as "1500m"
+func grpcTimeout(v string) (time.Duration, error) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid grpc-timeout %q", v)
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid grpc-timeout %q", v)
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid grpc-timeout %q", v)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// setMetadataHeaders copies backend metadata to response headers, skipping the
+// transport's own headers. Binary values are base64-encoded, as in gRPC.
+func setMetadataHeaders(h http.Header, md metadata.MD, prefix string) {
+	for key, values := range md {
+		if strings.HasPrefix(key, "grpc-") || strings.HasPrefix(key, ":") || key == "content-type" {
+			continue
+		}
+		for _, v := range values {
+			if strings.HasSuffix(key, "-bin") {
+				v = encodeBinaryHeader(v)
+			}
+			h.Add(prefix+key, v)
+		}
+	}
+}
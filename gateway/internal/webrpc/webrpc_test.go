@@ -0,0 +1,307 @@
+package webrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	chatv1 "github.com/go-chat/chat/pkg/api/chat/v1"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+)
+
+type fakeChat struct {
+	chatv1.UnimplementedChatServiceServer
+}
+
+func (fakeChat) GetChat(_ context.Context, req *chatv1.GetChatRequest) (*chatv1.GetChatResponse, error) {
+	if req.GetChatId() != "c1" {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	return &chatv1.GetChatResponse{Chat: &chatv1.Chat{ChatId: "c1", ParticipantIds: []string{"u1", "u2"}}}, nil
+}
+
+func (fakeChat) StreamMessages(req *chatv1.StreamMessagesRequest, stream grpc.ServerStreamingServer[chatv1.StreamMessagesResponse]) error {
+	for _, text := range []string{"hello", "bye"} {
+		if err := stream.Send(&chatv1.StreamMessagesResponse{Message: &chatv1.Message{ChatId: req.GetChatId(), Text: text}}); err != nil {
+			return err
+		}
+	}
+	return status.Error(codes.Unavailable, "stream closed")
+}
+
+func newTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	chatv1.RegisterChatServiceServer(srv, fakeChat{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	mux := http.NewServeMux()
+	New(map[string]grpc.ClientConnInterface{chatv1.ChatService_ServiceDesc.ServiceName: conn},
+		WithPublicMethods("/api.chat.v1.ChatService/StreamMessages"),
+	).Register(mux)
+	return mux
+}
+
+func envelope(flags byte, payload []byte) []byte {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+type frame struct {
+	flags   byte
+	payload []byte
+}
+
+func readFrames(t *testing.T, body []byte) []frame {
+	t.Helper()
+
+	var frames []frame
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("Truncated envelope: %q", body)
+		}
+		size := int(binary.BigEndian.Uint32(body[1:5]))
+		frames = append(frames, frame{flags: body[0], payload: body[5 : 5+size]})
+		body = body[5+size:]
+	}
+	return frames
+}
+
+// decodeText decodes a grpc-web-text body. Each envelope is encoded separately, so the
+// body is split after each padding run and the chunks decoded one by one.
+func decodeText(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var decoded []byte
+	for body != "" {
+		end := len(body)
+		if i := strings.Index(body, "="); i >= 0 {
+			end = i + 1
+			for end < len(body) && body[end] == '=' {
+				end++
+			}
+		}
+		buf, err := base64.StdEncoding.DecodeString(body[:end])
+		if err != nil {
+			t.Fatalf("Invalid base64 chunk %q: %v", body[:end], err)
+		}
+		decoded = append(decoded, buf...)
+		body = body[end:]
+	}
+	return decoded
+}
+
+func serve(handler http.Handler, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGRPCWeb_Unary(t *testing.T) {
+	handler := newTestHandler(t)
+	reqMsg, _ := proto.Marshal(&chatv1.GetChatRequest{ChatId: "c1"})
+
+	rec := serve(handler, "/api.chat.v1.ChatService/GetChat", "application/grpc-web+proto", envelope(0, reqMsg))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/grpc-web+proto" {
+		t.Errorf("Expected grpc-web content type, got '%s'", ct)
+	}
+	frames := readFrames(t, rec.Body.Bytes())
+	if len(frames) != 2 {
+		t.Fatalf("Expected message and trailer frames, got %d", len(frames))
+	}
+
+	var resp chatv1.GetChatResponse
+	if err := proto.Unmarshal(frames[0].payload, &resp); err != nil || resp.GetChat().GetChatId() != "c1" {
+		t.Errorf("Unexpected response %v: %v", &resp, err)
+	}
+	if frames[1].flags != flagGRPCWebTrailer || !strings.Contains(string(frames[1].payload), "grpc-status: 0\r\n") {
+		t.Errorf("Unexpected trailer frame: %+v", frames[1])
+	}
+}
+
+func TestGRPCWeb_Error_InTrailer(t *testing.T) {
+	handler := newTestHandler(t)
+	reqMsg, _ := proto.Marshal(&chatv1.GetChatRequest{ChatId: "missing"})
+
+	rec := serve(handler, "/api.chat.v1.ChatService/GetChat", "application/grpc-web+proto", envelope(0, reqMsg))
+
+	frames := readFrames(t, rec.Body.Bytes())
+	if rec.Code != http.StatusOK || len(frames) != 1 {
+		t.Fatalf("Expected status 200 with only a trailer frame, got %d and %d frames", rec.Code, len(frames))
+	}
+	trailer := string(frames[0].payload)
+	if !strings.Contains(trailer, "grpc-status: 5\r\n") || !strings.Contains(trailer, "grpc-message: chat not found\r\n") {
+		t.Errorf("Unexpected trailer: %q", trailer)
+	}
+}
+
+func TestGRPCWebText_ServerStreaming(t *testing.T) {
+	handler := newTestHandler(t)
+	reqMsg, _ := proto.Marshal(&chatv1.StreamMessagesRequest{ChatId: "c1"})
+	body := []byte(base64.StdEncoding.EncodeToString(envelope(0, reqMsg)))
+
+	rec := serve(handler, "/api.chat.v1.ChatService/StreamMessages", "application/grpc-web-text", body)
+
+	frames := readFrames(t, decodeText(t, rec.Body.String()))
+	if len(frames) != 3 {
+		t.Fatalf("Expected 2 messages and a trailer, got %d frames", len(frames))
+	}
+	if !strings.Contains(string(frames[2].payload), "grpc-status: 14\r\n") {
+		t.Errorf("Expected stream error in trailer, got %q", frames[2].payload)
+	}
+}
+
+func TestConnect_Unary_JSON(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := serve(handler, "/api.chat.v1.ChatService/GetChat", "application/json", []byte(`{"chatId":"c1"}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Chat struct {
+			ChatID string `json:"chatId"`
+		} `json:"chat"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Chat.ChatID != "c1" {
+		t.Errorf("Unexpected response %s: %v", rec.Body.String(), err)
+	}
+}
+
+func TestConnect_Unary_Error(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := serve(handler, "/api.chat.v1.ChatService/GetChat", "application/json", []byte(`{"chatId":"missing"}`))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	var body connectError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "not_found" || body.Message != "chat not found" {
+		t.Errorf("Unexpected error body %s: %v", rec.Body.String(), err)
+	}
+}
+
+func TestConnect_ServerStreaming(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := serve(handler, "/api.chat.v1.ChatService/StreamMessages", "application/connect+json",
+		envelope(0, []byte(`{"chatId":"c1"}`)))
+
+	frames := readFrames(t, rec.Body.Bytes())
+	if len(frames) != 3 {
+		t.Fatalf("Expected 2 messages and end of stream, got %d frames", len(frames))
+	}
+	if !strings.Contains(string(frames[1].payload), `"text":"bye"`) {
+		t.Errorf("Unexpected second message: %s", frames[1].payload)
+	}
+
+	var end connectEndStream
+	if frames[2].flags != flagConnectEndStream {
+		t.Fatalf("Expected end-stream flag, got %#x", frames[2].flags)
+	}
+	if err := json.Unmarshal(frames[2].payload, &end); err != nil || end.Error == nil || end.Error.Code != "unavailable" {
+		t.Errorf("Unexpected end of stream %s: %v", frames[2].payload, err)
+	}
+}
+
+func TestConnect_UnaryProtocol_RejectsStreamingMethod(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := serve(handler, "/api.chat.v1.ChatService/StreamMessages", "application/json", []byte(`{"chatId":"c1"}`))
+
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", rec.Code)
+	}
+}
+
+func TestHandler_Register_MountsOnlyPublicMethods(t *testing.T) {
+	mux := http.NewServeMux()
+	New(map[string]grpc.ClientConnInterface{
+		chatv1.ChatService_ServiceDesc.ServiceName:     nil,
+		socialv1.SocialService_ServiceDesc.ServiceName: nil,
+	}).Register(mux)
+
+	tests := []struct {
+		path       string
+		registered bool
+	}{
+		{"/api.chat.v1.ChatService/GetChat", true},
+		{"/api.social.v1.SocialService/ListFriends", true},
+		{"/api.social.v1.SocialService/CheckRelationship", false},
+		{"/api.chat.v1.ChatService/StreamMessages", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, pattern := mux.Handler(httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if registered := pattern != ""; registered != tt.registered {
+				t.Errorf("Expected registered %v, got pattern %q", tt.registered, pattern)
+			}
+		})
+	}
+}
+
+func TestHandler_UnknownContentType_Returns415(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := serve(handler, "/api.chat.v1.ChatService/GetChat", "text/plain", nil)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", rec.Code)
+	}
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"1500m", 1500 * time.Millisecond, false},
+		{"2S", 2 * time.Second, false},
+		{"1H", time.Hour, false},
+		{"10x", 0, true},
+		{"m", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := grpcTimeout(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("grpcTimeout(%q): expected (%v, error %v), got (%v, %v)", tt.value, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
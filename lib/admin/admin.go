@@ -0,0 +1,248 @@
+// Package admin serves an internal HTTP listener for operators: pprof profiles, the
+// effective configuration with secrets redacted, the route table, the cached token
+// verification keys and the state of outgoing gRPC connections. It binds to localhost by default and must never be
+// exposed through a public load balancer; reach it with kubectl port-forward.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultAddr is the admin listen address when ADMIN_ADDR is unset.
+const DefaultAddr = "localhost:6060"
+
+// AddrFromEnv returns the admin listen address from ADMIN_ADDR, or DefaultAddr when unset.
+// Setting ADMIN_ADDR to an empty string disables the listener.
+func AddrFromEnv() string {
+	if v, ok := os.LookupEnv("ADMIN_ADDR"); ok {
+		return v
+	}
+	return DefaultAddr
+}
+
+// Route is one entry of the route table.
+type Route struct {
+	// Method is the HTTP method, empty for routes matching any method, or GRPC for gRPC methods.
+	Method string `json:"method"`
+	// Path is the HTTP path pattern or the full gRPC method name.
+	Path string `json:"path"`
+	// Target is what serves the route, e.g. the RPC a REST route is proxied to.
+	Target string `json:"target,omitempty"`
+}
+
+// Key is a cached token verification key, as reported by a WithKeys source.
+type Key struct {
+	// KeyID is the key's kid.
+	KeyID string `json:"kid"`
+	// Algorithm is the signing algorithm the key is used with.
+	Algorithm string `json:"alg,omitempty"`
+	// CachedAt is when the key was first fetched.
+	CachedAt time.Time `json:"cached_at"`
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithConfig serves cfg at /debug/config. Fields whose names suggest secrets, or that are
+// tagged `admin:"redact"`, are replaced by a placeholder, as are passwords in URLs.
+func WithConfig(cfg interface{}) Option {
+	return func(s *Server) {
+		s.config = cfg
+	}
+}
+
+// WithRoutes adds the routes reported by fn to /debug/routes. fn is called on every
+// request, so routes registered after the Server is created are included.
+func WithRoutes(fn func() []Route) Option {
+	return func(s *Server) {
+		s.routes = append(s.routes, fn)
+	}
+}
+
+// WithConnections reports the state of the named client connections at /debug/connections.
+func WithConnections(conns map[string]*grpc.ClientConn) Option {
+	return func(s *Server) {
+		for name, conn := range conns {
+			s.conns[name] = conn
+		}
+	}
+}
+
+// WithKeys adds the keys reported by fn to /debug/jwks, each with its age. fn is called
+// on every request, so the current contents of the cache are shown.
+func WithKeys(fn func() []Key) Option {
+	return func(s *Server) {
+		s.keys = append(s.keys, fn)
+	}
+}
+
+// WithHandler serves an additional endpoint, e.g. "GET /debug/breakers".
+func WithHandler(pattern string, handler http.Handler) Option {
+	return func(s *Server) {
+		s.extra = append(s.extra, pattern)
+		s.mux.Handle(pattern, handler)
+	}
+}
+
+// Server is the admin HTTP listener.
+type Server struct {
+	httpServer *http.Server
+	mux        *http.ServeMux
+	config     interface{}
+	routes     []func() []Route
+	conns      map[string]*grpc.ClientConn
+	keys       []func() []Key
+	extra      []string
+}
+
+// New creates an admin server listening on addr. An empty addr disables the listener,
+// but the handler can still be mounted elsewhere.
+func New(addr string, opts ...Option) *Server {
+	s := &Server{
+		mux:   http.NewServeMux(),
+		conns: make(map[string]*grpc.ClientConn),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /{$}", s.index)
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.HandleFunc("GET /debug/config", s.serveConfig)
+	s.mux.HandleFunc("GET /debug/routes", s.serveRoutes)
+	s.mux.HandleFunc("GET /debug/connections", s.serveConnections)
+	s.mux.HandleFunc("GET /debug/jwks", s.serveKeys)
+
+	if addr != "" {
+		s.httpServer = &http.Server{
+			Addr:              addr,
+			Handler:           s.mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+	return s
+}
+
+// Handler returns the handler serving all admin endpoints.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves the admin endpoints. It blocks until the listener fails or is shut
+// down, and returns nil immediately when the listener is disabled or after Shutdown.
+func (s *Server) ListenAndServe() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the listener, waiting for in-flight requests such as CPU profiles.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) index(w http.ResponseWriter, _ *http.Request) {
+	endpoints := append([]string{
+		"/debug/pprof/",
+		"/debug/config",
+		"/debug/routes",
+		"/debug/connections",
+		"/debug/jwks",
+	}, s.extra...)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(strings.Join(endpoints, "\n") + "\n"))
+}
+
+func (s *Server) serveConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, Redact(s.config))
+}
+
+func (s *Server) serveRoutes(w http.ResponseWriter, _ *http.Request) {
+	routes := []Route{}
+	for _, fn := range s.routes {
+		routes = append(routes, fn()...)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	writeJSON(w, routes)
+}
+
+// connectionState is the /debug/connections entry for one client connection.
+type connectionState struct {
+	Target string `json:"target"`
+	State  string `json:"state"`
+}
+
+func (s *Server) serveConnections(w http.ResponseWriter, _ *http.Request) {
+	states := make(map[string]connectionState, len(s.conns))
+	for name, conn := range s.conns {
+		states[name] = connectionState{
+			Target: conn.CanonicalTarget(),
+			State:  conn.GetState().String(),
+		}
+	}
+	writeJSON(w, states)
+}
+
+// keyState is the /debug/jwks entry for one cached key.
+type keyState struct {
+	Key
+	Age string `json:"age"`
+}
+
+func (s *Server) serveKeys(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	keys := []keyState{}
+	for _, fn := range s.keys {
+		for _, key := range fn() {
+			keys = append(keys, keyState{Key: key, Age: now.Sub(key.CachedAt).Round(time.Second).String()})
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	writeJSON(w, keys)
+}
+
+// GRPCRoutes lists the methods registered on srv, for use with WithRoutes.
+func GRPCRoutes(srv *grpc.Server) func() []Route {
+	return func() []Route {
+		var routes []Route
+		for service, info := range srv.GetServiceInfo() {
+			for _, method := range info.Methods {
+				routes = append(routes, Route{Method: "GRPC", Path: "/" + service + "/" + method.Name})
+			}
+		}
+		return routes
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
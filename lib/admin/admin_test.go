@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected status 200, got %d", path, rec.Code)
+	}
+	return rec
+}
+
+func TestServer_Config_RedactsSecrets(t *testing.T) {
+	type database struct {
+		URL      string
+		Password string
+	}
+	cfg := struct {
+		Addr     string
+		Timeout  time.Duration
+		APIKey   string `admin:"redact"`
+		Database database
+		Empty    string `admin:"redact"`
+		internal string
+	}{
+		Addr:     ":8080",
+		Timeout:  5 * time.Second,
+		APIKey:   "k-123",
+		Database: database{URL: "postgres://app:hunter2@db:5432/chat", Password: "hunter2"},
+		internal: "hidden",
+	}
+
+	rec := get(t, New("", WithConfig(cfg)).Handler(), "/debug/config")
+
+	body := rec.Body.String()
+	if strings.Contains(body, "hunter2") || strings.Contains(body, "k-123") || strings.Contains(body, "hidden") {
+		t.Fatalf("Expected secrets to be redacted, got %s", body)
+	}
+
+	var got struct {
+		Addr     string
+		Timeout  string
+		APIKey   string
+		Empty    string
+		Database database
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode config: %v", err)
+	}
+	if got.Addr != ":8080" || got.Timeout != "5s" {
+		t.Errorf("Expected plain values to be kept, got %+v", got)
+	}
+	if got.APIKey != Redacted || got.Database.Password != Redacted {
+		t.Errorf("Expected secrets to be %s, got %+v", Redacted, got)
+	}
+	if got.Empty != "" {
+		t.Errorf("Expected unset secret to stay empty, got '%s'", got.Empty)
+	}
+	if got.Database.URL != "postgres://app:xxxxx@db:5432/chat" {
+		t.Errorf("Expected URL password to be masked, got '%s'", got.Database.URL)
+	}
+}
+
+func TestRedact_MapKeys(t *testing.T) {
+	got := Redact(map[string]string{"db_password": "secret", "host": "db"}).(map[string]interface{})
+
+	if got["db_password"] != Redacted || got["host"] != "db" {
+		t.Errorf("Unexpected redaction: %v", got)
+	}
+}
+
+func TestServer_Routes_SortedAcrossSources(t *testing.T) {
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	h := New("",
+		WithRoutes(func() []Route {
+			return []Route{{Method: "GET", Path: "/v1/chats", Target: "/api.chat.v1.ChatService/ListChats"}}
+		}),
+		WithRoutes(GRPCRoutes(srv)),
+	).Handler()
+
+	var routes []Route
+	if err := json.Unmarshal(get(t, h, "/debug/routes").Body.Bytes(), &routes); err != nil {
+		t.Fatalf("Failed to decode routes: %v", err)
+	}
+
+	expected := []Route{
+		{Method: "GRPC", Path: "/grpc.health.v1.Health/Check"},
+		{Method: "GRPC", Path: "/grpc.health.v1.Health/List"},
+		{Method: "GRPC", Path: "/grpc.health.v1.Health/Watch"},
+		{Method: "GET", Path: "/v1/chats", Target: "/api.chat.v1.ChatService/ListChats"},
+	}
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes, got %v", len(expected), routes)
+	}
+	for i := range expected {
+		if routes[i] != expected[i] {
+			t.Errorf("Route %d: expected %+v, got %+v", i, expected[i], routes[i])
+		}
+	}
+}
+
+func TestServer_Connections_ReportsState(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough:///users:8080", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	rec := get(t, New("", WithConnections(map[string]*grpc.ClientConn{"users": conn})).Handler(), "/debug/connections")
+
+	var states map[string]connectionState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatalf("Failed to decode connections: %v", err)
+	}
+	if got := states["users"]; got.Target != "passthrough:///users:8080" || got.State != "IDLE" {
+		t.Errorf("Expected idle users connection, got %+v", got)
+	}
+}
+
+func TestServer_Keys_ReportsAge(t *testing.T) {
+	cachedAt := time.Now().Add(-90 * time.Second)
+	keys := func() []Key {
+		return []Key{
+			{KeyID: "key-2", Algorithm: "RS256", CachedAt: cachedAt},
+			{KeyID: "key-1", Algorithm: "RS256", CachedAt: cachedAt},
+		}
+	}
+
+	rec := get(t, New("", WithKeys(keys)).Handler(), "/debug/jwks")
+
+	var got []keyState
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode keys: %v", err)
+	}
+	if len(got) != 2 || got[0].KeyID != "key-1" || got[1].KeyID != "key-2" {
+		t.Fatalf("Expected keys sorted by ID, got %+v", got)
+	}
+	if age, err := time.ParseDuration(got[0].Age); err != nil || age < 90*time.Second {
+		t.Errorf("Expected age of at least 90s, got %q", got[0].Age)
+	}
+}
+
+func TestServer_ExtraHandlerAndPprof(t *testing.T) {
+	h := New("", WithHandler("GET /debug/breakers", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))).Handler()
+
+	if body := get(t, h, "/").Body.String(); !strings.Contains(body, "/debug/breakers") {
+		t.Errorf("Expected index to list extra handler, got %q", body)
+	}
+	get(t, h, "/debug/breakers")
+	if body := get(t, h, "/debug/pprof/").Body.String(); !strings.Contains(body, "goroutine") {
+		t.Errorf("Expected pprof index, got %q", body)
+	}
+}
+
+func TestServer_Disabled_ListenAndServeReturns(t *testing.T) {
+	s := New("")
+
+	if err := s.ListenAndServe(); err != nil {
+		t.Errorf("Expected nil error for disabled listener, got %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected nil error on shutdown, got %v", err)
+	}
+}
+
+func TestAddrFromEnv(t *testing.T) {
+	t.Setenv("ADMIN_ADDR", "127.0.0.1:7070")
+	if got := AddrFromEnv(); got != "127.0.0.1:7070" {
+		t.Errorf("Expected ADMIN_ADDR, got '%s'", got)
+	}
+
+	t.Setenv("ADMIN_ADDR", "")
+	if got := AddrFromEnv(); got != "" {
+		t.Errorf("Expected empty address to disable the listener, got '%s'", got)
+	}
+}
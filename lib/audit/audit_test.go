@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logEntries(t *testing.T, l *Logger, methods ...string) {
+	t.Helper()
+	for _, method := range methods {
+		if err := l.Log(context.Background(), Entry{Time: time.Unix(1000, 0).UTC(), Method: method, Actor: "anonymous", Result: "OK"}); err != nil {
+			t.Fatalf("Log() failed: %v", err)
+		}
+	}
+}
+
+func TestLogger_ChainsEntries(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(NewWriterSink(&buf))
+	if err != nil {
+		t.Fatalf("NewLogger() failed: %v", err)
+	}
+	logEntries(t, l, "/a", "/b", "/c")
+
+	n, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected a valid chain, got %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 entries verified, got %d", n)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := NewLogger(NewWriterSink(&buf))
+	logEntries(t, l, "/a", "/b", "/c")
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{"modified", lines[0] + strings.Replace(lines[1], `"result":"OK"`, `"result":"PermissionDenied"`, 1) + lines[2]},
+		{"removed", lines[0] + lines[2]},
+		{"reordered", lines[1] + lines[0] + lines[2]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(strings.NewReader(tt.log)); err == nil {
+				t.Error("Expected tampering to be detected, got nil")
+			}
+		})
+	}
+}
+
+func TestFileSink_ResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, method := range []string{"/before", "/after"} {
+		sink, err := OpenFileSink(path)
+		if err != nil {
+			t.Fatalf("OpenFileSink() failed: %v", err)
+		}
+		l, err := NewLogger(sink)
+		if err != nil {
+			t.Fatalf("NewLogger() failed: %v", err)
+		}
+		logEntries(t, l, method)
+		_ = l.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if n, err := Verify(bytes.NewReader(data)); err != nil || n != 2 {
+		t.Errorf("Expected 2 chained entries, got %d (%v)", n, err)
+	}
+	if strings.Count(string(data), `"prev_hash":""`) != 1 {
+		t.Errorf("Expected the reopened file to continue the chain, got %s", data)
+	}
+}
+
+type failingSink struct {
+	WriterSink
+	fail bool
+}
+
+func (s *failingSink) Write(ctx context.Context, line []byte) error {
+	if s.fail {
+		return errors.New("bus unavailable")
+	}
+	return s.WriterSink.Write(ctx, line)
+}
+
+func TestLogger_FailedWrite_DoesNotAdvanceChain(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &failingSink{WriterSink: WriterSink{w: &buf}}
+	l, _ := NewLogger(sink)
+
+	logEntries(t, l, "/a")
+	sink.fail = true
+	if err := l.Log(context.Background(), Entry{Method: "/lost"}); err == nil {
+		t.Fatal("Expected write error, got nil")
+	}
+	sink.fail = false
+	logEntries(t, l, "/b")
+
+	if _, err := Verify(&buf); err != nil {
+		t.Errorf("Expected the chain to skip the lost entry, got %v", err)
+	}
+}
+
+type recordingPublisher struct {
+	topic string
+	keys  []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, key, value []byte) error {
+	p.topic = topic
+	p.keys = append(p.keys, string(key))
+	return nil
+}
+
+func TestBusSink_PublishesWithFixedKey(t *testing.T) {
+	p := &recordingPublisher{}
+	l, _ := NewLogger(NewBusSink(p, "audit.rpc"))
+	logEntries(t, l, "/a", "/b")
+
+	if p.topic != "audit.rpc" {
+		t.Errorf("Expected topic audit.rpc, got %q", p.topic)
+	}
+	if len(p.keys) != 2 || p.keys[0] != p.keys[1] {
+		t.Errorf("Expected every entry to share one key, got %v", p.keys)
+	}
+}
+
+func TestSinkFromEnv(t *testing.T) {
+	t.Setenv("AUDIT_SINK", "file:"+filepath.Join(t.TempDir(), "audit.log"))
+	sink, err := SinkFromEnv()
+	if err != nil {
+		t.Fatalf("SinkFromEnv() failed: %v", err)
+	}
+	_ = sink.Close()
+	if _, ok := sink.(*FileSink); !ok {
+		t.Errorf("Expected a file sink, got %T", sink)
+	}
+
+	t.Setenv("AUDIT_SINK", "stdout")
+	sink, err = SinkFromEnv()
+	if err != nil {
+		t.Fatalf("SinkFromEnv() failed: %v", err)
+	}
+	if _, ok := sink.(*WriterSink); !ok {
+		t.Errorf("Expected a writer sink, got %T", sink)
+	}
+
+	t.Setenv("AUDIT_SINK", "syslog")
+	if _, err := SinkFromEnv(); err == nil {
+		t.Error("Expected error for an unknown sink, got nil")
+	}
+}
+
+func TestSinkFromEnv_Unset_DefaultsToFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("AUDIT_SINK", "")
+
+	sink, err := SinkFromEnv()
+	if err != nil {
+		t.Fatalf("SinkFromEnv() failed: %v", err)
+	}
+	_ = sink.Close()
+
+	if _, ok := sink.(*FileSink); !ok {
+		t.Errorf("Expected a file sink by default, got %T", sink)
+	}
+	if _, err := os.Stat(DefaultFilePath); err != nil {
+		t.Errorf("Expected %s to be created, got %v", DefaultFilePath, err)
+	}
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Redacted replaces the value of sensitive fields.
+const Redacted = "[REDACTED]"
+
+// sensitiveNames are substrings of field names, lower-cased, whose values are never recorded
+// even when the field isn't marked with debug_redact.
+var sensitiveNames = []string{"password", "secret", "token", "credential", "private_key"}
+
+// isSensitive reports whether a field is marked with the debug_redact option or is named
+// like a secret.
+func isSensitive(fd protoreflect.FieldDescriptor) bool {
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDebugRedact() {
+		return true
+	}
+	name := strings.ToLower(string(fd.Name()))
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractFields records the value of each path in msg into target. Paths that don't resolve,
+// including paths through unset messages, are skipped.
+func extractFields(target map[string]string, msg proto.Message, paths []string) {
+	for _, path := range paths {
+		if value, ok := fieldValue(msg.ProtoReflect(), path); ok {
+			target[path] = value
+		}
+	}
+}
+
+// fieldValue resolves a dotted path of field names in m and renders the value. A sensitive
+// field anywhere on the path is redacted; messages, lists and maps are rendered as JSON
+// with their sensitive fields redacted.
+func fieldValue(m protoreflect.Message, path string) (string, bool) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return "", false
+		}
+		if isSensitive(fd) {
+			return Redacted, true
+		}
+
+		last := i == len(names)-1
+		if !last {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() || !m.Has(fd) {
+				return "", false
+			}
+			m = m.Get(fd).Message()
+			continue
+		}
+
+		switch v := plainValue(fd, m.Get(fd)).(type) {
+		case string:
+			return v, true
+		default:
+			buf, err := json.Marshal(v)
+			if err != nil {
+				return "", false
+			}
+			return string(buf), true
+		}
+	}
+	return "", false
+}
+
+// plainValue converts a field value into strings, numbers, maps and slices suitable for JSON,
+// redacting sensitive fields of nested messages.
+func plainValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]interface{}, list.Len())
+		for i := range out {
+			out[i] = singularValue(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]interface{})
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = singularValue(fd.MapValue(), v)
+			return true
+		})
+		return out
+	default:
+		return singularValue(fd, v)
+	}
+}
+
+func singularValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.StringKind:
+		return v.String()
+	default:
+		return v.Interface()
+	}
+}
+
+func messageValue(m protoreflect.Message) map[string]interface{} {
+	out := make(map[string]interface{})
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isSensitive(fd) {
+			out[string(fd.Name())] = Redacted
+			return true
+		}
+		out[string(fd.Name())] = plainValue(fd, v)
+		return true
+	})
+	return out
+}
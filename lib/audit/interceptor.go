@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// forwardedForMetadataKey carries the client address set by the gateway's HTTP proxy.
+const forwardedForMetadataKey = "x-forwarded-for"
+
+// MethodConfig declares what is recorded about one audited method. Fields are paths of
+// field names from the request or response message, e.g. "user_id" or "request.requester_id".
+type MethodConfig struct {
+	// RequestFields are recorded for every call.
+	RequestFields []string
+
+	// ResponseFields are recorded for successful calls, e.g. the ID of a created user.
+	ResponseFields []string
+}
+
+// Interceptor records the configured methods to an audit logger after they complete.
+// It must run inside the authorization middleware so the caller is known, e.g. with
+// grpc_middleware.WithInterceptor(grpc_middleware.StageAuth, ...).
+type Interceptor struct {
+	logger  *Logger
+	methods map[string]MethodConfig
+	errors  *slog.Logger
+	now     func() time.Time
+}
+
+// NewInterceptor creates an interceptor auditing methods, keyed by full method name.
+// Failures to write an entry are logged to errorLogger, or slog.Default() when nil,
+// and don't fail the RPC, which has already completed.
+func NewInterceptor(logger *Logger, methods map[string]MethodConfig, errorLogger *slog.Logger) *Interceptor {
+	if errorLogger == nil {
+		errorLogger = slog.Default()
+	}
+	return &Interceptor{logger: logger, methods: methods, errors: errorLogger, now: time.Now}
+}
+
+// UnaryServerInterceptor returns a unary server interceptor auditing the configured methods.
+func (a *Interceptor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		cfg, ok := a.methods[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		start := a.now()
+		resp, err := handler(ctx, req)
+
+		entry := Entry{
+			Time:         start.UTC(),
+			Method:       info.FullMethod,
+			Actor:        actor(ctx),
+			Target:       make(map[string]string),
+			Result:       status.Code(err).String(),
+			IP:           peerIP(ctx),
+			ForwardedFor: forwardedFor(ctx),
+			RequestID:    grpc_middleware.RequestIDFromContext(ctx),
+		}
+		if m, ok := req.(proto.Message); ok {
+			extractFields(entry.Target, m, cfg.RequestFields)
+		}
+		if m, ok := resp.(proto.Message); ok && err == nil {
+			extractFields(entry.Target, m, cfg.ResponseFields)
+		}
+
+		// The entry is written even if the caller has gone away, so use a context that isn't canceled with the RPC
+		if logErr := a.logger.Log(context.WithoutCancel(ctx), entry); logErr != nil {
+			a.errors.ErrorContext(ctx, "audit entry lost", "method", info.FullMethod, "error", logErr)
+		}
+		return resp, err
+	}
+}
+
+// actor describes the caller established by the authorization middleware.
+func actor(ctx context.Context) string {
+	caller, ok := grpc_middleware.CallerFromContext(ctx)
+	switch {
+	case !ok || caller.Kind == grpc_middleware.CallerAnonymous:
+		return "anonymous"
+	case caller.UserID != "":
+		return "user:" + caller.UserID
+	default:
+		return "service:" + caller.Service
+	}
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func forwardedFor(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(forwardedForMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const loginMethod = "/test.v1.AuthService/Login"
+
+// testMessages builds LoginRequest{email, password [debug_redact], profile: Profile{user_id, api_token}}
+// and LoginResponse{user_id}
+func testMessages(t *testing.T) (request, response protoreflect.MessageDescriptor) {
+	t.Helper()
+
+	field := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+		if typeName != "" {
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	password := field("password", 2, "")
+	password.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/v1/auth.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Profile"), Field: []*descriptorpb.FieldDescriptorProto{field("user_id", 1, ""), field("api_token", 2, "")}},
+			{Name: proto.String("LoginRequest"), Field: []*descriptorpb.FieldDescriptorProto{field("email", 1, ""), password, field("profile", 3, ".test.v1.Profile")}},
+			{Name: proto.String("LoginResponse"), Field: []*descriptorpb.FieldDescriptorProto{field("user_id", 1, "")}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build test descriptor: %v", err)
+	}
+	return file.Messages().ByName("LoginRequest"), file.Messages().ByName("LoginResponse")
+}
+
+func newMessage(md protoreflect.MessageDescriptor, values map[string]string) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	for name, value := range values {
+		m.Set(md.Fields().ByName(protoreflect.Name(name)), protoreflect.ValueOfString(value))
+	}
+	return m
+}
+
+func TestInterceptor_RecordsConfiguredMethod(t *testing.T) {
+	requestDesc, responseDesc := testMessages(t)
+	req := newMessage(requestDesc, map[string]string{"email": "user@example.com", "password": "hunter22"})
+	req.Set(requestDesc.Fields().ByName("profile"), protoreflect.ValueOfMessage(
+		newMessage(requestDesc.Fields().ByName("profile").Message(), map[string]string{"user_id": "u1", "api_token": "t0k"})))
+
+	var buf bytes.Buffer
+	l, _ := NewLogger(NewWriterSink(&buf))
+	interceptor := NewInterceptor(l, map[string]MethodConfig{
+		loginMethod: {RequestFields: []string{"email", "password", "profile", "missing"}, ResponseFields: []string{"user_id"}},
+	}, nil).UnaryServerInterceptor()
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 5000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedForMetadataKey, "203.0.113.9"))
+	ctx = grpc_middleware.ContextWithRequestID(ctx, "req-1")
+	_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return newMessage(responseDesc, map[string]string{"user_id": "u1"}), nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON entry, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]string{
+		"email":    "user@example.com",
+		"password": Redacted,
+		"profile":  `{"api_token":"[REDACTED]","user_id":"u1"}`,
+		"user_id":  "u1",
+	}
+	if !reflect.DeepEqual(entry.Target, expected) {
+		t.Errorf("Expected target %v, got %v", expected, entry.Target)
+	}
+	if entry.Actor != "anonymous" || entry.Result != "OK" || entry.IP != "10.0.0.7" || entry.ForwardedFor != "203.0.113.9" || entry.RequestID != "req-1" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("hunter22")) || bytes.Contains(buf.Bytes(), []byte("t0k")) {
+		t.Errorf("Expected secrets to be redacted, got %s", buf.String())
+	}
+}
+
+func TestInterceptor_RecordsFailureAndCaller(t *testing.T) {
+	requestDesc, _ := testMessages(t)
+
+	var buf bytes.Buffer
+	l, _ := NewLogger(NewWriterSink(&buf))
+	interceptor := NewInterceptor(l, map[string]MethodConfig{
+		loginMethod: {ResponseFields: []string{"user_id"}},
+	}, nil).UnaryServerInterceptor()
+
+	ctx := grpc_middleware.ContextWithCaller(context.Background(), grpc_middleware.Caller{Kind: grpc_middleware.CallerUser, Service: "gateway", UserID: "u1"})
+	_, err := interceptor(ctx, newMessage(requestDesc, nil), &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected the handler's error, got %v", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry.Actor != "user:u1" || entry.Result != "Unauthenticated" || len(entry.Target) != 0 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestInterceptor_IgnoresOtherMethods(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := NewLogger(NewWriterSink(&buf))
+	interceptor := NewInterceptor(l, map[string]MethodConfig{loginMethod: {}}, nil).UnaryServerInterceptor()
+
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.v1.AuthService/GetPublicKeys"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no entry, got %s", buf.String())
+	}
+}
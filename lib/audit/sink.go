@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// WriterSink writes entries as lines to an io.Writer, such as os.Stdout.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes line followed by a newline.
+func (s *WriterSink) Write(ctx context.Context, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close does nothing; the writer is owned by the caller.
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink appends entries to a file and continues the chain of the entries already in it.
+type FileSink struct {
+	WriterSink
+	file *os.File
+}
+
+// OpenFileSink opens path for appending, creating it readable only by its owner.
+func OpenFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileSink{WriterSink: WriterSink{w: f}, file: f}, nil
+}
+
+// LastHash returns the hash of the last entry in the file, or an empty string if it has none.
+func (s *FileSink) LastHash() (string, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	size := info.Size()
+	offset := size - maxLineSize
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, size-offset)
+	if _, err := s.file.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	buf = bytes.TrimRight(buf, "\n")
+	if len(buf) == 0 {
+		return "", nil
+	}
+	last := buf[bytes.LastIndexByte(buf, '\n')+1:]
+
+	var entry Entry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return "", fmt.Errorf("failed to decode last audit entry: %w", err)
+	}
+	return entry.Hash, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Publisher publishes a message to a topic of a message bus.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+}
+
+// BusSink publishes each entry as a message to a topic. Every entry has the same key, so
+// a partitioned bus keeps them in one partition, in chain order.
+type BusSink struct {
+	publisher Publisher
+	topic     string
+}
+
+// busKey is the message key of every audit entry.
+var busKey = []byte("audit")
+
+// NewBusSink creates a sink publishing entries to topic.
+func NewBusSink(publisher Publisher, topic string) *BusSink {
+	return &BusSink{publisher: publisher, topic: topic}
+}
+
+// Write publishes line.
+func (s *BusSink) Write(ctx context.Context, line []byte) error {
+	return s.publisher.Publish(ctx, s.topic, busKey, line)
+}
+
+// Close does nothing; the publisher is owned by the caller.
+func (s *BusSink) Close() error {
+	return nil
+}
+
+// DefaultFilePath is the file SinkFromEnv appends to when AUDIT_SINK is not set.
+const DefaultFilePath = "audit.log"
+
+// SinkFromEnv creates the sink named by AUDIT_SINK: "file:<path>", defaulting to
+// DefaultFilePath, or "stdout". Stdout must be asked for explicitly: the entries are
+// interleaved with the service's logs there, so the chain can't be checked with Verify
+// unless a collector separates them again.
+// A bus sink needs a publisher and is created with NewBusSink.
+func SinkFromEnv() (Sink, error) {
+	value := os.Getenv("AUDIT_SINK")
+	switch {
+	case value == "":
+		return OpenFileSink(DefaultFilePath)
+	case value == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(value, "file:"):
+		return OpenFileSink(strings.TrimPrefix(value, "file:"))
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", value)
+	}
+}
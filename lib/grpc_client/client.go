@@ -0,0 +1,375 @@
+// Package grpc_client builds client connections for calls between services, with the
+// client-side counterparts of the server middleware: request ID and trace propagation,
+// service-token credentials, deadline budgets, retries and metrics.
+package grpc_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// DefaultTimeout is the deadline of calls made without a deadline or request budget.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultReserve is kept back from the request budget for the caller to handle the response.
+	DefaultReserve = 50 * time.Millisecond
+
+	// KeepaliveTime is how often idle connections are pinged to detect dead peers. Servers
+	// built with grpc_middleware.Manager accept pings at this rate.
+	KeepaliveTime = 30 * time.Second
+
+	// KeepaliveTimeout is how long a ping may go unanswered before the connection is closed.
+	KeepaliveTimeout = 10 * time.Second
+)
+
+// retryableStatusCodes are retried for the methods listed in RetryConfig: the request either
+// never reached the server or the server refused it before doing any work.
+var retryableStatusCodes = []string{"UNAVAILABLE"}
+
+// Config describes the connection to one service.
+type Config struct {
+	// Target is the address of the service. Plain host:port addresses are resolved with DNS
+	// and calls are balanced round-robin across every resolved address.
+	Target string
+
+	// Timeout is the deadline of calls made without one. It defaults to DefaultTimeout.
+	// Calls made from a handler are bounded by the request's remaining budget instead.
+	Timeout time.Duration
+
+	// Retry configures retries of methods that are safe to repeat.
+	Retry RetryConfig
+
+	// Streams are the full names of server-streaming methods, which get no timeout: a
+	// stream stays open as long as the client listens, and a timeout would cut it.
+	Streams []string
+}
+
+// RetryConfig declares which methods are retried on Unavailable. Zero values disable retries.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+
+	// Methods are the full names of the methods to retry, e.g.
+	// "/api.social.v1.SocialService/CheckRelationship". Names ending in "/" cover a whole service.
+	Methods []string
+}
+
+// Factory creates client connections and shares them between clients: one connection is kept
+// per target and configuration, multiplexing all calls made through it.
+type Factory struct {
+	config factoryConfig
+
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+}
+
+type factoryConfig struct {
+	service        string
+	token          string
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	reserve        time.Duration
+	dialer         func(ctx context.Context, addr string) (net.Conn, error)
+	dialOptions    []grpc.DialOption
+}
+
+// Option configures a Factory.
+type Option func(*factoryConfig)
+
+// WithServiceToken authenticates calls as service with the shared service token.
+func WithServiceToken(service, token string) Option {
+	return func(c *factoryConfig) {
+		c.service = service
+		c.token = token
+	}
+}
+
+// WithTelemetry traces outgoing calls and records client metrics with the given providers.
+func WithTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) Option {
+	return func(c *factoryConfig) {
+		c.tracerProvider = tp
+		c.meterProvider = mp
+	}
+}
+
+// WithReserve sets the time kept back from the request budget. It defaults to DefaultReserve.
+func WithReserve(reserve time.Duration) Option {
+	return func(c *factoryConfig) {
+		c.reserve = reserve
+	}
+}
+
+// WithBufconn connects every target to lis instead of the network, for tests.
+func WithBufconn(lis *bufconn.Listener) Option {
+	return func(c *factoryConfig) {
+		c.dialer = func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}
+	}
+}
+
+// WithDialOptions appends dial options, applied after the factory's own.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *factoryConfig) {
+		c.dialOptions = append(c.dialOptions, opts...)
+	}
+}
+
+// NewFactory creates a client factory with the given options.
+func NewFactory(opts ...Option) *Factory {
+	cfg := factoryConfig{reserve: DefaultReserve}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Factory{config: cfg, conns: make(map[string]*grpc.ClientConn)}
+}
+
+// Conn returns the connection for cfg, creating it on first use. Connections are lazy,
+// so a service that is down when the connection is created doesn't cause an error.
+func (f *Factory) Conn(cfg Config) (*grpc.ClientConn, error) {
+	if cfg.Target == "" {
+		return nil, fmt.Errorf("client target is required")
+	}
+	serviceConfig, err := ServiceConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil, fmt.Errorf("client factory is closed")
+	}
+	key := cfg.Target + "\x00" + serviceConfig
+	if conn, ok := f.conns[key]; ok {
+		return conn, nil
+	}
+
+	opts, err := f.dialOptions(serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(f.target(cfg.Target), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s: %w", cfg.Target, err)
+	}
+	f.conns[key] = conn
+	return conn, nil
+}
+
+// Close closes every connection created by the factory.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for key, conn := range f.conns {
+		_ = conn.Close()
+		delete(f.conns, key)
+	}
+	return nil
+}
+
+// NewClient returns a typed client for cfg built with a generated constructor, e.g.
+// NewClient(factory, cfg, socialv1.NewSocialServiceClient).
+func NewClient[T any](f *Factory, cfg Config, newClient func(grpc.ClientConnInterface) T) (T, error) {
+	conn, err := f.Conn(cfg)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return newClient(conn), nil
+}
+
+func (f *Factory) target(addr string) string {
+	if f.config.dialer != nil {
+		return "passthrough:///bufnet"
+	}
+	return DNSTarget(addr)
+}
+
+// dialOptions assembles the client interceptors from outermost to innermost: telemetry
+// sees each call once, including its retries, and deadlines are applied before identity.
+func (f *Factory) dialOptions(serviceConfig string) ([]grpc.DialOption, error) {
+	unary := []grpc.UnaryClientInterceptor{}
+	stream := []grpc.StreamClientInterceptor{}
+
+	if f.config.tracerProvider != nil && f.config.meterProvider != nil {
+		tel, err := grpc_middleware.NewClientTelemetry(f.config.tracerProvider, f.config.meterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client telemetry: %w", err)
+		}
+		unary = append(unary, tel.UnaryClientInterceptor())
+		stream = append(stream, tel.StreamClientInterceptor())
+	}
+
+	unary = append(unary,
+		grpc_middleware.RequestIDUnaryClientInterceptor(),
+		grpc_middleware.DeadlineUnaryClientInterceptor(f.config.reserve),
+	)
+	stream = append(stream,
+		grpc_middleware.RequestIDStreamClientInterceptor(),
+		grpc_middleware.DeadlineStreamClientInterceptor(f.config.reserve),
+	)
+
+	if f.config.token != "" {
+		unary = append(unary, grpc_middleware.ServiceTokenUnaryClientInterceptor(f.config.service, f.config.token))
+		stream = append(stream, grpc_middleware.ServiceTokenStreamClientInterceptor(f.config.service, f.config.token))
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                KeepaliveTime,
+			Timeout:             KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}
+	if f.config.dialer != nil {
+		opts = append(opts, grpc.WithContextDialer(f.config.dialer))
+	}
+	return append(opts, f.config.dialOptions...), nil
+}
+
+// serviceConfig is the subset of the gRPC service config (https://github.com/grpc/grpc/blob/master/doc/service_config.md)
+// set for each connection.
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	Timeout     string       `json:"timeout,omitempty"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// ServiceConfig returns the service config JSON for cfg: round-robin balancing over all
+// resolved addresses, a default deadline for every method but the listed streams and
+// retries for the listed methods.
+func ServiceConfig(cfg Config) (string, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	if timeout < 0 || cfg.Retry.MaxAttempts < 0 {
+		return "", fmt.Errorf("client timeout and retry attempts must not be negative")
+	}
+
+	sc := serviceConfig{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+		// An empty name matches every method
+		MethodConfig: []methodConfig{{Name: []methodName{{}}, Timeout: formatDuration(timeout)}},
+	}
+
+	// A method config naming a method takes precedence over the default, so streams
+	// listed without a timeout have none. A name may appear only once, so streams are
+	// never retried.
+	streams, err := methodNames(cfg.Streams)
+	if err != nil {
+		return "", err
+	}
+	if len(streams) > 0 {
+		sc.MethodConfig = append(sc.MethodConfig, methodConfig{Name: streams})
+	}
+	isStream := make(map[methodName]bool, len(streams))
+	for _, name := range streams {
+		isStream[name] = true
+	}
+
+	if cfg.Retry.MaxAttempts >= 2 && len(cfg.Retry.Methods) > 0 {
+		retried, err := methodNames(cfg.Retry.Methods)
+		if err != nil {
+			return "", err
+		}
+		names := make([]methodName, 0, len(retried))
+		for _, name := range retried {
+			if !isStream[name] {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sc.MethodConfig = append(sc.MethodConfig, methodConfig{
+				Name:    names,
+				Timeout: formatDuration(timeout),
+				RetryPolicy: &retryPolicy{
+					MaxAttempts:          cfg.Retry.MaxAttempts,
+					InitialBackoff:       "0.1s",
+					MaxBackoff:           "1s",
+					BackoffMultiplier:    2,
+					RetryableStatusCodes: retryableStatusCodes,
+				},
+			})
+		}
+	}
+
+	buf, err := json.Marshal(sc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode service config: %w", err)
+	}
+	return string(buf), nil
+}
+
+// methodNames parses full method names, sorted so equal configs encode identically.
+// Names ending in "/" cover a whole service.
+func methodNames(fullMethods []string) ([]methodName, error) {
+	sorted := append([]string(nil), fullMethods...)
+	sort.Strings(sorted)
+
+	names := make([]methodName, 0, len(sorted))
+	for _, fullMethod := range sorted {
+		service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+		if !ok || service == "" {
+			return nil, fmt.Errorf("invalid method name %q", fullMethod)
+		}
+		names = append(names, methodName{Service: service, Method: method})
+	}
+	return names, nil
+}
+
+// formatDuration formats d as a service config duration, i.e. seconds with an "s" suffix.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// DNSTarget makes the DNS resolver explicit for plain host:port addresses, so every
+// A record is resolved and round-robin balancing can spread calls across replicas.
+func DNSTarget(addr string) string {
+	if strings.Contains(addr, "://") || strings.HasPrefix(addr, "unix:") {
+		return addr
+	}
+	return "dns:///" + addr
+}
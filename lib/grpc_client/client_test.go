@@ -0,0 +1,211 @@
+package grpc_client
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chat/lib/grpc_middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startServer serves the health service over bufconn behind interceptor and returns the listener
+func startServer(t *testing.T, interceptor grpc.UnaryServerInterceptor) *bufconn.Listener {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+	healthpb.RegisterHealthServer(srv, grpchealth.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+func newTestFactory(t *testing.T, opts ...Option) *Factory {
+	t.Helper()
+	f := NewFactory(opts...)
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestFactory_Bufconn_PropagatesContext(t *testing.T) {
+	var md metadata.MD
+	var hasDeadline bool
+	lis := startServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		_, hasDeadline = ctx.Deadline()
+		return handler(ctx, req)
+	})
+	f := newTestFactory(t, WithBufconn(lis), WithServiceToken("chat", "s3cret"))
+
+	client, err := NewClient(f, Config{Target: "social:8080"}, healthpb.NewHealthClient)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	ctx := grpc_middleware.ContextWithRequestID(context.Background(), "req-1")
+	ctx = grpc_middleware.ContextWithCaller(ctx, grpc_middleware.Caller{Kind: grpc_middleware.CallerUser, UserID: "u1"})
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+
+	expected := map[string]string{
+		grpc_middleware.RequestIDMetadataKey:    "req-1",
+		grpc_middleware.ServiceTokenMetadataKey: "s3cret",
+		grpc_middleware.ServiceNameMetadataKey:  "chat",
+		grpc_middleware.UserIDMetadataKey:       "u1",
+	}
+	for key, value := range expected {
+		if got := md.Get(key); len(got) != 1 || got[0] != value {
+			t.Errorf("Expected %s=%q, got %v", key, value, got)
+		}
+	}
+	if !hasDeadline {
+		t.Error("Expected the default timeout to be applied")
+	}
+}
+
+func TestFactory_Retry_RetriesUnavailable(t *testing.T) {
+	var attempts atomic.Int32
+	lis := startServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if attempts.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "starting")
+		}
+		return handler(ctx, req)
+	})
+	f := newTestFactory(t, WithBufconn(lis))
+
+	client, err := NewClient(f, Config{
+		Target: "social:8080",
+		Retry:  RetryConfig{MaxAttempts: 3, Methods: []string{"/grpc.health.v1.Health/"}},
+	}, healthpb.NewHealthClient)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestFactory_NoRetry_ReturnsUnavailable(t *testing.T) {
+	var attempts atomic.Int32
+	lis := startServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		attempts.Add(1)
+		return nil, status.Error(codes.Unavailable, "down")
+	})
+	f := newTestFactory(t, WithBufconn(lis))
+
+	client, err := NewClient(f, Config{Target: "social:8080"}, healthpb.NewHealthClient)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable, got %v", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestFactory_Conn_SharesConnections(t *testing.T) {
+	f := newTestFactory(t)
+
+	first, err := f.Conn(Config{Target: "social:8080"})
+	if err != nil {
+		t.Fatalf("Conn() failed: %v", err)
+	}
+	same, _ := f.Conn(Config{Target: "social:8080"})
+	other, _ := f.Conn(Config{Target: "social:8080", Timeout: time.Second})
+
+	if first != same {
+		t.Error("Expected the same connection for the same config")
+	}
+	if first == other {
+		t.Error("Expected a separate connection for a different config")
+	}
+}
+
+func TestFactory_Conn_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no target", Config{}},
+		{"negative timeout", Config{Target: "social:8080", Timeout: -time.Second}},
+		{"invalid retry method", Config{Target: "social:8080", Retry: RetryConfig{MaxAttempts: 2, Methods: []string{"Check"}}}},
+	}
+
+	f := newTestFactory(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.Conn(tt.cfg); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFactory_Close_RejectsNewConns(t *testing.T) {
+	f := NewFactory()
+	_ = f.Close()
+
+	if _, err := f.Conn(Config{Target: "social:8080"}); err == nil {
+		t.Error("Expected error after Close, got nil")
+	}
+}
+
+func TestServiceConfig_StreamsHaveNoTimeout(t *testing.T) {
+	stream := "/api.chat.v1.ChatService/StreamMessages"
+	cfgJSON, err := ServiceConfig(Config{
+		Timeout: time.Second,
+		Retry:   RetryConfig{MaxAttempts: 3, Methods: []string{stream, "/api.chat.v1.ChatService/GetChat"}},
+		Streams: []string{stream},
+	})
+	if err != nil {
+		t.Fatalf("ServiceConfig() failed: %v", err)
+	}
+
+	var sc serviceConfig
+	if err := json.Unmarshal([]byte(cfgJSON), &sc); err != nil {
+		t.Fatalf("Invalid service config JSON: %v", err)
+	}
+	configs := make(map[string][]methodConfig)
+	for _, mc := range sc.MethodConfig {
+		for _, name := range mc.Name {
+			configs[name.Method] = append(configs[name.Method], mc)
+		}
+	}
+	if got := configs["StreamMessages"]; len(got) != 1 || got[0].Timeout != "" || got[0].RetryPolicy != nil {
+		t.Errorf("Expected StreamMessages once, without timeout or retries, got %+v", got)
+	}
+	if got := configs["GetChat"]; len(got) != 1 || got[0].Timeout != "1s" || got[0].RetryPolicy == nil {
+		t.Errorf("Expected GetChat retried with the timeout, got %+v", got)
+	}
+}
+
+func TestDNSTarget(t *testing.T) {
+	tests := map[string]string{
+		"social:8080":         "dns:///social:8080",
+		"dns:///social:8080":  "dns:///social:8080",
+		"passthrough:///x:80": "passthrough:///x:80",
+		"unix:/tmp/grpc.sock": "unix:/tmp/grpc.sock",
+	}
+	for addr, expected := range tests {
+		if got := DNSTarget(addr); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, addr, got)
+		}
+	}
+}
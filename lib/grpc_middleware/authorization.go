@@ -0,0 +1,394 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	gochat "github.com/go-chat/lib/pkg/go_chat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// ServiceTokenMetadataKey carries the shared secret proving a call comes from another service.
+	ServiceTokenMetadataKey = "x-service-token"
+
+	// ServiceNameMetadataKey names the calling service, for logs and rate limiting.
+	ServiceNameMetadataKey = "x-service-name"
+
+	// UserRolesMetadataKey carries the roles of the end user a service is calling for.
+	UserRolesMetadataKey = "x-user-roles"
+)
+
+// adminRole is the user role granting ACCESS_ADMIN.
+const adminRole = "admin"
+
+// CallerKind is the kind of identity an RPC was made with.
+type CallerKind int
+
+const (
+	// CallerAnonymous presented no credentials.
+	CallerAnonymous CallerKind = iota
+	// CallerUser is an end user, relayed by a trusted service.
+	CallerUser
+	// CallerService is another service calling on its own behalf.
+	CallerService
+	// CallerAdmin is an end user with the admin role.
+	CallerAdmin
+)
+
+// Caller is the authenticated identity of an RPC.
+type Caller struct {
+	Kind CallerKind
+
+	// Service is the name of the service that made or relayed the call, if any.
+	Service string
+
+	// UserID is the end user the call is made for, if any.
+	UserID string
+
+	// ServiceAuthenticated is set when the call presented a valid service token, whether
+	// the service called on its own behalf or relayed a user.
+	ServiceAuthenticated bool
+}
+
+type callerKey struct{}
+
+// ContextWithCaller returns a copy of ctx carrying caller.
+func ContextWithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller authenticated by the authorization middleware.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// Authenticator establishes the caller of an RPC from its context. It returns an
+// anonymous caller when no credentials are presented and an error when they are invalid.
+type Authenticator func(ctx context.Context) (Caller, error)
+
+// ServiceTokenFromEnv returns the shared service token from SERVICE_TOKEN.
+func ServiceTokenFromEnv() string {
+	return os.Getenv("SERVICE_TOKEN")
+}
+
+// ServiceTokenAuthenticator authenticates services by the shared token in x-service-token.
+// A service calling for an end user also sends x-user-id and x-user-roles, which are only
+// trusted alongside a valid token. When token is empty every presented token is rejected,
+// so only public methods can be called.
+func ServiceTokenAuthenticator(token string) Authenticator {
+	return func(ctx context.Context) (Caller, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		presented := firstValue(md, ServiceTokenMetadataKey)
+		if presented == "" {
+			return Caller{Kind: CallerAnonymous}, nil
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return Caller{}, status.Error(codes.Unauthenticated, "invalid service token")
+		}
+
+		caller := Caller{Kind: CallerService, Service: firstValue(md, ServiceNameMetadataKey), ServiceAuthenticated: true}
+		if userID := firstValue(md, UserIDMetadataKey); userID != "" {
+			caller.Kind = CallerUser
+			caller.UserID = userID
+			if hasRole(md.Get(UserRolesMetadataKey), adminRole) {
+				caller.Kind = CallerAdmin
+			}
+		}
+		return caller, nil
+	}
+}
+
+// ServiceTokenUnaryClientInterceptor returns a unary client interceptor that authenticates calls
+// as service with token. When the context carries a user caller, as it does in handlers behind
+// the authorization middleware, the user's identity is relayed so the call is made on their behalf.
+func ServiceTokenUnaryClientInterceptor(service, token string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoingWithServiceToken(ctx, service, token), method, req, reply, cc, opts...)
+	}
+}
+
+// ServiceTokenStreamClientInterceptor returns a stream client interceptor that authenticates
+// streams as service with token, relaying the user caller from the context.
+func ServiceTokenStreamClientInterceptor(service, token string) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoingWithServiceToken(ctx, service, token), desc, cc, method, opts...)
+	}
+}
+
+// RelayUnaryClientInterceptor returns a unary client interceptor for edge services, such as
+// the gateway, that forward calls from unauthenticated clients. Only calls whose context
+// carries an authenticated caller are made as service with token: a verified user is relayed,
+// and ContextWithCaller with a CallerService marks calls the edge makes on its own behalf.
+// Anonymous calls are sent without credentials, so backends treat them as CallerAnonymous.
+func RelayUnaryClientInterceptor(service, token string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoingRelayed(ctx, service, token), method, req, reply, cc, opts...)
+	}
+}
+
+// RelayStreamClientInterceptor returns a stream client interceptor authenticating streams
+// like RelayUnaryClientInterceptor.
+func RelayStreamClientInterceptor(service, token string) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoingRelayed(ctx, service, token), desc, cc, method, opts...)
+	}
+}
+
+func outgoingRelayed(ctx context.Context, service, token string) context.Context {
+	if caller, ok := CallerFromContext(ctx); ok && caller.Kind != CallerAnonymous {
+		return outgoingWithServiceToken(ctx, service, token)
+	}
+	return withIdentity(ctx)
+}
+
+func outgoingWithServiceToken(ctx context.Context, service, token string) context.Context {
+	pairs := []string{ServiceTokenMetadataKey, token, ServiceNameMetadataKey, service}
+	if caller, ok := CallerFromContext(ctx); ok && caller.UserID != "" {
+		pairs = append(pairs, UserIDMetadataKey, caller.UserID)
+		if caller.Kind == CallerAdmin {
+			pairs = append(pairs, UserRolesMetadataKey, adminRole)
+		}
+	}
+	return withIdentity(ctx, pairs...)
+}
+
+// withIdentity replaces the identity headers of the outgoing metadata with pairs. They are
+// replaced rather than appended, so a value copied from incoming metadata can't be sent
+// alongside the token.
+func withIdentity(ctx context.Context, pairs ...string) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	for _, key := range []string{ServiceTokenMetadataKey, ServiceNameMetadataKey, UserIDMetadataKey, UserRolesMetadataKey} {
+		md.Delete(key)
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		md.Set(pairs[i], pairs[i+1])
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// defaultAccessOverrides sets the access of services registered by lib rather than
+// declared in the services' protos. Keys ending in "/" cover a whole service.
+var defaultAccessOverrides = map[string]gochat.Access{
+	// Container health checks are unauthenticated
+	"/grpc.health.v1.Health/": gochat.Access_ACCESS_PUBLIC,
+	// Reflection exposes the full API surface, so it is kept to tooling holding the service token
+	"/grpc.reflection.v1.ServerReflection/":      gochat.Access_ACCESS_SERVICE,
+	"/grpc.reflection.v1alpha.ServerReflection/": gochat.Access_ACCESS_SERVICE,
+}
+
+// AuthorizationMiddleware enforces the (go_chat.auth) option of each method before its
+// handler runs. The option is read from the method descriptors registered by the generated
+// code. Methods without it are denied, so a new RPC is never exposed by accident.
+type AuthorizationMiddleware struct {
+	authenticate Authenticator
+	overrides    map[string]gochat.Access
+	files        *protoregistry.Files
+
+	access sync.Map // full method name -> gochat.Access
+}
+
+// NewAuthorizationMiddleware creates an authorization middleware authenticating callers with
+// authenticate. overrides set the access of methods, or of whole services with keys ending
+// in "/", on top of the defaults for the health and reflection services.
+func NewAuthorizationMiddleware(authenticate Authenticator, overrides map[string]gochat.Access) *AuthorizationMiddleware {
+	merged := make(map[string]gochat.Access, len(defaultAccessOverrides)+len(overrides))
+	for method, access := range defaultAccessOverrides {
+		merged[method] = access
+	}
+	for method, access := range overrides {
+		merged[method] = access
+	}
+	return &AuthorizationMiddleware{
+		authenticate: authenticate,
+		overrides:    merged,
+		files:        protoregistry.GlobalFiles,
+	}
+}
+
+// UnaryServerInterceptor returns a unary server interceptor enforcing method access.
+func (a *AuthorizationMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor enforcing method access.
+func (a *AuthorizationMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Unannotated returns the methods of services without an access policy, which the
+// middleware denies.
+func (a *AuthorizationMiddleware) Unannotated(services map[string]grpc.ServiceInfo) []string {
+	var missing []string
+	for service, info := range services {
+		for _, method := range info.Methods {
+			fullMethod := "/" + service + "/" + method.Name
+			if a.accessFor(fullMethod) == gochat.Access_ACCESS_UNSPECIFIED {
+				missing = append(missing, fullMethod)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (a *AuthorizationMiddleware) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	access := a.accessFor(fullMethod)
+	if access == gochat.Access_ACCESS_UNSPECIFIED {
+		return nil, status.Error(codes.PermissionDenied, "method has no authorization policy")
+	}
+
+	caller, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.allowed(access) {
+		if caller.Kind == CallerAnonymous {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		return nil, status.Error(codes.PermissionDenied, "caller is not allowed to call this method")
+	}
+	setLoggedUser(ctx, caller.UserID)
+	return ContextWithCaller(ctx, caller), nil
+}
+
+// accessFor returns the access policy of fullMethod, from the overrides or the method's
+// (go_chat.auth) option, caching the result.
+func (a *AuthorizationMiddleware) accessFor(fullMethod string) gochat.Access {
+	if access, ok := a.access.Load(fullMethod); ok {
+		return access.(gochat.Access)
+	}
+
+	access := a.lookup(fullMethod)
+	a.access.Store(fullMethod, access)
+	return access
+}
+
+func (a *AuthorizationMiddleware) lookup(fullMethod string) gochat.Access {
+	if access, ok := a.overrides[fullMethod]; ok {
+		return access
+	}
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return gochat.Access_ACCESS_UNSPECIFIED
+	}
+	if access, ok := a.overrides["/"+service+"/"]; ok {
+		return access
+	}
+
+	desc, err := a.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return gochat.Access_ACCESS_UNSPECIFIED
+	}
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return gochat.Access_ACCESS_UNSPECIFIED
+	}
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method))
+	if methodDesc == nil || methodDesc.Options() == nil {
+		return gochat.Access_ACCESS_UNSPECIFIED
+	}
+	return proto.GetExtension(methodDesc.Options(), gochat.E_Auth).(gochat.Access)
+}
+
+// allowed reports whether the caller may call a method with the given access.
+// Each level admits only its own kind of caller, except that admins are also users and
+// service methods admit any call made with the service token, including relayed ones.
+func (c Caller) allowed(access gochat.Access) bool {
+	switch access {
+	case gochat.Access_ACCESS_PUBLIC:
+		return true
+	case gochat.Access_ACCESS_USER:
+		return c.Kind == CallerUser || c.Kind == CallerAdmin
+	case gochat.Access_ACCESS_SERVICE:
+		return c.Kind == CallerService || c.ServiceAuthenticated
+	case gochat.Access_ACCESS_ADMIN:
+		return c.Kind == CallerAdmin
+	default:
+		return false
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func hasRole(values []string, role string) bool {
+	for _, v := range values {
+		for _, r := range strings.Split(v, ",") {
+			if strings.TrimSpace(r) == role {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package grpc_middleware
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gochat "github.com/go-chat/lib/pkg/go_chat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+const testServiceToken = "s3cret"
+
+// newTestAuthorization registers test.v1.TestService, whose methods are named after their
+// access, in a private registry
+func newTestAuthorization(t *testing.T) *AuthorizationMiddleware {
+	t.Helper()
+
+	method := func(name string, access gochat.Access) *descriptorpb.MethodDescriptorProto {
+		m := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".google.protobuf.Empty"),
+			OutputType: proto.String(".google.protobuf.Empty"),
+		}
+		if access != gochat.Access_ACCESS_UNSPECIFIED {
+			m.Options = &descriptorpb.MethodOptions{}
+			proto.SetExtension(m.Options, gochat.E_Auth, access)
+		}
+		return m
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/service.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/empty.proto", "go_chat/auth.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("TestService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("Public", gochat.Access_ACCESS_PUBLIC),
+				method("User", gochat.Access_ACCESS_USER),
+				method("Service", gochat.Access_ACCESS_SERVICE),
+				method("Admin", gochat.Access_ACCESS_ADMIN),
+				method("Unannotated", gochat.Access_ACCESS_UNSPECIFIED),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("Failed to build test descriptor: %v", err)
+	}
+	files := &protoregistry.Files{}
+	if err := files.RegisterFile(file); err != nil {
+		t.Fatalf("Failed to register test descriptor: %v", err)
+	}
+
+	a := NewAuthorizationMiddleware(ServiceTokenAuthenticator(testServiceToken), nil)
+	a.files = files
+	return a
+}
+
+func callerContext(pairs ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+func TestAuthorizationMiddleware_EnforcesAccess(t *testing.T) {
+	a := newTestAuthorization(t)
+
+	anonymous := context.Background()
+	service := callerContext(ServiceTokenMetadataKey, testServiceToken, ServiceNameMetadataKey, "chat")
+	user := callerContext(ServiceTokenMetadataKey, testServiceToken, UserIDMetadataKey, "u1")
+	admin := callerContext(ServiceTokenMetadataKey, testServiceToken, UserIDMetadataKey, "u1", UserRolesMetadataKey, "member,admin")
+	forged := callerContext(ServiceTokenMetadataKey, "guess", UserIDMetadataKey, "u1")
+	unsigned := callerContext(UserIDMetadataKey, "u1")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		expected codes.Code
+	}{
+		{"public anonymous", anonymous, "/test.v1.TestService/Public", codes.OK},
+		{"user anonymous", anonymous, "/test.v1.TestService/User", codes.Unauthenticated},
+		{"user without token", unsigned, "/test.v1.TestService/User", codes.Unauthenticated},
+		{"user forged token", forged, "/test.v1.TestService/User", codes.Unauthenticated},
+		{"user", user, "/test.v1.TestService/User", codes.OK},
+		{"user as admin", admin, "/test.v1.TestService/User", codes.OK},
+		{"user as service", service, "/test.v1.TestService/User", codes.PermissionDenied},
+		{"service", service, "/test.v1.TestService/Service", codes.OK},
+		{"service relaying user", user, "/test.v1.TestService/Service", codes.OK},
+		{"service anonymous", anonymous, "/test.v1.TestService/Service", codes.Unauthenticated},
+		{"admin", admin, "/test.v1.TestService/Admin", codes.OK},
+		{"admin as user", user, "/test.v1.TestService/Admin", codes.PermissionDenied},
+		{"unannotated", admin, "/test.v1.TestService/Unannotated", codes.PermissionDenied},
+		{"unknown service", service, "/test.v1.OtherService/Do", codes.PermissionDenied},
+		{"health", anonymous, "/grpc.health.v1.Health/Check", codes.OK},
+		{"reflection anonymous", anonymous, "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.UnaryServerInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, okHandler)
+			if got := status.Code(err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizationMiddleware_StoresCaller(t *testing.T) {
+	a := newTestAuthorization(t)
+	stream := &contextServerStream{
+		ServerStream: &mockServerStream{},
+		ctx:          callerContext(ServiceTokenMetadataKey, testServiceToken, ServiceNameMetadataKey, "gateway", UserIDMetadataKey, "u1"),
+	}
+
+	var caller Caller
+	err := a.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test.v1.TestService/User"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			caller, _ = CallerFromContext(ss.Context())
+			return nil
+		})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := (Caller{Kind: CallerUser, Service: "gateway", UserID: "u1", ServiceAuthenticated: true}); caller != expected {
+		t.Errorf("Expected caller %+v, got %+v", expected, caller)
+	}
+}
+
+func TestServiceTokenAuthenticator_EmptyToken_RejectsAllTokens(t *testing.T) {
+	_, err := ServiceTokenAuthenticator("")(callerContext(ServiceTokenMetadataKey, "anything"))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestAuthorizationMiddleware_Unannotated(t *testing.T) {
+	a := newTestAuthorization(t)
+
+	missing := a.Unannotated(map[string]grpc.ServiceInfo{
+		"test.v1.TestService":   {Methods: []grpc.MethodInfo{{Name: "Public"}, {Name: "Unannotated"}}},
+		"grpc.health.v1.Health": {Methods: []grpc.MethodInfo{{Name: "Check"}}},
+	})
+
+	if expected := []string{"/test.v1.TestService/Unannotated"}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Expected %v, got %v", expected, missing)
+	}
+}
+
+func TestAuthorizationMiddleware_Overrides(t *testing.T) {
+	a := NewAuthorizationMiddleware(ServiceTokenAuthenticator(testServiceToken), map[string]gochat.Access{
+		"/legacy.v1.LegacyService/": gochat.Access_ACCESS_PUBLIC,
+	})
+
+	_, err := a.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/legacy.v1.LegacyService/Do"}, okHandler)
+	if err != nil {
+		t.Errorf("Expected override to make the method public, got %v", err)
+	}
+}
+
+func TestServiceTokenUnaryClientInterceptor_RelaysCaller(t *testing.T) {
+	ctx := ContextWithCaller(context.Background(), Caller{Kind: CallerAdmin, Service: "gateway", UserID: "u1"})
+	// A user ID copied from elsewhere must not be sent alongside the token
+	ctx = metadata.AppendToOutgoingContext(ctx, UserIDMetadataKey, "forged")
+
+	var sent metadata.MD
+	err := ServiceTokenUnaryClientInterceptor("chat", testServiceToken)(ctx, "/test.v1.TestService/Admin", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			sent, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	caller, err := ServiceTokenAuthenticator(testServiceToken)(metadata.NewIncomingContext(context.Background(), sent))
+	if err != nil {
+		t.Fatalf("Expected relayed credentials to authenticate, got %v", err)
+	}
+	if expected := (Caller{Kind: CallerAdmin, Service: "chat", UserID: "u1", ServiceAuthenticated: true}); caller != expected {
+		t.Errorf("Expected caller %+v, got %+v", expected, caller)
+	}
+}
+
+func TestRelayUnaryClientInterceptor_SendsTokenOnlyForAuthenticatedCallers(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected Caller
+	}{
+		{"anonymous", context.Background(), Caller{Kind: CallerAnonymous}},
+		{"user", ContextWithCaller(context.Background(), Caller{Kind: CallerUser, UserID: "u1"}),
+			Caller{Kind: CallerUser, Service: "gateway", UserID: "u1", ServiceAuthenticated: true}},
+		{"own behalf", ContextWithCaller(context.Background(), Caller{Kind: CallerService}),
+			Caller{Kind: CallerService, Service: "gateway", ServiceAuthenticated: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Identity copied from a client request must never reach the backend
+			ctx := metadata.AppendToOutgoingContext(tt.ctx, ServiceTokenMetadataKey, testServiceToken, UserIDMetadataKey, "forged")
+
+			var sent metadata.MD
+			err := RelayUnaryClientInterceptor("gateway", testServiceToken)(ctx, "/test.v1.TestService/User", nil, nil, nil,
+				func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+					sent, _ = metadata.FromOutgoingContext(ctx)
+					return nil
+				})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			caller, err := ServiceTokenAuthenticator(testServiceToken)(metadata.NewIncomingContext(context.Background(), sent))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if caller != tt.expected {
+				t.Errorf("Expected caller %+v, got %+v", tt.expected, caller)
+			}
+		})
+	}
+}
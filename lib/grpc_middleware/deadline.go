@@ -0,0 +1,219 @@
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlinePolicy bounds the deadline a method runs with. Zero values disable each bound.
+type DeadlinePolicy struct {
+	// Default is applied when the caller sets no deadline.
+	Default time.Duration
+
+	// Max caps the deadline a caller may set.
+	Max time.Duration
+
+	// MinRemaining rejects requests arriving with less budget than this, since they
+	// would most likely time out halfway through.
+	MinRemaining time.Duration
+}
+
+// DeadlineConfig declares per-method deadline policies, keyed by full method name.
+type DeadlineConfig struct {
+	// Default applies to methods without an entry in Methods.
+	Default DeadlinePolicy
+
+	// Methods overrides Default for individual methods. A zero policy exempts a method,
+	// e.g. a long-lived stream.
+	Methods map[string]DeadlinePolicy
+}
+
+// DefaultDeadlines returns the policy services use for request/response methods: 5s when
+// the caller sets no deadline, at most 30s, and at least 10ms of remaining budget.
+func DefaultDeadlines() DeadlineConfig {
+	return DeadlineConfig{
+		Default: DeadlinePolicy{
+			Default:      5 * time.Second,
+			Max:          30 * time.Second,
+			MinRemaining: 10 * time.Millisecond,
+		},
+	}
+}
+
+// DeadlineMiddleware enforces deadline policies on incoming RPCs and records the resulting
+// deadline as the request's budget, so client interceptors can propagate it to downstream calls.
+type DeadlineMiddleware struct {
+	config DeadlineConfig
+	now    func() time.Time
+}
+
+// NewDeadlineMiddleware creates a deadline middleware from cfg.
+func NewDeadlineMiddleware(cfg DeadlineConfig) (*DeadlineMiddleware, error) {
+	if err := cfg.Default.validate(); err != nil {
+		return nil, fmt.Errorf("invalid default deadline policy: %w", err)
+	}
+	for method, policy := range cfg.Methods {
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("invalid deadline policy for %s: %w", method, err)
+		}
+	}
+	return &DeadlineMiddleware{config: cfg, now: time.Now}, nil
+}
+
+// UnaryServerInterceptor returns a unary server interceptor enforcing the method's deadline policy.
+func (d *DeadlineMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, cancel, err := d.apply(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor enforcing the method's deadline policy.
+func (d *DeadlineMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, cancel, err := d.apply(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// apply bounds the deadline of ctx by the method's policy and records it as the budget.
+func (d *DeadlineMiddleware) apply(ctx context.Context, fullMethod string) (context.Context, context.CancelFunc, error) {
+	policy, ok := d.config.Methods[fullMethod]
+	if !ok {
+		policy = d.config.Default
+	}
+
+	now := d.now()
+	deadline, hasDeadline := ctx.Deadline()
+	switch {
+	case !hasDeadline && policy.Default > 0:
+		deadline, hasDeadline = now.Add(policy.Default), true
+	case hasDeadline && policy.Max > 0 && deadline.Sub(now) > policy.Max:
+		deadline = now.Add(policy.Max)
+	}
+	if !hasDeadline {
+		return ctx, func() {}, nil
+	}
+
+	if remaining := deadline.Sub(now); remaining < policy.MinRemaining {
+		return nil, nil, status.Errorf(codes.DeadlineExceeded,
+			"remaining deadline %s is below the minimum of %s", remaining, policy.MinRemaining)
+	}
+
+	// Shortening the deadline of ctx is a no-op when the caller's is already earlier
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ContextWithBudget(ctx, deadline), cancel, nil
+}
+
+func (p DeadlinePolicy) validate() error {
+	switch {
+	case p.Default < 0 || p.Max < 0 || p.MinRemaining < 0:
+		return fmt.Errorf("durations must not be negative")
+	case p.Max > 0 && p.Default > p.Max:
+		return fmt.Errorf("default %s exceeds max %s", p.Default, p.Max)
+	}
+	return nil
+}
+
+type budgetKey struct{}
+
+// ContextWithBudget returns a copy of ctx recording deadline as the request's budget.
+// Unlike the context deadline, the budget survives context.WithoutCancel, so work detached
+// from the request still can't give downstream calls more time than the caller allowed.
+func ContextWithBudget(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, budgetKey{}, deadline)
+}
+
+// BudgetFromContext returns the deadline by which the current request must complete: the
+// earlier of the context deadline and the recorded budget. ok is false if there is neither.
+func BudgetFromContext(ctx context.Context) (deadline time.Time, ok bool) {
+	deadline, ok = ctx.Deadline()
+	if budget, hasBudget := ctx.Value(budgetKey{}).(time.Time); hasBudget && (!ok || budget.Before(deadline)) {
+		deadline, ok = budget, true
+	}
+	return deadline, ok
+}
+
+// DeadlineUnaryClientInterceptor returns a unary client interceptor that gives downstream calls
+// the request's remaining budget minus reserve, kept back for the caller to handle the response.
+// Calls are failed with DeadlineExceeded without being sent once the budget is spent.
+func DeadlineUnaryClientInterceptor(reserve time.Duration) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, cancel, err := outgoingWithBudget(ctx, method, reserve)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// DeadlineStreamClientInterceptor returns a stream client interceptor that gives downstream
+// streams the request's remaining budget minus reserve.
+func DeadlineStreamClientInterceptor(reserve time.Duration) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, cancel, err := outgoingWithBudget(ctx, method, reserve)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// The stream owns the shortened context; it is released when the stream's context ends
+		context.AfterFunc(stream.Context(), cancel)
+		return stream, nil
+	}
+}
+
+func outgoingWithBudget(ctx context.Context, method string, reserve time.Duration) (context.Context, context.CancelFunc, error) {
+	budget, ok := BudgetFromContext(ctx)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+
+	deadline := budget.Add(-reserve)
+	if !time.Now().Before(deadline) {
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "no deadline budget left to call %s", method)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,178 @@
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const profileMethod = "/api.users.v1.UserService/GetProfileByID"
+
+// remainingHandler returns the time left until the handler context's deadline, or -1 without one
+func remainingHandler(now time.Time) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return time.Duration(-1), nil
+		}
+		return deadline.Sub(now), nil
+	}
+}
+
+func newTestDeadlineMiddleware(t *testing.T, cfg DeadlineConfig, now time.Time) *DeadlineMiddleware {
+	t.Helper()
+	d, err := NewDeadlineMiddleware(cfg)
+	if err != nil {
+		t.Fatalf("NewDeadlineMiddleware() failed: %v", err)
+	}
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func TestDeadlineMiddleware_AppliesPolicy(t *testing.T) {
+	now := time.Now()
+	d := newTestDeadlineMiddleware(t, DeadlineConfig{
+		Default: DeadlinePolicy{Default: 5 * time.Second, Max: 30 * time.Second},
+		Methods: map[string]DeadlinePolicy{
+			"/api.chat.v1.ChatService/StreamMessages": {},
+		},
+	}, now)
+
+	withDeadline := func(timeout time.Duration) context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), now.Add(timeout))
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		expected time.Duration
+	}{
+		{"default when unset", context.Background(), profileMethod, 5 * time.Second},
+		{"caller deadline kept", withDeadline(2 * time.Second), profileMethod, 2 * time.Second},
+		{"capped at max", withDeadline(time.Minute), profileMethod, 30 * time.Second},
+		{"exempt method", context.Background(), "/api.chat.v1.ChatService/StreamMessages", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, err := d.UnaryServerInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, remainingHandler(now))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if remaining != tt.expected {
+				t.Errorf("Expected remaining deadline %v, got %v", tt.expected, remaining)
+			}
+		})
+	}
+}
+
+func TestDeadlineMiddleware_InsufficientBudget_Rejects(t *testing.T) {
+	now := time.Now()
+	d := newTestDeadlineMiddleware(t, DeadlineConfig{Default: DeadlinePolicy{MinRemaining: 50 * time.Millisecond}}, now)
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(10*time.Millisecond))
+	defer cancel()
+
+	called := false
+	_, err := d.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: profileMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if called {
+		t.Error("Expected handler not to be called")
+	}
+}
+
+func TestDeadlineMiddleware_Stream_AppliesDefault(t *testing.T) {
+	d := newTestDeadlineMiddleware(t, DeadlineConfig{Default: DeadlinePolicy{Default: time.Second}}, time.Now())
+	stream := &contextServerStream{ServerStream: &mockServerStream{}, ctx: context.Background()}
+
+	err := d.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		if _, ok := BudgetFromContext(ss.Context()); !ok {
+			t.Error("Expected stream context to carry a budget")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestBudgetFromContext_SurvivesWithoutCancel(t *testing.T) {
+	deadline := time.Now().Add(time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	got, ok := BudgetFromContext(context.WithoutCancel(ContextWithBudget(ctx, deadline)))
+
+	if !ok || !got.Equal(deadline) {
+		t.Errorf("Expected budget %v, got %v (ok=%v)", deadline, got, ok)
+	}
+}
+
+func TestDeadlineUnaryClientInterceptor_ShrinksByReserve(t *testing.T) {
+	budget := time.Now().Add(time.Second)
+	ctx := context.WithoutCancel(ContextWithBudget(context.Background(), budget))
+
+	var sent time.Time
+	err := DeadlineUnaryClientInterceptor(100*time.Millisecond)(ctx, "/api.social.v1.SocialService/CheckRelationship", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			sent, _ = ctx.Deadline()
+			return nil
+		})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := budget.Add(-100 * time.Millisecond); !sent.Equal(expected) {
+		t.Errorf("Expected outgoing deadline %v, got %v", expected, sent)
+	}
+}
+
+func TestDeadlineUnaryClientInterceptor_BudgetSpent_FailsFast(t *testing.T) {
+	ctx := ContextWithBudget(context.Background(), time.Now().Add(50*time.Millisecond))
+
+	called := false
+	err := DeadlineUnaryClientInterceptor(100*time.Millisecond)(ctx, "/api.social.v1.SocialService/CheckRelationship", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			called = true
+			return nil
+		})
+
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if called {
+		t.Error("Expected call not to be sent")
+	}
+}
+
+func TestDeadlineUnaryClientInterceptor_NoBudget_PassesThrough(t *testing.T) {
+	err := DeadlineUnaryClientInterceptor(time.Second)(context.Background(), "/test.v1.TestService/Do", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("Expected no deadline to be added")
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewDeadlineMiddleware_DefaultAboveMax_ReturnsError(t *testing.T) {
+	_, err := NewDeadlineMiddleware(DeadlineConfig{Default: DeadlinePolicy{Default: time.Minute, Max: time.Second}})
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
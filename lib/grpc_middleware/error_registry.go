@@ -0,0 +1,163 @@
+package grpc_middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// internalMessage is the only message clients see for unregistered errors.
+const internalMessage = "internal server error"
+
+// ErrorMapping is the status returned to clients for a registered domain error.
+type ErrorMapping struct {
+	// Code is the gRPC status code.
+	Code codes.Code
+
+	// Message is the public status message. It replaces the domain error's text,
+	// which may carry internal context added by wrapping.
+	Message string
+
+	// Reason, when set, is attached as a google.rpc.ErrorInfo with the registry's domain,
+	// giving clients a stable machine-readable identifier such as CHAT_NOT_FOUND.
+	Reason string
+
+	// Details are attached to the status after the ErrorInfo.
+	Details []protoadapt.MessageV1
+}
+
+type errorEntry struct {
+	target  error
+	mapping ErrorMapping
+}
+
+// ErrorRegistry converts errors returned by handlers into gRPC statuses. Registered
+// domain errors, matched with errors.Is, get their mapped code, message and details.
+// Errors that already carry a status pass through, context errors become Canceled or
+// DeadlineExceeded, and anything else is logged with its correlation ID and masked as Internal.
+type ErrorRegistry struct {
+	domain  string
+	logger  *slog.Logger
+	entries []errorEntry
+}
+
+// NewErrorRegistry creates a registry reporting ErrorInfo under domain, e.g. "chat.go-chat".
+// Unmapped errors are logged to logger, or to slog.Default() when logger is nil.
+func NewErrorRegistry(domain string, logger *slog.Logger) *ErrorRegistry {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ErrorRegistry{domain: domain, logger: logger}
+}
+
+// Register maps errors matching target to m. Targets are tried in registration order,
+// so register more specific errors first. It returns r so registrations can be chained.
+func (r *ErrorRegistry) Register(target error, m ErrorMapping) *ErrorRegistry {
+	r.entries = append(r.entries, errorEntry{target: target, mapping: m})
+	return r
+}
+
+// ToStatus converts err into a status error. It returns nil for a nil err.
+func (r *ErrorRegistry) ToStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Errors that already carry a status (and possibly google.rpc details) pass through untouched
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, e := range r.entries {
+		if errors.Is(err, e.target) {
+			return r.mappedStatus(ctx, err, e.mapping)
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return r.internalStatus(ctx, err)
+}
+
+func (r *ErrorRegistry) mappedStatus(ctx context.Context, err error, m ErrorMapping) error {
+	// Domain errors are expected, but the wrapped context is useful when debugging
+	r.logger.LogAttrs(ctx, slog.LevelDebug, "mapped domain error",
+		slog.String("request_id", RequestIDFromContext(ctx)),
+		slog.String("code", m.Code.String()),
+		slog.String("error", err.Error()),
+	)
+
+	st := status.New(m.Code, m.Message)
+
+	details := make([]protoadapt.MessageV1, 0, len(m.Details)+1)
+	if m.Reason != "" {
+		details = append(details, &errdetails.ErrorInfo{Reason: m.Reason, Domain: r.domain})
+	}
+	details = append(details, m.Details...)
+	if len(details) == 0 {
+		return st.Err()
+	}
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// internalStatus logs err and hides it behind a generic message. The correlation ID is
+// returned in a google.rpc.RequestInfo so a client report can be matched to the log line.
+func (r *ErrorRegistry) internalStatus(ctx context.Context, err error) error {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		id = NewRequestID()
+	}
+
+	r.logger.LogAttrs(ctx, slog.LevelError, "internal error",
+		slog.String("request_id", id),
+		slog.String("error", err.Error()),
+	)
+
+	st := status.New(codes.Internal, internalMessage)
+	withDetails, detailsErr := st.WithDetails(&errdetails.RequestInfo{RequestId: id})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// UnaryServerInterceptor returns a unary server interceptor converting handler errors with ToStatus.
+func (r *ErrorRegistry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, r.ToStatus(ctx, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor converting handler errors with ToStatus.
+func (r *ErrorRegistry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return r.ToStatus(ss.Context(), handler(srv, ss))
+	}
+}
@@ -0,0 +1,162 @@
+package grpc_middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+var (
+	errChatNotFound = errors.New("chat not found")
+	errChatFull     = errors.New("chat is full")
+)
+
+func newTestRegistry(logs *bytes.Buffer) *ErrorRegistry {
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return NewErrorRegistry("chat.go-chat", logger).
+		Register(errChatNotFound, ErrorMapping{Code: codes.NotFound, Message: "chat not found", Reason: "CHAT_NOT_FOUND"}).
+		Register(errChatFull, ErrorMapping{
+			Code:    codes.FailedPrecondition,
+			Message: "chat is full",
+			Details: []protoadapt.MessageV1{&errdetails.PreconditionFailure{
+				Violations: []*errdetails.PreconditionFailure_Violation{{Type: "CAPACITY", Subject: "chat"}},
+			}},
+		})
+}
+
+func TestErrorRegistry_ToStatus_MapsWrappedDomainError(t *testing.T) {
+	var logs bytes.Buffer
+	r := newTestRegistry(&logs)
+
+	err := r.ToStatus(context.Background(), fmt.Errorf("load chat c1: %w", errChatNotFound))
+
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound || st.Message() != "chat not found" {
+		t.Fatalf("Expected NotFound 'chat not found', got %v '%s'", st.Code(), st.Message())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("Expected ErrorInfo detail, got %v", details)
+	}
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok || info.GetReason() != "CHAT_NOT_FOUND" || info.GetDomain() != "chat.go-chat" {
+		t.Errorf("Unexpected ErrorInfo: %v", details[0])
+	}
+	if !strings.Contains(logs.String(), "load chat c1") {
+		t.Errorf("Expected wrapped error to be logged, got %s", logs.String())
+	}
+}
+
+func TestErrorRegistry_ToStatus_AttachesDetails(t *testing.T) {
+	r := newTestRegistry(&bytes.Buffer{})
+
+	st := status.Convert(r.ToStatus(context.Background(), errChatFull))
+
+	if st.Code() != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, got %v", st.Code())
+	}
+	if details := st.Details(); len(details) != 1 {
+		t.Errorf("Expected only the registered detail without a reason, got %v", details)
+	} else if _, ok := details[0].(*errdetails.PreconditionFailure); !ok {
+		t.Errorf("Expected PreconditionFailure, got %T", details[0])
+	}
+}
+
+func TestErrorRegistry_ToStatus_UnknownError_MasksAsInternal(t *testing.T) {
+	var logs bytes.Buffer
+	r := newTestRegistry(&logs)
+	ctx := ContextWithRequestID(context.Background(), "req-42")
+
+	st := status.Convert(r.ToStatus(ctx, errors.New("pq: connection refused")))
+
+	if st.Code() != codes.Internal || st.Message() != "internal server error" {
+		t.Fatalf("Expected masked Internal error, got %v '%s'", st.Code(), st.Message())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("Expected RequestInfo detail, got %v", details)
+	}
+	if info, ok := details[0].(*errdetails.RequestInfo); !ok || info.GetRequestId() != "req-42" {
+		t.Errorf("Expected request ID req-42 in RequestInfo, got %v", details[0])
+	}
+	if !strings.Contains(logs.String(), "pq: connection refused") || !strings.Contains(logs.String(), "req-42") {
+		t.Errorf("Expected internal error to be logged with its request ID, got %s", logs.String())
+	}
+}
+
+func TestErrorRegistry_ToStatus_PassThrough(t *testing.T) {
+	r := newTestRegistry(&bytes.Buffer{})
+
+	tests := []struct {
+		name     string
+		err      error
+		expected codes.Code
+	}{
+		{"nil", nil, codes.OK},
+		{"status error", status.Error(codes.InvalidArgument, "bad input"), codes.InvalidArgument},
+		{"canceled", context.Canceled, codes.Canceled},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(r.ToStatus(context.Background(), tt.err)); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestErrorRegistry_StreamServerInterceptor_MapsError(t *testing.T) {
+	r := newTestRegistry(&bytes.Buffer{})
+	interceptor := r.StreamServerInterceptor()
+	stream := &contextServerStream{ServerStream: &mockServerStream{}, ctx: context.Background()}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		return errChatNotFound
+	})
+
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestErrorRegistry_UnaryServerInterceptor_PassesThroughResponse(t *testing.T) {
+	r := newTestRegistry(&bytes.Buffer{})
+	interceptor := r.UnaryServerInterceptor()
+
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	if err != nil || resp != "ok" {
+		t.Errorf("Expected response 'ok' without error, got %v, %v", resp, err)
+	}
+}
+
+func TestErrorRegistry_UnaryServerInterceptor_StatusError_PassesThrough(t *testing.T) {
+	r := newTestRegistry(&bytes.Buffer{})
+	interceptor := r.UnaryServerInterceptor()
+	original := status.Error(codes.ResourceExhausted, "rate limit exceeded")
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, original
+	})
+
+	if !errors.Is(err, original) {
+		t.Fatalf("Expected original status error to pass through, got: %v", err)
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected code ResourceExhausted, got %v", status.Code(err))
+	}
+}
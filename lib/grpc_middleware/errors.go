@@ -1,16 +1,21 @@
 package grpc_middleware
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"buf.build/go/protovalidate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // formatValidationError converts a protovalidate error into a gRPC status error.
 // It returns an InvalidArgument error code with a descriptive message containing
-// all validation violations.
+// all validation violations. Each violation is also attached as a google.rpc.BadRequest
+// field violation, so clients can point at the invalid field; the message remains
+// as a human-readable fallback.
 func formatValidationError(err error) error {
 	if err == nil {
 		return nil
@@ -18,8 +23,37 @@ func formatValidationError(err error) error {
 
 	// Format the error message to be client-friendly
 	msg := formatErrorMessage(err.Error())
+	st := status.New(codes.InvalidArgument, msg)
 
-	return status.Error(codes.InvalidArgument, msg)
+	badRequest := badRequestFromError(err)
+	if badRequest == nil {
+		return st.Err()
+	}
+	withDetails, detailsErr := st.WithDetails(badRequest)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// badRequestFromError builds a BadRequest with one field violation per protovalidate
+// violation, carrying the field path (e.g. "profile.nickname"), the rule ID as the reason
+// (e.g. "string.pattern") and the rule's message. It returns nil for other errors.
+func badRequestFromError(err error) *errdetails.BadRequest {
+	var validationErr *protovalidate.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) == 0 {
+		return nil
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range validationErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       protovalidate.FieldPathString(violation.Proto.GetField()),
+			Reason:      violation.Proto.GetRuleId(),
+			Description: violation.Proto.GetMessage(),
+		})
+	}
+	return badRequest
 }
 
 // formatErrorMessage formats the validation error message for better readability.
@@ -0,0 +1,79 @@
+package grpc_middleware
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"buf.build/go/protovalidate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func fieldPath(names ...string) *validate.FieldPath {
+	path := &validate.FieldPath{}
+	for _, name := range names {
+		path.Elements = append(path.Elements, &validate.FieldPathElement{FieldName: proto.String(name)})
+	}
+	return path
+}
+
+func TestFormatValidationError_AttachesFieldViolations(t *testing.T) {
+	err := &protovalidate.ValidationError{Violations: []*protovalidate.Violation{
+		{Proto: &validate.Violation{
+			Field:   fieldPath("nickname"),
+			RuleId:  proto.String("string.pattern"),
+			Message: proto.String("value does not match regex pattern `^[a-z0-9_]+$`"),
+		}},
+		{Proto: &validate.Violation{
+			Field:   fieldPath("credentials", "password"),
+			RuleId:  proto.String("string.min_len"),
+			Message: proto.String("value length must be at least 8 characters"),
+		}},
+	}}
+
+	st := status.Convert(formatValidationError(err))
+
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", st.Code())
+	}
+	if !strings.Contains(st.Message(), "nickname") || !strings.Contains(st.Message(), "credentials.password") {
+		t.Errorf("Expected message to keep describing the violations, got '%s'", st.Message())
+	}
+
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("Expected BadRequest detail, got %v", details)
+	}
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	if !ok {
+		t.Fatalf("Expected BadRequest, got %T", details[0])
+	}
+
+	expected := []*errdetails.BadRequest_FieldViolation{
+		{Field: "nickname", Reason: "string.pattern", Description: "value does not match regex pattern `^[a-z0-9_]+$`"},
+		{Field: "credentials.password", Reason: "string.min_len", Description: "value length must be at least 8 characters"},
+	}
+	if len(badRequest.GetFieldViolations()) != len(expected) {
+		t.Fatalf("Expected %d field violations, got %v", len(expected), badRequest.GetFieldViolations())
+	}
+	for i, want := range expected {
+		if got := badRequest.GetFieldViolations()[i]; !proto.Equal(got, want) {
+			t.Errorf("Expected violation %v, got %v", want, got)
+		}
+	}
+}
+
+func TestFormatValidationError_OtherError_NoDetails(t *testing.T) {
+	st := status.Convert(formatValidationError(errors.New("compilation error: bad rule")))
+
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", st.Code())
+	}
+	if len(st.Details()) != 0 {
+		t.Errorf("Expected no details, got %v", st.Details())
+	}
+}
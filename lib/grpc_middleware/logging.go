@@ -0,0 +1,118 @@
+package grpc_middleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UserIDMetadataKey is the gRPC metadata key carrying the authenticated user ID,
+// set by the gateway after validating the caller's JWT.
+const UserIDMetadataKey = "x-user-id"
+
+type requestLogKey struct{}
+
+// requestLog collects fields established by inner stages, such as the authenticated user.
+type requestLog struct {
+	userID string
+}
+
+// setLoggedUser records the user authenticated by the authorization middleware for the
+// log line. It is a no-op outside of the logging middleware.
+func setLoggedUser(ctx context.Context, userID string) {
+	if l, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		l.userID = userID
+	}
+}
+
+// LoggingMiddleware logs one structured line per RPC with method, status code,
+// latency, user ID, peer address, request ID and, when tracing is enabled, trace ID.
+// The user ID is the one verified by the authorization middleware; x-user-id metadata
+// alone is never logged, since any client can send it.
+type LoggingMiddleware struct {
+	logger *slog.Logger
+}
+
+// NewLoggingMiddleware creates a new logging middleware writing to the given logger.
+func NewLoggingMiddleware(logger *slog.Logger) *LoggingMiddleware {
+	return &LoggingMiddleware{logger: logger}
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that logs each call after it completes.
+func (l *LoggingMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		entry := &requestLog{}
+		resp, err := handler(context.WithValue(ctx, requestLogKey{}, entry), req)
+		l.log(ctx, info.FullMethod, start, entry, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that logs each stream after it ends.
+func (l *LoggingMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+		entry := &requestLog{}
+		ctx := context.WithValue(ss.Context(), requestLogKey{}, entry)
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+		l.log(ss.Context(), info.FullMethod, start, entry, err)
+		return err
+	}
+}
+
+func (l *LoggingMiddleware) log(ctx context.Context, method string, start time.Time, entry *requestLog, err error) {
+	code := status.Code(err)
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("request_id", RequestIDFromContext(ctx)),
+		slog.String("user_id", entry.userID),
+		slog.String("peer", peerAddr(ctx)),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	l.logger.LogAttrs(ctx, levelForCode(code), "grpc request", attrs...)
+}
+
+// levelForCode logs server-side failures at error level and client mistakes at warn level.
+func levelForCode(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented, codes.Unavailable, codes.DeadlineExceeded:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package grpc_middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log line %q: %v", buf.String(), err)
+	}
+	return entry
+}
+
+func TestLoggingMiddleware_UnaryServerInterceptor_LogsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := NewLoggingMiddleware(newTestLogger(&buf)).UnaryServerInterceptor()
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+	ctx = ContextWithRequestID(ctx, "req-1")
+
+	// The authorization stage records the verified user
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		setLoggedUser(ctx, "user-1")
+		return "ok", nil
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.chat.v1.ChatService/SendMessage"}
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	entry := decodeLogLine(t, &buf)
+
+	expected := map[string]string{
+		"level":      "INFO",
+		"method":     "/api.chat.v1.ChatService/SendMessage",
+		"code":       "OK",
+		"request_id": "req-1",
+		"user_id":    "user-1",
+		"peer":       "10.0.0.1:5000",
+	}
+	for key, want := range expected {
+		if got := entry[key]; got != want {
+			t.Errorf("Expected %s=%q, got %v", key, want, got)
+		}
+	}
+	if _, ok := entry["latency"]; !ok {
+		t.Error("Expected latency to be logged")
+	}
+}
+
+func TestLoggingMiddleware_LogsOnlyVerifiedUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		pairs    []string
+		expected string
+	}{
+		// x-user-id without the service token is not trusted by authorization
+		{"unverified", []string{UserIDMetadataKey, "forged"}, ""},
+		{"relayed", []string{ServiceTokenMetadataKey, testServiceToken, UserIDMetadataKey, "user-1"}, "user-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logging := NewLoggingMiddleware(newTestLogger(&buf)).UnaryServerInterceptor()
+			authz := NewAuthorizationMiddleware(ServiceTokenAuthenticator(testServiceToken), nil).UnaryServerInterceptor()
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(tt.pairs...))
+			info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+			_, _ = logging(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return authz(ctx, req, info, okHandler)
+			})
+
+			if entry := decodeLogLine(t, &buf); entry["user_id"] != tt.expected {
+				t.Errorf("Expected user_id %q, got %v", tt.expected, entry["user_id"])
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_UnaryServerInterceptor_LogsErrorLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedLevel string
+		expectedCode  string
+	}{
+		{"client error", status.Error(codes.NotFound, "chat not found"), "WARN", "NotFound"},
+		{"server error", status.Error(codes.Internal, "internal server error"), "ERROR", "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			interceptor := NewLoggingMiddleware(newTestLogger(&buf)).UnaryServerInterceptor()
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, tt.err
+			}
+
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+			if err != tt.err {
+				t.Errorf("Expected handler error to be returned unchanged, got: %v", err)
+			}
+
+			entry := decodeLogLine(t, &buf)
+			if entry["level"] != tt.expectedLevel {
+				t.Errorf("Expected level %s, got %v", tt.expectedLevel, entry["level"])
+			}
+			if entry["code"] != tt.expectedCode {
+				t.Errorf("Expected code %s, got %v", tt.expectedCode, entry["code"])
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_StreamServerInterceptor_LogsStream(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := NewLoggingMiddleware(newTestLogger(&buf)).StreamServerInterceptor()
+
+	stream := &headerCapturingStream{
+		mockServerStream: &mockServerStream{},
+		ctx:              ContextWithRequestID(context.Background(), "req-2"),
+		header:           &metadata.MD{},
+	}
+
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/api.chat.v1.ChatService/StreamMessages"}
+	if err := interceptor(nil, stream, info, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	entry := decodeLogLine(t, &buf)
+	if entry["method"] != "/api.chat.v1.ChatService/StreamMessages" {
+		t.Errorf("Unexpected method: %v", entry["method"])
+	}
+	if entry["request_id"] != "req-2" {
+		t.Errorf("Expected request_id req-2, got %v", entry["request_id"])
+	}
+}
@@ -2,21 +2,108 @@ package grpc_middleware
 
 import (
 	"fmt"
+	"log/slog"
+	"time"
 
+	gochat "github.com/go-chat/lib/pkg/go_chat"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
+// keepaliveMinTime is the shortest interval at which clients may send keepalive pings.
+// It is below the 30s interval used by lib/grpc_client.
+const keepaliveMinTime = 20 * time.Second
+
 // Manager orchestrates gRPC middleware in the correct order.
 // It provides a centralized way to configure and apply middleware across all services.
 type Manager struct {
 	config *Config
 }
 
+// Stage is a named position in the interceptor pipeline. Stages run in the order they
+// are declared, from outermost to innermost.
+type Stage int
+
+const (
+	// StageRecovery converts panics into Internal errors. It is outermost so a panic
+	// anywhere in the chain is caught.
+	StageRecovery Stage = iota
+	// StageTelemetry traces and measures each RPC, covering the rest of the chain.
+	StageTelemetry
+	// StageRequestID takes the correlation ID from incoming metadata or generates one.
+	StageRequestID
+	// StageLogging logs each RPC once, after it completes, with the final status code.
+	StageLogging
+	// StageDeadline applies default and maximum deadlines and rejects requests without
+	// enough budget left.
+	StageDeadline
+	// StageAuth establishes and checks the caller's identity.
+	StageAuth
+	// StageRateLimit rejects callers over their quota before any work is done.
+	StageRateLimit
+	// StageValidation validates requests against their protovalidate rules.
+	StageValidation
+	// StageErrors maps domain errors returned by handlers to gRPC statuses. It is
+	// innermost so every outer stage sees the final status.
+	StageErrors
+)
+
+// StageInterceptor is a custom interceptor pair run at Stage, after the stage's built-in
+// interceptor if it has one. Either interceptor may be nil.
+type StageInterceptor struct {
+	Stage  Stage
+	Unary  grpc.UnaryServerInterceptor
+	Stream grpc.StreamServerInterceptor
+}
+
 // Config holds middleware configuration options.
 type Config struct {
 	// ValidationEnabled controls whether request validation middleware is active.
 	// When enabled, all incoming requests are validated against proto validation rules.
 	ValidationEnabled bool
+
+	// ResponseValidation, when not zero, also validates outgoing responses and takes these
+	// actions on invalid ones. It requires ValidationEnabled.
+	ResponseValidation ResponseAction
+
+	// Logger enables request ID propagation and per-request logging when set.
+	// Each RPC is logged once, after the handler returns.
+	Logger *slog.Logger
+
+	// RequestIDEnabled propagates the request ID without logging. It is implied by Logger.
+	RequestIDEnabled bool
+
+	// TracerProvider and MeterProvider enable tracing and RED metrics when both are set.
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+
+	// RecoveryEnabled converts handler panics into Internal errors. Recovery is the
+	// outermost interceptor, so a panic anywhere in the chain is caught.
+	RecoveryEnabled bool
+
+	// PanicHook is called for every recovered panic when recovery is enabled.
+	PanicHook PanicHook
+
+	// Authenticator, when set, enforces the (go_chat.auth) access of each method at StageAuth.
+	Authenticator Authenticator
+
+	// AccessOverrides sets the access of methods, or of whole services with keys ending
+	// in "/", that aren't annotated in their protos.
+	AccessOverrides map[string]gochat.Access
+
+	// Deadlines, when set, enforces per-method deadline policies at StageDeadline.
+	Deadlines *DeadlineConfig
+
+	// RateLimit, when set, enforces per-method rate and concurrency limits at StageRateLimit.
+	RateLimit *RateLimitConfig
+
+	// ErrorRegistry, when set, maps domain errors to statuses at StageErrors.
+	ErrorRegistry *ErrorRegistry
+
+	// Interceptors are custom interceptors inserted at their stage, in the order given.
+	Interceptors []StageInterceptor
 }
 
 // Option is a functional option for configuring the Manager.
@@ -36,38 +123,178 @@ func NewManager(opts ...Option) (*Manager, error) {
 	return &Manager{config: cfg}, nil
 }
 
+// ServerOptions returns the options installing the unary and stream interceptor chains and
+// the keepalive policy, ready to pass to grpc.NewServer.
+func (m *Manager) ServerOptions() ([]grpc.ServerOption, error) {
+	stages, err := m.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors(stages)...),
+		grpc.ChainStreamInterceptor(streamInterceptors(stages)...),
+		// Accept the keepalive pings of lib/grpc_client, which the default policy would
+		// answer with GOAWAY
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+	}, nil
+}
+
 // UnaryInterceptors returns all unary server interceptors in the correct order.
 // Interceptors are ordered from outermost to innermost in the execution chain.
 func (m *Manager) UnaryInterceptors() ([]grpc.UnaryServerInterceptor, error) {
-	var interceptors []grpc.UnaryServerInterceptor
-
-	// Validation middleware - validate requests before handler execution
-	if m.config.ValidationEnabled {
-		validator, err := NewValidationMiddleware()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create validation middleware: %w", err)
-		}
-		interceptors = append(interceptors, validator.UnaryServerInterceptor())
+	stages, err := m.pipeline()
+	if err != nil {
+		return nil, err
 	}
-
-	return interceptors, nil
+	return unaryInterceptors(stages), nil
 }
 
 // StreamInterceptors returns all stream server interceptors in the correct order.
 // Interceptors are ordered from outermost to innermost in the execution chain.
 func (m *Manager) StreamInterceptors() ([]grpc.StreamServerInterceptor, error) {
-	var interceptors []grpc.StreamServerInterceptor
+	stages, err := m.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	return streamInterceptors(stages), nil
+}
 
-	// Validation middleware - validate stream messages
-	if m.config.ValidationEnabled {
-		validator, err := NewValidationMiddleware()
+// pipeline builds the enabled built-in interceptors and the custom ones, ordered by stage.
+func (m *Manager) pipeline() ([]StageInterceptor, error) {
+	var pipeline []StageInterceptor
+	for stage := StageRecovery; stage <= StageErrors; stage++ {
+		builtin, err := m.builtin(stage)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, builtin...)
+
+		for _, custom := range m.config.Interceptors {
+			if custom.Stage == stage {
+				pipeline = append(pipeline, custom)
+			}
+		}
+	}
+	return pipeline, nil
+}
+
+// builtin returns the interceptors the configuration enables at stage.
+func (m *Manager) builtin(stage Stage) ([]StageInterceptor, error) {
+	switch stage {
+	case StageRecovery:
+		if !m.config.RecoveryEnabled {
+			return nil, nil
+		}
+		recovery, err := NewRecoveryMiddleware(m.config.Logger, m.config.MeterProvider, m.config.PanicHook)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create recovery middleware: %w", err)
+		}
+		return []StageInterceptor{{stage, recovery.UnaryServerInterceptor(), recovery.StreamServerInterceptor()}}, nil
+
+	case StageTelemetry:
+		if !m.telemetryEnabled() {
+			return nil, nil
+		}
+		telemetry, err := NewTelemetryMiddleware(m.config.TracerProvider, m.config.MeterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create telemetry middleware: %w", err)
+		}
+		return []StageInterceptor{{stage, telemetry.UnaryServerInterceptor(), telemetry.StreamServerInterceptor()}}, nil
+
+	case StageRequestID:
+		if !m.config.RequestIDEnabled && m.config.Logger == nil {
+			return nil, nil
+		}
+		return []StageInterceptor{{stage, RequestIDUnaryServerInterceptor(), RequestIDStreamServerInterceptor()}}, nil
+
+	case StageLogging:
+		if m.config.Logger == nil {
+			return nil, nil
+		}
+		logging := NewLoggingMiddleware(m.config.Logger)
+		return []StageInterceptor{{stage, logging.UnaryServerInterceptor(), logging.StreamServerInterceptor()}}, nil
+
+	case StageDeadline:
+		if m.config.Deadlines == nil {
+			return nil, nil
+		}
+		deadlines, err := NewDeadlineMiddleware(*m.config.Deadlines)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create deadline middleware: %w", err)
+		}
+		return []StageInterceptor{{stage, deadlines.UnaryServerInterceptor(), deadlines.StreamServerInterceptor()}}, nil
+
+	case StageAuth:
+		if m.config.Authenticator == nil {
+			return nil, nil
+		}
+		authz := NewAuthorizationMiddleware(m.config.Authenticator, m.config.AccessOverrides)
+		return []StageInterceptor{{stage, authz.UnaryServerInterceptor(), authz.StreamServerInterceptor()}}, nil
+
+	case StageRateLimit:
+		if m.config.RateLimit == nil {
+			return nil, nil
+		}
+		limiter, err := NewRateLimiter(*m.config.RateLimit, m.config.MeterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+		}
+		return []StageInterceptor{{stage, limiter.UnaryServerInterceptor(), limiter.StreamServerInterceptor()}}, nil
+
+	case StageValidation:
+		if !m.config.ValidationEnabled {
+			return nil, nil
+		}
+		validator, err := NewValidationMiddleware(
+			ValidateResponses(m.config.ResponseValidation, m.config.Logger, m.config.MeterProvider),
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validation middleware: %w", err)
 		}
-		interceptors = append(interceptors, validator.StreamServerInterceptor())
+		return []StageInterceptor{{stage, validator.UnaryServerInterceptor(), validator.StreamServerInterceptor()}}, nil
+
+	case StageErrors:
+		if m.config.ErrorRegistry == nil {
+			return nil, nil
+		}
+		registry := m.config.ErrorRegistry
+		return []StageInterceptor{{stage, registry.UnaryServerInterceptor(), registry.StreamServerInterceptor()}}, nil
 	}
 
-	return interceptors, nil
+	return nil, nil
+}
+
+// UnannotatedMethods returns the methods of services that authorization would deny because
+// they have no access policy, e.g. to log them once the services are registered.
+func (m *Manager) UnannotatedMethods(services map[string]grpc.ServiceInfo) []string {
+	return NewAuthorizationMiddleware(m.config.Authenticator, m.config.AccessOverrides).Unannotated(services)
+}
+
+func (m *Manager) telemetryEnabled() bool {
+	return m.config.TracerProvider != nil && m.config.MeterProvider != nil
+}
+
+func unaryInterceptors(stages []StageInterceptor) []grpc.UnaryServerInterceptor {
+	var interceptors []grpc.UnaryServerInterceptor
+	for _, s := range stages {
+		if s.Unary != nil {
+			interceptors = append(interceptors, s.Unary)
+		}
+	}
+	return interceptors
+}
+
+func streamInterceptors(stages []StageInterceptor) []grpc.StreamServerInterceptor {
+	var interceptors []grpc.StreamServerInterceptor
+	for _, s := range stages {
+		if s.Stream != nil {
+			interceptors = append(interceptors, s.Stream)
+		}
+	}
+	return interceptors
 }
 
 // WithValidation enables or disables validation middleware.
@@ -78,3 +305,85 @@ func WithValidation(enabled bool) Option {
 	}
 }
 
+// WithResponseValidation validates outgoing responses, taking actions on invalid ones.
+// Zero actions leave responses unchecked.
+func WithResponseValidation(actions ResponseAction) Option {
+	return func(c *Config) {
+		c.ResponseValidation = actions
+	}
+}
+
+// WithLogger enables request ID propagation and structured request logging to the given logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithRequestID enables request ID propagation, for services that don't log requests.
+func WithRequestID() Option {
+	return func(c *Config) {
+		c.RequestIDEnabled = true
+	}
+}
+
+// WithTelemetry enables OpenTelemetry tracing and RED metrics using the given providers.
+func WithTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) Option {
+	return func(c *Config) {
+		c.TracerProvider = tp
+		c.MeterProvider = mp
+	}
+}
+
+// WithRecovery enables panic recovery. hook, if not nil, is called for every recovered
+// panic in addition to logging it.
+func WithRecovery(hook PanicHook) Option {
+	return func(c *Config) {
+		c.RecoveryEnabled = true
+		c.PanicHook = hook
+	}
+}
+
+// WithAuthorization authenticates callers with authenticate and enforces the access each
+// method declares with the (go_chat.auth) option. Unannotated methods are denied.
+func WithAuthorization(authenticate Authenticator) Option {
+	return func(c *Config) {
+		c.Authenticator = authenticate
+	}
+}
+
+// WithAccessOverrides sets the access of methods that aren't annotated in their protos.
+func WithAccessOverrides(overrides map[string]gochat.Access) Option {
+	return func(c *Config) {
+		c.AccessOverrides = overrides
+	}
+}
+
+// WithDeadlines enforces the per-method deadline policies declared in cfg.
+func WithDeadlines(cfg DeadlineConfig) Option {
+	return func(c *Config) {
+		c.Deadlines = &cfg
+	}
+}
+
+// WithRateLimit enforces the per-method rate and concurrency limits declared in cfg.
+func WithRateLimit(cfg RateLimitConfig) Option {
+	return func(c *Config) {
+		c.RateLimit = &cfg
+	}
+}
+
+// WithErrorRegistry maps handler errors to statuses with registry, innermost in the chain.
+func WithErrorRegistry(registry *ErrorRegistry) Option {
+	return func(c *Config) {
+		c.ErrorRegistry = registry
+	}
+}
+
+// WithInterceptor inserts custom interceptors at stage, after its built-in interceptor
+// and any custom interceptors added before. Either interceptor may be nil.
+func WithInterceptor(stage Stage, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) Option {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors, StageInterceptor{Stage: stage, Unary: unary, Stream: stream})
+	}
+}
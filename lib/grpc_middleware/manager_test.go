@@ -1,7 +1,19 @@
 package grpc_middleware
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
 	"testing"
+
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewManager_DefaultConfig(t *testing.T) {
@@ -119,3 +131,152 @@ func TestManager_MultipleOptions(t *testing.T) {
 		t.Error("Expected validation to be enabled (last option should win)")
 	}
 }
+
+func TestManager_UnaryInterceptors_WithLogger(t *testing.T) {
+	mgr, err := NewManager(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.UnaryInterceptors()
+	if err != nil {
+		t.Fatalf("UnaryInterceptors() failed: %v", err)
+	}
+
+	// Request ID, logging and validation
+	if len(interceptors) != 3 {
+		t.Errorf("Expected 3 interceptors, got %d", len(interceptors))
+	}
+}
+
+func TestManager_StreamInterceptors_WithLogger(t *testing.T) {
+	mgr, err := NewManager(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.StreamInterceptors()
+	if err != nil {
+		t.Fatalf("StreamInterceptors() failed: %v", err)
+	}
+
+	// Request ID, logging and validation
+	if len(interceptors) != 3 {
+		t.Errorf("Expected 3 interceptors, got %d", len(interceptors))
+	}
+}
+
+func TestManager_UnaryInterceptors_WithTelemetry(t *testing.T) {
+	mgr, err := NewManager(WithTelemetry(tracenoop.NewTracerProvider(), metricnoop.NewMeterProvider()))
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.UnaryInterceptors()
+	if err != nil {
+		t.Fatalf("UnaryInterceptors() failed: %v", err)
+	}
+
+	// Telemetry and validation
+	if len(interceptors) != 2 {
+		t.Errorf("Expected 2 interceptors, got %d", len(interceptors))
+	}
+}
+
+// recordingInterceptor appends name to calls when the interceptor runs
+func recordingInterceptor(calls *[]string, name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	}
+}
+
+// runUnary runs interceptors around handler, outermost first, as grpc.ChainUnaryInterceptor does
+func runUnary(interceptors []grpc.UnaryServerInterceptor, handler grpc.UnaryHandler) (interface{}, error) {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test.v1.TestService/Do"}, next)
+		}
+	}
+	return handler(context.Background(), nil)
+}
+
+func TestManager_UnaryInterceptors_CustomStagesInOrder(t *testing.T) {
+	var calls []string
+	mgr, err := NewManager(
+		WithValidation(false),
+		WithInterceptor(StageErrors, recordingInterceptor(&calls, "errors"), nil),
+		WithInterceptor(StageAuth, recordingInterceptor(&calls, "auth"), nil),
+		WithInterceptor(StageRateLimit, recordingInterceptor(&calls, "rate limit"), nil),
+		WithInterceptor(StageAuth, recordingInterceptor(&calls, "authz"), nil),
+		WithInterceptor(StageRecovery, recordingInterceptor(&calls, "outermost"), nil),
+	)
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.UnaryInterceptors()
+	if err != nil {
+		t.Fatalf("UnaryInterceptors() failed: %v", err)
+	}
+	if _, err := runUnary(interceptors, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"outermost", "auth", "authz", "rate limit", "errors"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected order %v, got %v", expected, calls)
+	}
+
+	streams, err := mgr.StreamInterceptors()
+	if err != nil {
+		t.Fatalf("StreamInterceptors() failed: %v", err)
+	}
+	if len(streams) != 0 {
+		t.Errorf("Expected nil stream interceptors to be skipped, got %d", len(streams))
+	}
+}
+
+func TestManager_ErrorRegistry_MapsBeforeLogging(t *testing.T) {
+	var logs bytes.Buffer
+	errNotFound := errors.New("not found")
+	mgr, err := NewManager(
+		WithValidation(false),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+		WithErrorRegistry(NewErrorRegistry("test", slog.New(slog.DiscardHandler)).
+			Register(errNotFound, ErrorMapping{Code: codes.NotFound, Message: "thing not found"})),
+	)
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.UnaryInterceptors()
+	if err != nil {
+		t.Fatalf("UnaryInterceptors() failed: %v", err)
+	}
+	_, err = runUnary(interceptors, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, errNotFound })
+
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+	if !strings.Contains(logs.String(), `"code":"NotFound"`) {
+		t.Errorf("Expected logging to see the mapped code, got %s", logs.String())
+	}
+}
+
+func TestManager_ServerOptions(t *testing.T) {
+	mgr, err := NewManager(WithRecovery(nil), WithRequestID())
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	opts, err := mgr.ServerOptions()
+	if err != nil {
+		t.Fatalf("ServerOptions() failed: %v", err)
+	}
+	if len(opts) != 3 {
+		t.Errorf("Expected unary and stream chain and keepalive options, got %d", len(opts))
+	}
+	grpc.NewServer(opts...).Stop()
+}
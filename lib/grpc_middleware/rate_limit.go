@@ -0,0 +1,429 @@
+package grpc_middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// maxBucketsPerMethod bounds the number of callers tracked per method. When it is reached,
+// buckets that have refilled completely are dropped, since they hold no state.
+const maxBucketsPerMethod = 10000
+
+// defaultConcurrencyRetryDelay is suggested to callers rejected by a concurrency limit
+// without a latency target.
+const defaultConcurrencyRetryDelay = 100 * time.Millisecond
+
+// MethodLimit declares the limits applied to one method. Zero values disable each limit.
+type MethodLimit struct {
+	// Rate is the sustained number of requests per second allowed for each caller.
+	Rate float64
+
+	// Burst is the number of requests a caller may make at once after being idle.
+	// It defaults to Rate rounded up.
+	Burst int
+
+	// MaxConcurrent caps the requests in flight across all callers.
+	MaxConcurrent int
+
+	// LatencyTarget makes the concurrency limit adaptive: it shrinks multiplicatively
+	// when a request takes longer than the target or fails with DeadlineExceeded or
+	// Unavailable, and grows back additively towards MaxConcurrent on fast requests.
+	LatencyTarget time.Duration
+
+	// MinConcurrent is the floor of an adaptive concurrency limit. It defaults to 1.
+	MinConcurrent int
+}
+
+// RateLimitConfig declares per-method limits, keyed by full method name,
+// e.g. "/api.users.v1.UserService/SearchByNickname".
+type RateLimitConfig struct {
+	// Default applies to methods without an entry in Methods.
+	Default MethodLimit
+
+	// Methods overrides Default for individual methods.
+	Methods map[string]MethodLimit
+
+	// Caller identifies the caller a request is rate limited as. It defaults to the
+	// authenticated user or service, falling back to the peer's IP address.
+	Caller func(ctx context.Context) string
+}
+
+// RateLimiter rejects requests over their method's limits with ResourceExhausted and a
+// google.rpc.RetryInfo telling the caller when to retry. Rejections are counted in the
+// rpc.server.rate_limited metric.
+type RateLimiter struct {
+	config   RateLimitConfig
+	rejected metric.Int64Counter
+	now      func() time.Time
+
+	mu      sync.Mutex
+	methods map[string]*methodLimiter
+}
+
+// NewRateLimiter creates a rate limiter from cfg. A nil meter provider disables the metric.
+func NewRateLimiter(cfg RateLimitConfig, mp metric.MeterProvider) (*RateLimiter, error) {
+	if err := cfg.Default.validate(); err != nil {
+		return nil, fmt.Errorf("invalid default limit: %w", err)
+	}
+	for method, limit := range cfg.Methods {
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("invalid limit for %s: %w", method, err)
+		}
+	}
+	if cfg.Caller == nil {
+		cfg.Caller = callerFromContext
+	}
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+
+	rejected, err := mp.Meter(instrumentationName).Int64Counter("rpc.server.rate_limited",
+		metric.WithDescription("Number of RPCs rejected by rate or concurrency limits, by method and limit"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limited counter: %w", err)
+	}
+
+	return &RateLimiter{
+		config:   cfg,
+		rejected: rejected,
+		now:      time.Now,
+		methods:  make(map[string]*methodLimiter),
+	}, nil
+}
+
+// UnaryServerInterceptor returns a unary server interceptor enforcing the configured limits.
+func (l *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		release, err := l.admit(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		release(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor enforcing the configured limits
+// when a stream is opened. A stream holds its concurrency slot until it ends.
+func (l *RateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		release, err := l.admit(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		err = handler(srv, ss)
+		release(err)
+		return err
+	}
+}
+
+// admit checks the rate and concurrency limits of fullMethod. On success it returns a
+// function that must be called with the handler's error once the request completes.
+func (l *RateLimiter) admit(ctx context.Context, fullMethod string) (func(error), error) {
+	m := l.method(fullMethod)
+	if m == nil {
+		return func(error) {}, nil
+	}
+
+	now := l.now()
+	if m.limit.Rate > 0 {
+		if wait, ok := m.take(l.config.Caller(ctx), now); !ok {
+			return nil, l.reject(ctx, fullMethod, "rate", "rate limit exceeded", wait)
+		}
+	}
+
+	if m.concurrency == nil {
+		return func(error) {}, nil
+	}
+	if !m.concurrency.acquire() {
+		return nil, l.reject(ctx, fullMethod, "concurrency", "too many concurrent requests", m.concurrency.retryDelay())
+	}
+	return func(err error) {
+		m.concurrency.release(l.now().Sub(now), status.Code(err))
+	}, nil
+}
+
+func (l *RateLimiter) reject(ctx context.Context, fullMethod, limit, msg string, retryAfter time.Duration) error {
+	l.rejected.Add(ctx, 1, metric.WithAttributes(append(methodAttributes(fullMethod), attribute.String("limit", limit))...))
+
+	st := status.New(codes.ResourceExhausted, msg)
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// method returns the limiter for fullMethod, or nil if the method has no limits.
+func (l *RateLimiter) method(fullMethod string) *methodLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if m, ok := l.methods[fullMethod]; ok {
+		return m
+	}
+
+	limit, ok := l.config.Methods[fullMethod]
+	if !ok {
+		limit = l.config.Default
+	}
+	var m *methodLimiter
+	if limit.Rate > 0 || limit.MaxConcurrent > 0 {
+		m = newMethodLimiter(limit)
+	}
+	l.methods[fullMethod] = m
+	return m
+}
+
+func (m MethodLimit) validate() error {
+	switch {
+	case m.Rate < 0 || m.Burst < 0 || m.MaxConcurrent < 0 || m.MinConcurrent < 0 || m.LatencyTarget < 0:
+		return fmt.Errorf("limits must not be negative")
+	case m.MaxConcurrent > 0 && m.MinConcurrent > m.MaxConcurrent:
+		return fmt.Errorf("min concurrent %d exceeds max concurrent %d", m.MinConcurrent, m.MaxConcurrent)
+	}
+	return nil
+}
+
+// rateLimitsJSON is the JSON form of a RateLimitConfig.
+type rateLimitsJSON struct {
+	Default methodLimitJSON            `json:"default"`
+	Methods map[string]methodLimitJSON `json:"methods"`
+}
+
+// methodLimitJSON is the JSON form of a MethodLimit, with the latency target as a duration string.
+type methodLimitJSON struct {
+	Rate          float64 `json:"rate"`
+	Burst         int     `json:"burst"`
+	MaxConcurrent int     `json:"max_concurrent"`
+	MinConcurrent int     `json:"min_concurrent"`
+	LatencyTarget string  `json:"latency_target"`
+}
+
+func (j methodLimitJSON) limit() (MethodLimit, error) {
+	limit := MethodLimit{
+		Rate:          j.Rate,
+		Burst:         j.Burst,
+		MaxConcurrent: j.MaxConcurrent,
+		MinConcurrent: j.MinConcurrent,
+	}
+	if j.LatencyTarget != "" {
+		target, err := time.ParseDuration(j.LatencyTarget)
+		if err != nil {
+			return MethodLimit{}, fmt.Errorf("invalid latency target: %w", err)
+		}
+		limit.LatencyTarget = target
+	}
+	return limit, limit.validate()
+}
+
+// ParseRateLimits parses limits declared as JSON, for example
+//
+//	{"methods": {"/api.users.v1.UserService/SearchByNickname": {"rate": 5, "burst": 10, "max_concurrent": 32, "latency_target": "200ms"}}}
+//
+// A "default" object applies to methods not listed. An empty string declares no limits.
+func ParseRateLimits(s string) (RateLimitConfig, error) {
+	if s == "" {
+		return RateLimitConfig{}, nil
+	}
+
+	var raw rateLimitsJSON
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid rate limits: %w", err)
+	}
+
+	var cfg RateLimitConfig
+	var err error
+	if cfg.Default, err = raw.Default.limit(); err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid default limit: %w", err)
+	}
+	cfg.Methods = make(map[string]MethodLimit, len(raw.Methods))
+	for method, j := range raw.Methods {
+		if cfg.Methods[method], err = j.limit(); err != nil {
+			return RateLimitConfig{}, fmt.Errorf("invalid limit for %s: %w", method, err)
+		}
+	}
+	return cfg, nil
+}
+
+// RateLimitsFromEnv parses the limits from RATE_LIMITS, so each deployment tunes them
+// without a rebuild. It is unset in development, where no limits apply.
+func RateLimitsFromEnv() (RateLimitConfig, error) {
+	return ParseRateLimits(os.Getenv("RATE_LIMITS"))
+}
+
+// methodLimiter holds the token buckets of each caller and the shared concurrency limit of one method.
+type methodLimiter struct {
+	limit MethodLimit
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+
+	concurrency *concurrencyLimiter
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newMethodLimiter(limit MethodLimit) *methodLimiter {
+	m := &methodLimiter{limit: limit, buckets: make(map[string]*tokenBucket)}
+
+	m.burst = float64(limit.Burst)
+	if limit.Burst == 0 {
+		m.burst = math.Ceil(limit.Rate)
+	}
+
+	if limit.MaxConcurrent > 0 {
+		m.concurrency = newConcurrencyLimiter(limit)
+	}
+	return m
+}
+
+// take removes a token from caller's bucket. When the bucket is empty it returns false
+// and how long until a token is available.
+func (m *methodLimiter) take(caller string, now time.Time) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[caller]
+	if !ok {
+		if len(m.buckets) >= maxBucketsPerMethod {
+			m.evictFull(now)
+		}
+		b = &tokenBucket{tokens: m.burst, last: now}
+		m.buckets[caller] = b
+	}
+
+	b.tokens = math.Min(m.burst, b.tokens+now.Sub(b.last).Seconds()*m.limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / m.limit.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (m *methodLimiter) evictFull(now time.Time) {
+	for caller, b := range m.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*m.limit.Rate >= m.burst {
+			delete(m.buckets, caller)
+		}
+	}
+}
+
+// concurrencyLimiter is an AIMD concurrency limit. Without a latency target it is a fixed
+// limit of MaxConcurrent.
+type concurrencyLimiter struct {
+	min, max float64
+	target   time.Duration
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+}
+
+func newConcurrencyLimiter(limit MethodLimit) *concurrencyLimiter {
+	minimum := limit.MinConcurrent
+	if minimum == 0 {
+		minimum = 1
+	}
+	return &concurrencyLimiter{
+		min:    float64(minimum),
+		max:    float64(limit.MaxConcurrent),
+		target: limit.LatencyTarget,
+		limit:  float64(limit.MaxConcurrent),
+	}
+}
+
+func (c *concurrencyLimiter) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight >= int(c.limit) {
+		return false
+	}
+	c.inFlight++
+	return true
+}
+
+func (c *concurrencyLimiter) release(latency time.Duration, code codes.Code) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	if c.target == 0 {
+		return
+	}
+
+	if latency > c.target || code == codes.DeadlineExceeded || code == codes.Unavailable {
+		c.limit = math.Max(c.min, c.limit*0.9)
+	} else {
+		c.limit = math.Min(c.max, c.limit+1/c.limit)
+	}
+}
+
+// retryDelay suggests when a rejected caller should retry: one latency target, by which
+// time a slot has most likely been released.
+func (c *concurrencyLimiter) retryDelay() time.Duration {
+	if c.target == 0 {
+		return defaultConcurrencyRetryDelay
+	}
+	return c.target
+}
+
+// callerFromContext identifies the caller by the identity established by the authorization
+// middleware, so requests the gateway relays are limited per end user rather than sharing
+// the gateway's bucket. An x-user-id without that identity is ignored since any client can
+// send one; such callers are identified by the peer's IP address.
+func callerFromContext(ctx context.Context) string {
+	if caller, ok := CallerFromContext(ctx); ok {
+		switch {
+		case caller.UserID != "":
+			return "user:" + caller.UserID
+		case caller.Service != "":
+			return "service:" + caller.Service
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "peer:" + host
+	}
+	return ""
+}
@@ -0,0 +1,319 @@
+package grpc_middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	gochat "github.com/go-chat/lib/pkg/go_chat"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const searchMethod = "/api.users.v1.UserService/SearchByNickname"
+
+func okHandler(ctx context.Context, req interface{}) (interface{}, error) {
+	return "ok", nil
+}
+
+func userContext(userID string) context.Context {
+	return ContextWithCaller(context.Background(), Caller{Kind: CallerUser, UserID: userID})
+}
+
+func TestRateLimiter_Rate_RejectsCallerOverBurst(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	limiter, err := NewRateLimiter(RateLimitConfig{
+		Methods: map[string]MethodLimit{searchMethod: {Rate: 2, Burst: 2}},
+	}, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: searchMethod}
+
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(userContext("u1"), nil, info, okHandler); err != nil {
+			t.Fatalf("Expected request %d within burst to pass, got %v", i, err)
+		}
+	}
+
+	_, err = interceptor(userContext("u1"), nil, info, okHandler)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("Expected RetryInfo detail, got %v", details)
+	}
+	retry, ok := details[0].(*errdetails.RetryInfo)
+	if !ok || retry.GetRetryDelay().AsDuration() != 500*time.Millisecond {
+		t.Errorf("Expected retry delay of 500ms, got %v", details[0])
+	}
+
+	// Other callers have their own bucket
+	if _, err := interceptor(userContext("u2"), nil, info, okHandler); err != nil {
+		t.Errorf("Expected another caller to pass, got %v", err)
+	}
+
+	// The bucket refills over time
+	now = now.Add(500 * time.Millisecond)
+	if _, err := interceptor(userContext("u1"), nil, info, okHandler); err != nil {
+		t.Errorf("Expected request after refill to pass, got %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+	if got := counterValue(rm, "rpc.server.rate_limited"); got != 1 {
+		t.Errorf("Expected 1 rejection recorded, got %d", got)
+	}
+}
+
+func TestRateLimiter_UnlimitedMethod_PassesThrough(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{
+		Methods: map[string]MethodLimit{searchMethod: {Rate: 1}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.users.v1.UserService/GetProfileByID"}
+
+	for i := 0; i < 10; i++ {
+		if _, err := limiter.UnaryServerInterceptor()(userContext("u1"), nil, info, okHandler); err != nil {
+			t.Fatalf("Expected unlimited method to pass, got %v", err)
+		}
+	}
+}
+
+func TestRateLimiter_Default_AppliesPerMethod(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Default: MethodLimit{Rate: 1}}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	interceptor := limiter.UnaryServerInterceptor()
+
+	for _, method := range []string{"/test.v1.TestService/A", "/test.v1.TestService/B"} {
+		if _, err := interceptor(userContext("u1"), nil, &grpc.UnaryServerInfo{FullMethod: method}, okHandler); err != nil {
+			t.Errorf("Expected first call to %s to pass, got %v", method, err)
+		}
+	}
+	if _, err := interceptor(userContext("u1"), nil, &grpc.UnaryServerInfo{FullMethod: "/test.v1.TestService/A"}, okHandler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestRateLimiter_Concurrency_RejectsWhenFull(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{
+		Methods: map[string]MethodLimit{searchMethod: {MaxConcurrent: 1}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: searchMethod}
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			<-unblock
+			return nil, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	_, err = interceptor(context.Background(), nil, info, okHandler)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted while the slot is taken, got %v", err)
+	}
+	if len(st.Details()) != 1 {
+		t.Errorf("Expected RetryInfo detail, got %v", st.Details())
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := interceptor(context.Background(), nil, info, okHandler); err != nil {
+		t.Errorf("Expected request to pass once the slot is released, got %v", err)
+	}
+}
+
+func TestConcurrencyLimiter_Adaptive(t *testing.T) {
+	c := newConcurrencyLimiter(MethodLimit{MaxConcurrent: 10, MinConcurrent: 2, LatencyTarget: 100 * time.Millisecond})
+
+	for i := 0; i < 50; i++ {
+		c.acquire()
+		c.release(time.Second, codes.OK)
+	}
+	if c.limit != 2 {
+		t.Errorf("Expected limit to shrink to the minimum of 2, got %v", c.limit)
+	}
+
+	c.acquire()
+	c.release(time.Millisecond, codes.OK)
+	if c.limit <= 2 {
+		t.Errorf("Expected limit to grow after a fast request, got %v", c.limit)
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.acquire()
+		c.release(time.Millisecond, codes.OK)
+	}
+	if c.limit != 10 {
+		t.Errorf("Expected limit to recover to the maximum of 10, got %v", c.limit)
+	}
+
+	c.acquire()
+	c.release(time.Millisecond, codes.DeadlineExceeded)
+	if c.limit != 9 {
+		t.Errorf("Expected DeadlineExceeded to shrink the limit to 9, got %v", c.limit)
+	}
+}
+
+func TestRateLimiter_Stream_Rejects(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Default: MethodLimit{Rate: 1}}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	interceptor := limiter.StreamServerInterceptor()
+	stream := &contextServerStream{ServerStream: &mockServerStream{}, ctx: userContext("u1")}
+	handler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+	if err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: searchMethod}, handler); err != nil {
+		t.Fatalf("Expected first stream to open, got %v", err)
+	}
+	if err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: searchMethod}, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestNewRateLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit MethodLimit
+	}{
+		{"negative rate", MethodLimit{Rate: -1}},
+		{"min above max", MethodLimit{MaxConcurrent: 2, MinConcurrent: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRateLimiter(RateLimitConfig{Methods: map[string]MethodLimit{searchMethod: tt.limit}}, nil); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestCallerFromContext(t *testing.T) {
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"authenticated service", ContextWithCaller(userContext("u1"), Caller{Kind: CallerService, Service: "chat"}), "service:chat"},
+		{"user", userContext("u1"), "user:u1"},
+		{"unverified user ID", metadata.NewIncomingContext(peerCtx, metadata.Pairs(UserIDMetadataKey, "u1")), "peer:10.0.0.1"},
+		{"peer", peerCtx, "peer:10.0.0.1"},
+		{"unknown", context.Background(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callerFromContext(tt.ctx); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_RelayedUsers_LimitedSeparately(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{
+		Methods: map[string]MethodLimit{searchMethod: {Rate: 1, Burst: 1}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() failed: %v", err)
+	}
+	authz := NewAuthorizationMiddleware(ServiceTokenAuthenticator("secret"), map[string]gochat.Access{searchMethod: gochat.Access_ACCESS_USER})
+	chain := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		return authz.UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return limiter.UnaryServerInterceptor()(ctx, req, info, next)
+		})
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: searchMethod}
+
+	// Both users reach the service through the same gateway peer
+	gateway := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 5000}})
+	relayed := func(userID string) context.Context {
+		return metadata.NewIncomingContext(gateway, metadata.Pairs(
+			ServiceTokenMetadataKey, "secret",
+			ServiceNameMetadataKey, "gateway",
+			UserIDMetadataKey, userID,
+		))
+	}
+
+	if _, err := chain(relayed("u1"), nil, info, okHandler); err != nil {
+		t.Fatalf("Expected first call of u1 to pass, got %v", err)
+	}
+	if _, err := chain(relayed("u2"), nil, info, okHandler); err != nil {
+		t.Errorf("Expected u2 to have its own bucket, got %v", err)
+	}
+	if _, err := chain(relayed("u1"), nil, info, okHandler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected second call of u1 to be limited, got %v", err)
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	cfg, err := ParseRateLimits(`{
+		"default": {"rate": 50},
+		"methods": {"` + searchMethod + `": {"rate": 5, "burst": 10, "max_concurrent": 32, "min_concurrent": 4, "latency_target": "200ms"}}
+	}`)
+	if err != nil {
+		t.Fatalf("ParseRateLimits() failed: %v", err)
+	}
+
+	expected := MethodLimit{Rate: 5, Burst: 10, MaxConcurrent: 32, MinConcurrent: 4, LatencyTarget: 200 * time.Millisecond}
+	if got := cfg.Methods[searchMethod]; got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+	if cfg.Default.Rate != 50 {
+		t.Errorf("Expected default rate 50, got %v", cfg.Default.Rate)
+	}
+}
+
+func TestParseRateLimits_Invalid_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"malformed", `{"methods":`},
+		{"unknown field", `{"methods": {"` + searchMethod + `": {"rps": 5}}}`},
+		{"bad latency target", `{"methods": {"` + searchMethod + `": {"latency_target": "soon"}}}`},
+		{"negative rate", `{"default": {"rate": -1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRateLimits(tt.input); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
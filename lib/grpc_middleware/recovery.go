@@ -0,0 +1,110 @@
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PanicHook is called with every recovered handler panic and its stack trace,
+// e.g. to report it to an error tracker. It must not panic itself.
+type PanicHook func(ctx context.Context, fullMethod string, recovered interface{}, stack []byte)
+
+// RecoveryMiddleware turns handler panics into Internal errors instead of letting them
+// crash the process. Each panic is logged with its stack trace and counted in the
+// rpc.server.panics metric.
+type RecoveryMiddleware struct {
+	logger *slog.Logger
+	hook   PanicHook
+	panics metric.Int64Counter
+}
+
+// NewRecoveryMiddleware creates a recovery middleware. A nil logger logs to slog.Default(),
+// a nil meter provider disables the metric and hook may be nil.
+func NewRecoveryMiddleware(logger *slog.Logger, mp metric.MeterProvider, hook PanicHook) (*RecoveryMiddleware, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+
+	panics, err := mp.Meter(instrumentationName).Int64Counter("rpc.server.panics",
+		metric.WithDescription("Number of RPC handler panics recovered, by method"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create panics counter: %w", err)
+	}
+
+	return &RecoveryMiddleware{logger: logger, hook: hook, panics: panics}, nil
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that recovers from handler panics.
+func (r *RecoveryMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = r.recovered(ctx, info.FullMethod, recovered)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that recovers from handler panics.
+func (r *RecoveryMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = r.recovered(ss.Context(), info.FullMethod, recovered)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func (r *RecoveryMiddleware) recovered(ctx context.Context, fullMethod string, recovered interface{}) error {
+	stack := debug.Stack()
+
+	// Recovery runs outside the request ID middleware, so the ID is read from metadata
+	r.logger.LogAttrs(ctx, slog.LevelError, "panic in grpc handler",
+		slog.String("method", fullMethod),
+		slog.String("request_id", metadataRequestID(ctx)),
+		slog.String("panic", fmt.Sprint(recovered)),
+		slog.String("stack", string(stack)),
+	)
+	r.panics.Add(ctx, 1, metric.WithAttributes(methodAttributes(fullMethod)...))
+	if r.hook != nil {
+		r.hook(ctx, fullMethod, recovered, stack)
+	}
+
+	return status.Error(codes.Internal, internalMessage)
+}
+
+// metadataRequestID returns the request ID sent by the caller, or an empty string.
+func metadataRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package grpc_middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryMiddleware_UnaryPanic_ReturnsInternal(t *testing.T) {
+	var logs bytes.Buffer
+	reader := sdkmetric.NewManualReader()
+	var hooked interface{}
+	recovery, err := NewRecoveryMiddleware(
+		slog.New(slog.NewJSONHandler(&logs, nil)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		func(ctx context.Context, fullMethod string, recovered interface{}, stack []byte) {
+			hooked = recovered
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewRecoveryMiddleware() failed: %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-7"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.auth.v1.AuthService/Login"}
+	_, err = recovery.UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		var svc interface{ Login() }
+		svc.Login()
+		return nil, nil
+	})
+
+	st := status.Convert(err)
+	if st.Code() != codes.Internal || st.Message() != "internal server error" {
+		t.Errorf("Expected Internal 'internal server error', got %v '%s'", st.Code(), st.Message())
+	}
+	if hooked == nil {
+		t.Error("Expected hook to receive the panic value")
+	}
+	for _, want := range []string{"nil pointer dereference", "req-7", "recovery_test.go"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected log to contain %q, got %s", want, logs.String())
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+	if got := counterValue(rm, "rpc.server.panics"); got != 1 {
+		t.Errorf("Expected 1 recorded panic, got %d", got)
+	}
+}
+
+func TestRecoveryMiddleware_StreamPanic_ReturnsInternal(t *testing.T) {
+	recovery, err := NewRecoveryMiddleware(slog.New(slog.DiscardHandler), nil, nil)
+	if err != nil {
+		t.Fatalf("NewRecoveryMiddleware() failed: %v", err)
+	}
+	stream := &contextServerStream{ServerStream: &mockServerStream{}, ctx: context.Background()}
+
+	err = recovery.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("boom")
+	})
+
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal, got %v", err)
+	}
+}
+
+func TestRecoveryMiddleware_NoPanic_PassesThrough(t *testing.T) {
+	recovery, err := NewRecoveryMiddleware(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRecoveryMiddleware() failed: %v", err)
+	}
+
+	resp, err := recovery.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", status.Error(codes.NotFound, "missing")
+		})
+
+	if resp != "ok" || status.Code(err) != codes.NotFound {
+		t.Errorf("Expected handler result to pass through, got %v, %v", resp, err)
+	}
+}
+
+func TestManager_UnaryInterceptors_WithRecovery(t *testing.T) {
+	mgr, err := NewManager(WithRecovery(nil), WithValidation(false))
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	interceptors, err := mgr.UnaryInterceptors()
+	if err != nil {
+		t.Fatalf("UnaryInterceptors() failed: %v", err)
+	}
+	if len(interceptors) != 1 {
+		t.Fatalf("Expected 1 interceptor, got %d", len(interceptors))
+	}
+
+	_, err = interceptors[0](context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal, got %v", err)
+	}
+}
+
+// counterValue sums the data points of the named int64 counter, or returns -1 if it wasn't recorded
+func counterValue(rm metricdata.ResourceMetrics, name string) int64 {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				return -1
+			}
+			var total int64
+			for _, dp := range sum.DataPoints {
+				total += dp.Value
+			}
+			return total
+		}
+	}
+	return -1
+}
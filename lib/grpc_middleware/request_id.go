@@ -0,0 +1,129 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDMetadataKey is the gRPC metadata key carrying the correlation ID.
+// The gateway sets it from the X-Request-ID HTTP header.
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the given request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID generates a random 128-bit request ID encoded as hex.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestIDUnaryServerInterceptor returns a unary server interceptor that takes the request ID
+// from incoming metadata (or generates one), stores it in the context and echoes it in response headers.
+func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		id := incomingRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, id))
+		return handler(ContextWithRequestID(ctx, id), req)
+	}
+}
+
+// RequestIDStreamServerInterceptor returns a stream server interceptor that takes the request ID
+// from incoming metadata (or generates one), stores it in the stream context and echoes it in response headers.
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		id := incomingRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, id))
+		return handler(srv, &contextServerStream{
+			ServerStream: ss,
+			ctx:          ContextWithRequestID(ss.Context(), id),
+		})
+	}
+}
+
+// RequestIDUnaryClientInterceptor returns a unary client interceptor that forwards the request ID
+// from the context to the downstream service, so one request can be traced across services.
+func RequestIDUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoingWithRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// RequestIDStreamClientInterceptor returns a stream client interceptor that forwards the request ID
+// from the context to the downstream service.
+func RequestIDStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoingWithRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// incomingRequestID returns the request ID sent by the caller, generating a new one if absent.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return NewRequestID()
+}
+
+func outgoingWithRequestID(ctx context.Context) context.Context {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(RequestIDMetadataKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+}
+
+// contextServerStream overrides the context of a wrapped grpc.ServerStream.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the overridden stream context.
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,122 @@
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDUnaryServerInterceptor_PropagatesIncomingID(t *testing.T) {
+	interceptor := RequestIDUnaryServerInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-123"))
+
+	var gotID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotID = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if gotID != "req-123" {
+		t.Errorf("Expected request ID 'req-123', got '%s'", gotID)
+	}
+}
+
+func TestRequestIDUnaryServerInterceptor_GeneratesMissingID(t *testing.T) {
+	interceptor := RequestIDUnaryServerInterceptor()
+
+	var gotID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotID = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(gotID) != 32 {
+		t.Errorf("Expected generated 32-char request ID, got '%s'", gotID)
+	}
+}
+
+func TestRequestIDStreamServerInterceptor_PropagatesIncomingID(t *testing.T) {
+	interceptor := RequestIDStreamServerInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-456"))
+
+	var header metadata.MD
+	stream := &mockServerStream{}
+	wrapped := &headerCapturingStream{mockServerStream: stream, ctx: ctx, header: &header}
+
+	var gotID string
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		gotID = RequestIDFromContext(ss.Context())
+		return nil
+	}
+
+	if err := interceptor(nil, wrapped, &grpc.StreamServerInfo{}, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if gotID != "req-456" {
+		t.Errorf("Expected request ID 'req-456', got '%s'", gotID)
+	}
+	if got := header.Get(RequestIDMetadataKey); len(got) != 1 || got[0] != "req-456" {
+		t.Errorf("Expected request ID echoed in header, got %v", got)
+	}
+}
+
+func TestRequestIDUnaryClientInterceptor_ForwardsID(t *testing.T) {
+	interceptor := RequestIDUnaryClientInterceptor()
+	ctx := ContextWithRequestID(context.Background(), "req-789")
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	if err := interceptor(ctx, "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := outgoing.Get(RequestIDMetadataKey); len(got) != 1 || got[0] != "req-789" {
+		t.Errorf("Expected forwarded request ID, got %v", got)
+	}
+}
+
+func TestRequestIDUnaryClientInterceptor_NoID_LeavesMetadataUntouched(t *testing.T) {
+	interceptor := RequestIDUnaryClientInterceptor()
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if _, ok := metadata.FromOutgoingContext(ctx); ok {
+			t.Error("Expected no outgoing metadata without a request ID")
+		}
+		return nil
+	}
+
+	if err := interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+// headerCapturingStream records headers set on the stream and serves a fixed context
+type headerCapturingStream struct {
+	*mockServerStream
+	ctx    context.Context
+	header *metadata.MD
+}
+
+func (s *headerCapturingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *headerCapturingStream) SetHeader(md metadata.MD) error {
+	*s.header = metadata.Join(*s.header, md)
+	return nil
+}
@@ -0,0 +1,267 @@
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "github.com/go-chat/lib/grpc_middleware"
+
+// TelemetryMiddleware creates a server span per RPC, continuing the W3C trace context
+// found in incoming metadata, and records RED metrics (requests, errors, duration).
+type TelemetryMiddleware struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	requests   metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// NewTelemetryMiddleware creates a new telemetry middleware from the given providers.
+// Trace context is extracted with the globally installed propagator.
+func NewTelemetryMiddleware(tp trace.TracerProvider, mp metric.MeterProvider) (*TelemetryMiddleware, error) {
+	meter := mp.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("rpc.server.requests",
+		metric.WithDescription("Number of RPCs handled, by method and status code"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requests counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("rpc.server.duration",
+		metric.WithDescription("Duration of RPCs, by method and status code"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &TelemetryMiddleware{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: otel.GetTextMapPropagator(),
+		requests:   requests,
+		duration:   duration,
+	}, nil
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that traces and measures each call.
+func (t *TelemetryMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, span, start := t.start(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		t.finish(ctx, span, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that traces and measures each stream.
+func (t *TelemetryMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, span, start := t.start(ss.Context(), info.FullMethod)
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+		t.finish(ctx, span, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (t *TelemetryMiddleware) start(ctx context.Context, fullMethod string) (context.Context, trace.Span, time.Time) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = t.propagator.Extract(ctx, MetadataCarrier(md))
+
+	ctx, span := t.tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(methodAttributes(fullMethod)...),
+	)
+	return ctx, span, time.Now()
+}
+
+func (t *TelemetryMiddleware) finish(ctx context.Context, span trace.Span, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if isServerError(code) {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+
+	attrs := metric.WithAttributes(append(methodAttributes(fullMethod),
+		attribute.String("rpc.grpc.status_code", code.String()),
+	)...)
+	t.requests.Add(ctx, 1, attrs)
+	t.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// ClientTelemetry creates a client span per outgoing RPC, injecting its W3C trace context
+// into outgoing metadata, and records RED metrics for the calls.
+type ClientTelemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	requests   metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// NewClientTelemetry creates client-side telemetry from the given providers.
+// Trace context is injected with the globally installed propagator.
+func NewClientTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*ClientTelemetry, error) {
+	meter := mp.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("rpc.client.requests",
+		metric.WithDescription("Number of RPCs sent, by method and status code"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requests counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("rpc.client.duration",
+		metric.WithDescription("Duration of outgoing RPCs, by method and status code"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &ClientTelemetry{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: otel.GetTextMapPropagator(),
+		requests:   requests,
+		duration:   duration,
+	}, nil
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that traces and measures each call.
+func (t *ClientTelemetry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, span, start := t.start(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		t.finish(ctx, span, method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that traces each stream until it
+// is opened. Only opening the stream is measured, since its lifetime is up to the caller.
+func (t *ClientTelemetry) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, span, start := t.start(ctx, method)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		t.finish(ctx, span, method, start, err)
+		return stream, err
+	}
+}
+
+func (t *ClientTelemetry) start(ctx context.Context, fullMethod string) (context.Context, trace.Span, time.Time) {
+	ctx, span := t.tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(methodAttributes(fullMethod)...),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	t.propagator.Inject(ctx, MetadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span, time.Now()
+}
+
+func (t *ClientTelemetry) finish(ctx context.Context, span trace.Span, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+
+	attrs := metric.WithAttributes(append(methodAttributes(fullMethod),
+		attribute.String("rpc.grpc.status_code", code.String()),
+	)...)
+	t.requests.Add(ctx, 1, attrs)
+	t.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// methodAttributes splits "/package.Service/Method" into OpenTelemetry RPC attributes.
+func methodAttributes(fullMethod string) []attribute.KeyValue {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	}
+}
+
+// isServerError reports whether a status code indicates a fault on the server side
+// rather than a problem with the client's request.
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetadataCarrier adapts gRPC metadata to propagation.TextMapCarrier,
+// so trace context can be injected into and extracted from metadata.
+type MetadataCarrier metadata.MD
+
+// Get returns the first value for the key.
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set replaces the values for the key.
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys lists the metadata keys.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,239 @@
+package grpc_middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestTelemetry returns a telemetry middleware backed by an in-memory span exporter and a manual metric reader
+func newTestTelemetry(t *testing.T) (*TelemetryMiddleware, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	mw, err := NewTelemetryMiddleware(tp, mp)
+	if err != nil {
+		t.Fatalf("NewTelemetryMiddleware() failed: %v", err)
+	}
+	return mw, exporter, reader
+}
+
+func TestTelemetryMiddleware_UnaryServerInterceptor_ContinuesIncomingTrace(t *testing.T) {
+	mw, exporter, _ := newTestTelemetry(t)
+	interceptor := mw.UnaryServerInterceptor()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.chat.v1.ChatService/SendMessage"}
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name != "api.chat.v1.ChatService/SendMessage" {
+		t.Errorf("Unexpected span name: %s", span.Name)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected span to continue incoming trace, got trace ID %s", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected parent span ID from traceparent, got %s", got)
+	}
+	if span.Status.Code == otelcodes.Error {
+		t.Error("Expected successful span not to be marked as error")
+	}
+}
+
+func TestTelemetryMiddleware_UnaryServerInterceptor_MarksServerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantError bool
+	}{
+		{"internal", status.Error(codes.Internal, "boom"), true},
+		{"not found", status.Error(codes.NotFound, "chat not found"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, exporter, _ := newTestTelemetry(t)
+			interceptor := mw.UnaryServerInterceptor()
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, tt.err
+			}
+
+			_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			if got := spans[0].Status.Code == otelcodes.Error; got != tt.wantError {
+				t.Errorf("Expected error status %v, got %v", tt.wantError, got)
+			}
+		})
+	}
+}
+
+func TestTelemetryMiddleware_UnaryServerInterceptor_RecordsMetrics(t *testing.T) {
+	mw, _, reader := newTestTelemetry(t)
+	interceptor := mw.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.chat.v1.ChatService/GetChat"}
+	_, _ = interceptor(context.Background(), nil, info, handler)
+	_, _ = interceptor(context.Background(), nil, info, handler)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+
+	var found bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "rpc.server.requests" {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || len(sum.DataPoints) != 1 {
+				t.Fatalf("Unexpected requests data: %#v", m.Data)
+			}
+			dp := sum.DataPoints[0]
+			if dp.Value != 2 {
+				t.Errorf("Expected 2 requests, got %d", dp.Value)
+			}
+			if v, _ := dp.Attributes.Value(attribute.Key("rpc.grpc.status_code")); v.AsString() != "NotFound" {
+				t.Errorf("Expected status code attribute NotFound, got %s", v.AsString())
+			}
+			if v, _ := dp.Attributes.Value(attribute.Key("rpc.method")); v.AsString() != "GetChat" {
+				t.Errorf("Expected method attribute GetChat, got %s", v.AsString())
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected rpc.server.requests metric to be recorded")
+	}
+}
+
+func TestTelemetryMiddleware_StreamServerInterceptor_CreatesSpan(t *testing.T) {
+	mw, exporter, _ := newTestTelemetry(t)
+	interceptor := mw.StreamServerInterceptor()
+
+	stream := &headerCapturingStream{
+		mockServerStream: &mockServerStream{},
+		ctx:              context.Background(),
+		header:           &metadata.MD{},
+	}
+
+	var handlerSpanValid bool
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		handlerSpanValid = traceSpanValid(ss.Context())
+		return nil
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/api.chat.v1.ChatService/StreamMessages"}
+	if err := interceptor(nil, stream, info, handler); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !handlerSpanValid {
+		t.Error("Expected handler stream context to carry the server span")
+	}
+	if len(exporter.GetSpans()) != 1 {
+		t.Errorf("Expected 1 span, got %d", len(exporter.GetSpans()))
+	}
+}
+
+func TestClientTelemetry_UnaryClientInterceptor_InjectsTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	tel, err := NewClientTelemetry(
+		sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	)
+	if err != nil {
+		t.Fatalf("NewClientTelemetry() failed: %v", err)
+	}
+
+	var sent metadata.MD
+	err = tel.UnaryClientInterceptor()(context.Background(), "/api.social.v1.SocialService/CheckRelationship", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			sent, _ = metadata.FromOutgoingContext(ctx)
+			return status.Error(codes.NotFound, "no relationship")
+		})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the call's error to be returned, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].SpanKind != trace.SpanKindClient {
+		t.Fatalf("Expected 1 client span, got %v", spans)
+	}
+	traceparent := sent.Get("traceparent")
+	if len(traceparent) != 1 || !strings.Contains(traceparent[0], spans[0].SpanContext.SpanID().String()) {
+		t.Errorf("Expected traceparent naming the client span, got %v", traceparent)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+	if got := counterValue(rm, "rpc.client.requests"); got != 1 {
+		t.Errorf("Expected 1 client request recorded, got %d", got)
+	}
+}
+
+func traceSpanValid(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	md := metadata.MD{}
+	carrier := MetadataCarrier(md)
+
+	carrier.Set("Traceparent", "value")
+
+	if got := carrier.Get("traceparent"); got != "value" {
+		t.Errorf("Expected case-insensitive get, got '%s'", got)
+	}
+	if keys := carrier.Keys(); len(keys) != 1 || keys[0] != "traceparent" {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+}
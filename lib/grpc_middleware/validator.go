@@ -3,28 +3,115 @@ package grpc_middleware
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 
 	"buf.build/go/protovalidate"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
+// ResponseAction is a set of actions taken when an outgoing response violates its
+// validation rules. Actions combine with |; the zero value disables response validation.
+type ResponseAction uint8
+
+const (
+	// ResponseActionLog logs the violations with the method and request ID.
+	ResponseActionLog ResponseAction = 1 << iota
+	// ResponseActionMetric counts the response in the rpc.server.invalid_responses metric.
+	ResponseActionMetric
+	// ResponseActionFail replaces the response with an Internal error.
+	ResponseActionFail
+)
+
+// responseActionNames maps the names accepted by ParseResponseActions to actions.
+var responseActionNames = map[string]ResponseAction{
+	"log":    ResponseActionLog,
+	"metric": ResponseActionMetric,
+	"fail":   ResponseActionFail,
+}
+
+// ParseResponseActions parses a comma-separated list of actions such as "log,metric".
+// An empty string disables response validation.
+func ParseResponseActions(s string) (ResponseAction, error) {
+	var actions ResponseAction
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		action, ok := responseActionNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown response validation action %q", name)
+		}
+		actions |= action
+	}
+	return actions, nil
+}
+
+// ResponseActionsFromEnv parses the response validation actions from RESPONSE_VALIDATION.
+// It is unset in production; staging sets it to catch services returning invalid responses.
+func ResponseActionsFromEnv() (ResponseAction, error) {
+	return ParseResponseActions(os.Getenv("RESPONSE_VALIDATION"))
+}
+
 // ValidationMiddleware validates incoming gRPC requests against proto validation rules.
 // It uses protovalidate-go to enforce constraints defined in proto files using buf.validate annotations.
+// It can also validate outgoing responses, see ValidateResponses.
 type ValidationMiddleware struct {
 	validator protovalidate.Validator
+	responses *responseValidator
+}
+
+// ValidationOption is a functional option for configuring the ValidationMiddleware.
+type ValidationOption func(*ValidationMiddleware)
+
+// ValidateResponses validates unary responses and messages sent on server streams, taking
+// actions on the ones that violate their rules. Violations are logged to logger, or to
+// slog.Default() when logger is nil, and counted with mp when it is not nil.
+func ValidateResponses(actions ResponseAction, logger *slog.Logger, mp metric.MeterProvider) ValidationOption {
+	return func(v *ValidationMiddleware) {
+		if actions == 0 {
+			return
+		}
+		if logger == nil {
+			logger = slog.Default()
+		}
+		if mp == nil {
+			mp = noop.NewMeterProvider()
+		}
+		v.responses = &responseValidator{actions: actions, logger: logger, meterProvider: mp}
+	}
 }
 
 // NewValidationMiddleware creates a new validation middleware instance.
 // It initializes the protovalidate validator that will be reused for all requests.
-func NewValidationMiddleware() (*ValidationMiddleware, error) {
+func NewValidationMiddleware(opts ...ValidationOption) (*ValidationMiddleware, error) {
 	validator, err := protovalidate.New()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize protovalidate: %w", err)
 	}
-	return &ValidationMiddleware{validator: validator}, nil
+
+	v := &ValidationMiddleware{validator: validator}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if v.responses != nil {
+		v.responses.validator = validator
+		v.responses.invalid, err = v.responses.meterProvider.Meter(instrumentationName).Int64Counter("rpc.server.invalid_responses",
+			metric.WithDescription("Number of responses violating their validation rules, by method"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invalid responses counter: %w", err)
+		}
+	}
+	return v, nil
 }
 
 // UnaryServerInterceptor returns a unary server interceptor for request validation.
@@ -49,7 +136,15 @@ func (v *ValidationMiddleware) UnaryServerInterceptor() grpc.UnaryServerIntercep
 		}
 
 		// Validation passed, call actual handler
-		return handler(ctx, req)
+		resp, err := handler(ctx, req)
+		if err != nil || v.responses == nil {
+			return resp, err
+		}
+
+		if err := v.responses.check(ctx, info.FullMethod, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
 	}
 }
 
@@ -66,15 +161,20 @@ func (v *ValidationMiddleware) StreamServerInterceptor() grpc.StreamServerInterc
 		wrapped := &validatingServerStream{
 			ServerStream: ss,
 			validator:    v.validator,
+			responses:    v.responses,
+			fullMethod:   info.FullMethod,
 		}
 		return handler(srv, wrapped)
 	}
 }
 
-// validatingServerStream wraps grpc.ServerStream to validate received messages.
+// validatingServerStream wraps grpc.ServerStream to validate received messages,
+// and sent messages when response validation is enabled.
 type validatingServerStream struct {
 	grpc.ServerStream
-	validator protovalidate.Validator
+	validator  protovalidate.Validator
+	responses  *responseValidator
+	fullMethod string
 }
 
 // RecvMsg validates messages received from the client.
@@ -98,3 +198,51 @@ func (s *validatingServerStream) RecvMsg(m interface{}) error {
 
 	return nil
 }
+
+// SendMsg validates messages sent to the client when response validation is enabled.
+func (s *validatingServerStream) SendMsg(m interface{}) error {
+	if s.responses != nil {
+		if err := s.responses.check(s.Context(), s.fullMethod, m); err != nil {
+			return err
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// responseValidator takes the configured actions on responses violating their rules.
+type responseValidator struct {
+	actions       ResponseAction
+	validator     protovalidate.Validator
+	logger        *slog.Logger
+	meterProvider metric.MeterProvider
+	invalid       metric.Int64Counter
+}
+
+// check validates resp. It returns an Internal error only when the response is invalid
+// and ResponseActionFail is set.
+func (r *responseValidator) check(ctx context.Context, fullMethod string, resp interface{}) error {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil
+	}
+	err := r.validator.Validate(msg)
+	if err == nil {
+		return nil
+	}
+
+	if r.actions&ResponseActionLog != 0 {
+		r.logger.LogAttrs(ctx, slog.LevelError, "invalid grpc response",
+			slog.String("method", fullMethod),
+			slog.String("request_id", RequestIDFromContext(ctx)),
+			slog.String("message", string(proto.MessageName(msg))),
+			slog.String("error", err.Error()),
+		)
+	}
+	if r.actions&ResponseActionMetric != 0 {
+		r.invalid.Add(ctx, 1, metric.WithAttributes(methodAttributes(fullMethod)...))
+	}
+	if r.actions&ResponseActionFail != 0 {
+		return status.Error(codes.Internal, internalMessage)
+	}
+	return nil
+}
@@ -1,13 +1,24 @@
 package grpc_middleware
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
+	"strings"
 	"testing"
 
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -204,3 +215,161 @@ func TestValidatingServerStream_RecvMsg_NilMessage(t *testing.T) {
 	}
 }
 
+// newUUIDResponse returns a dynamic message with a user_id field that must be a UUID,
+// standing in for generated responses such as RegisterResponse
+func newUUIDResponse(t *testing.T, userID string) proto.Message {
+	t.Helper()
+
+	fieldOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(fieldOptions, validate.E_Field, &validate.FieldRules{
+		Type: &validate.FieldRules_String_{String_: &validate.StringRules{
+			WellKnown: &validate.StringRules_Uuid{Uuid: true},
+		}},
+	})
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/response.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("RegisterResponse"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("user_id"),
+				JsonName: proto.String("userId"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Options:  fieldOptions,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("Failed to build test descriptor: %v", err)
+	}
+
+	desc := file.Messages().Get(0)
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(desc.Fields().Get(0), protoreflect.ValueOfString(userID))
+	return msg
+}
+
+func TestParseResponseActions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ResponseAction
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"log", ResponseActionLog, false},
+		{"log, Metric", ResponseActionLog | ResponseActionMetric, false},
+		{"fail", ResponseActionFail, false},
+		{"panic", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseResponseActions(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidationMiddleware_InvalidResponse_LogsAndCounts(t *testing.T) {
+	var logs bytes.Buffer
+	reader := sdkmetric.NewManualReader()
+	middleware, err := NewValidationMiddleware(ValidateResponses(
+		ResponseActionLog|ResponseActionMetric,
+		slog.New(slog.NewJSONHandler(&logs, nil)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	))
+	if err != nil {
+		t.Fatalf("NewValidationMiddleware() failed: %v", err)
+	}
+	invalid := newUUIDResponse(t, "not-a-uuid")
+
+	resp, err := middleware.UnaryServerInterceptor()(context.Background(), &emptypb.Empty{},
+		&grpc.UnaryServerInfo{FullMethod: "/api.auth.v1.AuthService/Register"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return invalid, nil })
+
+	if err != nil || resp != invalid {
+		t.Errorf("Expected the invalid response to be returned without failing, got %v, %v", resp, err)
+	}
+	if !strings.Contains(logs.String(), "user_id") || !strings.Contains(logs.String(), "AuthService/Register") {
+		t.Errorf("Expected violation to be logged with the method, got %s", logs.String())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+	if got := counterValue(rm, "rpc.server.invalid_responses"); got != 1 {
+		t.Errorf("Expected 1 invalid response recorded, got %d", got)
+	}
+}
+
+func TestValidationMiddleware_InvalidResponse_Fail(t *testing.T) {
+	middleware, err := NewValidationMiddleware(ValidateResponses(ResponseActionFail, slog.New(slog.DiscardHandler), nil))
+	if err != nil {
+		t.Fatalf("NewValidationMiddleware() failed: %v", err)
+	}
+	interceptor := middleware.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.auth.v1.AuthService/Register"}
+
+	_, err = interceptor(context.Background(), &emptypb.Empty{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return newUUIDResponse(t, "not-a-uuid"), nil
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal for an invalid response, got %v", err)
+	}
+
+	_, err = interceptor(context.Background(), &emptypb.Empty{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return newUUIDResponse(t, "8f14e45f-ceea-467f-a0e6-6b8b5ad1c8a1"), nil
+	})
+	if err != nil {
+		t.Errorf("Expected a valid response to pass, got %v", err)
+	}
+}
+
+func TestValidatingServerStream_SendMsg_InvalidResponse_Fail(t *testing.T) {
+	middleware, err := NewValidationMiddleware(ValidateResponses(ResponseActionFail, slog.New(slog.DiscardHandler), nil))
+	if err != nil {
+		t.Fatalf("NewValidationMiddleware() failed: %v", err)
+	}
+	sent := 0
+	stream := &contextServerStream{
+		ServerStream: &mockServerStream{sendMsgFunc: func(m interface{}) error { sent++; return nil }},
+		ctx:          context.Background(),
+	}
+
+	err = middleware.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/api.chat.v1.ChatService/StreamMessages"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			return ss.SendMsg(newUUIDResponse(t, "not-a-uuid"))
+		})
+
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal for an invalid streamed message, got %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("Expected invalid message not to be sent, got %d sends", sent)
+	}
+}
+
+func TestValidationMiddleware_ResponsesDisabledByDefault(t *testing.T) {
+	middleware, err := NewValidationMiddleware()
+	if err != nil {
+		t.Fatalf("NewValidationMiddleware() failed: %v", err)
+	}
+
+	_, err = middleware.UnaryServerInterceptor()(context.Background(), &emptypb.Empty{}, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return newUUIDResponse(t, "not-a-uuid"), nil
+		})
+	if err != nil {
+		t.Errorf("Expected responses to be unchecked by default, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger is implemented by database handles and message bus clients that can
+// verify their connection, e.g. *sql.DB or *pgxpool.Pool.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck returns a Checker that pings a database or message bus connection.
+func PingCheck(p Pinger) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if err := p.PingContext(ctx); err != nil {
+			return fmt.Errorf("ping failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// GRPCCheck returns a Checker that queries the grpc.health.v1 status of a downstream
+// service over an existing client connection. An empty service checks overall health.
+func GRPCCheck(conn grpc.ClientConnInterface, service string) Checker {
+	client := healthpb.NewHealthClient(conn)
+	return CheckerFunc(func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("downstream status %s", resp.GetStatus())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockPinger struct {
+	err error
+}
+
+func (m *mockPinger) PingContext(ctx context.Context) error {
+	return m.err
+}
+
+func TestPingCheck(t *testing.T) {
+	if err := PingCheck(&mockPinger{}).Check(context.Background()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	pingErr := errors.New("connection reset")
+	err := PingCheck(&mockPinger{err: pingErr}).Check(context.Background())
+	if !errors.Is(err, pingErr) {
+		t.Errorf("Expected wrapped ping error, got: %v", err)
+	}
+}
+
+// startHealthServer serves a standard health server over bufconn and returns a client connection to it.
+func startHealthServer(t *testing.T) (*grpchealth.Server, *grpc.ClientConn) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return hs, conn
+}
+
+func TestGRPCCheck_Serving_ReturnsNil(t *testing.T) {
+	hs, conn := startHealthServer(t)
+	hs.SetServingStatus("api.social.v1.SocialService", healthpb.HealthCheckResponse_SERVING)
+
+	if err := GRPCCheck(conn, "api.social.v1.SocialService").Check(context.Background()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestGRPCCheck_NotServing_ReturnsError(t *testing.T) {
+	hs, conn := startHealthServer(t)
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	if err := GRPCCheck(conn, "").Check(context.Background()); err == nil {
+		t.Error("Expected error for NOT_SERVING downstream")
+	}
+}
+
+func TestGRPCCheck_UnknownService_ReturnsError(t *testing.T) {
+	_, conn := startHealthServer(t)
+
+	if err := GRPCCheck(conn, "api.unknown.v1.Service").Check(context.Background()); err == nil {
+		t.Error("Expected error for unknown downstream service")
+	}
+}
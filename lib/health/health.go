@@ -0,0 +1,227 @@
+package health
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 2 * time.Second
+
+	// DefaultDrainDelay is how long a stopping server keeps accepting calls after it
+	// reports NOT_SERVING, so load balancers and probes notice before it goes away.
+	DefaultDrainDelay = 5 * time.Second
+)
+
+// Checker reports whether a dependency such as a database, message bus or
+// downstream service is usable. A nil error means healthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts an ordinary function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Server implements the grpc.health.v1 protocol on top of periodic dependency checks.
+// The overall status ("") and every registered service name are SERVING only while
+// all required checks pass. Each check is also exposed under its own name so operators
+// can probe a single dependency.
+type Server struct {
+	health   *grpchealth.Server
+	services []string
+	checks   []namedCheck
+	interval time.Duration
+	timeout  time.Duration
+
+	mu      sync.RWMutex
+	results map[string]error
+}
+
+type namedCheck struct {
+	name     string
+	checker  Checker
+	optional bool
+}
+
+// Option is a functional option for configuring the health Server.
+type Option func(*Server)
+
+// NewServer creates a health server reporting status for the given gRPC service names.
+// Until the first round of checks completes, every service reports NOT_SERVING.
+func NewServer(services []string, opts ...Option) *Server {
+	s := &Server{
+		health:   grpchealth.NewServer(),
+		services: services,
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		results:  make(map[string]error),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.setAll(healthpb.HealthCheckResponse_NOT_SERVING)
+	return s
+}
+
+// WithCheck adds a named dependency check, e.g. "postgres" or "social".
+func WithCheck(name string, checker Checker) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, namedCheck{name: name, checker: checker})
+	}
+}
+
+// WithOptionalCheck adds a check on a dependency the service can run without, such as a
+// downstream service only some methods call. Its result is reported under its own name,
+// but a failure leaves the service SERVING in a degraded mode rather than taking it out
+// of rotation, which would cascade one outage into every service depending on this one.
+func WithOptionalCheck(name string, checker Checker) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, namedCheck{name: name, checker: checker, optional: true})
+	}
+}
+
+// WithInterval sets how often dependency checks are run.
+func WithInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.interval = d
+	}
+}
+
+// WithTimeout sets the deadline for a single dependency check.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = d
+	}
+}
+
+// Register registers the grpc.health.v1 Health service on the gRPC server.
+func (s *Server) Register(gs *grpc.Server) {
+	healthpb.RegisterHealthServer(gs, s.health)
+}
+
+// Run performs an initial round of checks and then re-runs them every interval
+// until ctx is cancelled. It is meant to be started in its own goroutine.
+func (s *Server) Run(ctx context.Context) {
+	s.CheckNow(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.CheckNow(ctx)
+		}
+	}
+}
+
+// CheckNow runs all dependency checks concurrently and updates the served statuses.
+func (s *Server) CheckNow(ctx context.Context) {
+	results := make(map[string]error, len(s.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range s.checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+			err := c.checker.Check(checkCtx)
+
+			mu.Lock()
+			results[c.name] = err
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, c := range s.checks {
+		err := results[c.name]
+		if err != nil && !c.optional {
+			healthy = false
+		}
+		s.health.SetServingStatus(c.name, toServingStatus(err == nil))
+	}
+
+	s.mu.Lock()
+	s.results = results
+	s.mu.Unlock()
+
+	s.setAll(toServingStatus(healthy))
+}
+
+// Results returns the outcome of the most recent round of checks, keyed by check name.
+func (s *Server) Results() map[string]error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[string]error, len(s.results))
+	for name, err := range s.results {
+		results[name] = err
+	}
+	return results
+}
+
+// Shutdown marks every service NOT_SERVING and ignores further updates,
+// letting load balancers drain the instance before the gRPC server stops.
+func (s *Server) Shutdown() {
+	s.health.Shutdown()
+}
+
+// GracefulStopper is implemented by *grpc.Server.
+type GracefulStopper interface {
+	GracefulStop()
+}
+
+// Drain reports NOT_SERVING, waits delay for load balancers to stop sending new calls
+// and then stops srv once its in-flight calls have finished.
+func (s *Server) Drain(srv GracefulStopper, delay time.Duration) {
+	s.Shutdown()
+	time.Sleep(delay)
+	srv.GracefulStop()
+}
+
+// DrainOnSignal blocks until SIGINT or SIGTERM and then drains srv. It is meant to be
+// started in its own goroutine; srv.Serve returns once the drain completes.
+func (s *Server) DrainOnSignal(srv GracefulStopper, delay time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	<-ctx.Done()
+	s.Drain(srv, delay)
+}
+
+func (s *Server) setAll(status healthpb.HealthCheckResponse_ServingStatus) {
+	s.health.SetServingStatus("", status)
+	for _, svc := range s.services {
+		s.health.SetServingStatus(svc, status)
+	}
+}
+
+func toServingStatus(healthy bool) healthpb.HealthCheckResponse_ServingStatus {
+	if healthy {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
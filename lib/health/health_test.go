@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func statusOf(t *testing.T, s *Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q) failed: %v", service, err)
+	}
+	return resp.GetStatus()
+}
+
+func TestNewServer_BeforeFirstCheck_NotServing(t *testing.T) {
+	s := NewServer([]string{"api.test.v1.TestService"})
+
+	if got := statusOf(t, s, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING before first check, got %v", got)
+	}
+}
+
+func TestServer_CheckNow_NoChecks_Serving(t *testing.T) {
+	s := NewServer([]string{"api.test.v1.TestService"})
+
+	s.CheckNow(context.Background())
+
+	for _, svc := range []string{"", "api.test.v1.TestService"} {
+		if got := statusOf(t, s, svc); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected SERVING for %q, got %v", svc, got)
+		}
+	}
+}
+
+func TestServer_CheckNow_FailingDependency_NotServing(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	s := NewServer(
+		[]string{"api.test.v1.TestService"},
+		WithCheck("postgres", CheckerFunc(func(ctx context.Context) error { return dbErr })),
+		WithCheck("kafka", CheckerFunc(func(ctx context.Context) error { return nil })),
+	)
+
+	s.CheckNow(context.Background())
+
+	tests := []struct {
+		service  string
+		expected healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"", healthpb.HealthCheckResponse_NOT_SERVING},
+		{"api.test.v1.TestService", healthpb.HealthCheckResponse_NOT_SERVING},
+		{"postgres", healthpb.HealthCheckResponse_NOT_SERVING},
+		{"kafka", healthpb.HealthCheckResponse_SERVING},
+	}
+
+	for _, tt := range tests {
+		if got := statusOf(t, s, tt.service); got != tt.expected {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.service, got)
+		}
+	}
+
+	results := s.Results()
+	if !errors.Is(results["postgres"], dbErr) {
+		t.Errorf("Expected postgres result to be the check error, got %v", results["postgres"])
+	}
+	if results["kafka"] != nil {
+		t.Errorf("Expected kafka result to be nil, got %v", results["kafka"])
+	}
+}
+
+func TestServer_CheckNow_FailingOptionalDependency_StillServing(t *testing.T) {
+	s := NewServer(
+		[]string{"api.test.v1.TestService"},
+		WithCheck("postgres", CheckerFunc(func(ctx context.Context) error { return nil })),
+		WithOptionalCheck("social", CheckerFunc(func(ctx context.Context) error { return errors.New("unavailable") })),
+	)
+
+	s.CheckNow(context.Background())
+
+	tests := []struct {
+		service  string
+		expected healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"", healthpb.HealthCheckResponse_SERVING},
+		{"api.test.v1.TestService", healthpb.HealthCheckResponse_SERVING},
+		{"postgres", healthpb.HealthCheckResponse_SERVING},
+		{"social", healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+
+	for _, tt := range tests {
+		if got := statusOf(t, s, tt.service); got != tt.expected {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.service, got)
+		}
+	}
+}
+
+func TestServer_CheckNow_Recovers(t *testing.T) {
+	healthy := false
+	s := NewServer(nil, WithCheck("social", CheckerFunc(func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("unavailable")
+		}
+		return nil
+	})))
+
+	s.CheckNow(context.Background())
+	if got := statusOf(t, s, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected NOT_SERVING, got %v", got)
+	}
+
+	healthy = true
+	s.CheckNow(context.Background())
+	if got := statusOf(t, s, ""); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected SERVING after recovery, got %v", got)
+	}
+}
+
+func TestServer_Shutdown_NotServing(t *testing.T) {
+	s := NewServer(nil)
+	s.CheckNow(context.Background())
+
+	s.Shutdown()
+
+	if got := statusOf(t, s, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING after shutdown, got %v", got)
+	}
+}
+
+type fakeStopper struct {
+	stopped bool
+	status  healthpb.HealthCheckResponse_ServingStatus
+	s       *Server
+	t       *testing.T
+}
+
+func (f *fakeStopper) GracefulStop() {
+	f.stopped = true
+	f.status = statusOf(f.t, f.s, "")
+}
+
+func TestServer_Drain_NotServingBeforeStop(t *testing.T) {
+	s := NewServer(nil)
+	s.CheckNow(context.Background())
+	stopper := &fakeStopper{s: s, t: t}
+
+	s.Drain(stopper, 0)
+
+	if !stopper.stopped {
+		t.Fatal("Expected the gRPC server to be stopped")
+	}
+	if stopper.status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING before the server stopped, got %v", stopper.status)
+	}
+}
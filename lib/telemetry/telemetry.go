@@ -0,0 +1,154 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config holds tracing and metrics settings for a single service.
+type Config struct {
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+
+	// OTLPEndpoint is the host:port of an OTLP/gRPC collector.
+	// When empty, spans are still created and propagated but not exported.
+	OTLPEndpoint string
+
+	// OTLPInsecure disables TLS towards the collector.
+	OTLPInsecure bool
+
+	// SampleRatio is the fraction of new root traces to sample. Child spans follow their parent.
+	SampleRatio float64
+
+	// MetricsAddr is the listen address of the Prometheus /metrics endpoint.
+	MetricsAddr string
+}
+
+// ConfigFromEnv builds a Config from the standard OTEL_* environment variables
+// plus METRICS_ADDR for the Prometheus listener.
+func ConfigFromEnv(serviceName string) Config {
+	cfg := Config{
+		ServiceName:  serviceName,
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		OTLPInsecure: true,
+		SampleRatio:  1.0,
+		MetricsAddr:  ":9090",
+	}
+
+	if v, err := strconv.ParseBool(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE")); err == nil {
+		cfg.OTLPInsecure = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64); err == nil {
+		cfg.SampleRatio = v
+	}
+	if v := os.Getenv("METRICS_ADDR"); v != "" {
+		cfg.MetricsAddr = v
+	}
+
+	return cfg
+}
+
+// Provider owns the service's tracer and meter providers and the Prometheus registry backing /metrics.
+type Provider struct {
+	cfg            Config
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	registry       *prometheus.Registry
+}
+
+// New creates tracer and meter providers for the service and installs the
+// W3C trace context and baggage propagators globally.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	traceOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	if cfg.OTLPEndpoint != "" {
+		exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		traceOpts = append(traceOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	metricsExporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return &Provider{
+		cfg:            cfg,
+		tracerProvider: sdktrace.NewTracerProvider(traceOpts...),
+		meterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithResource(res), sdkmetric.WithReader(metricsExporter)),
+		registry:       registry,
+	}, nil
+}
+
+// TracerProvider returns the provider used to create spans.
+func (p *Provider) TracerProvider() trace.TracerProvider {
+	return p.tracerProvider
+}
+
+// MeterProvider returns the provider used to record metrics.
+func (p *Provider) MeterProvider() metric.MeterProvider {
+	return p.meterProvider
+}
+
+// MetricsHandler returns an http.Handler serving all metrics in Prometheus text format.
+func (p *Provider) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// ServeMetrics serves /metrics on the configured MetricsAddr. It blocks until the listener fails.
+func (p *Provider) ServeMetrics() error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", p.MetricsHandler())
+	return http.ListenAndServe(p.cfg.MetricsAddr, mux)
+}
+
+// Shutdown flushes pending spans and stops both providers.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return errors.Join(
+		p.tracerProvider.Shutdown(ctx),
+		p.meterProvider.Shutdown(ctx),
+	)
+}
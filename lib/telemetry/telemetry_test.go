@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv_Defaults(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "")
+	t.Setenv("METRICS_ADDR", "")
+
+	cfg := ConfigFromEnv("chat")
+
+	if cfg.ServiceName != "chat" {
+		t.Errorf("Expected service name 'chat', got '%s'", cfg.ServiceName)
+	}
+	if cfg.OTLPEndpoint != "" {
+		t.Errorf("Expected export to be disabled by default, got endpoint '%s'", cfg.OTLPEndpoint)
+	}
+	if cfg.SampleRatio != 1.0 {
+		t.Errorf("Expected sample ratio 1.0, got %v", cfg.SampleRatio)
+	}
+	if cfg.MetricsAddr != ":9090" {
+		t.Errorf("Expected metrics address ':9090', got '%s'", cfg.MetricsAddr)
+	}
+}
+
+func TestConfigFromEnv_Overrides(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "false")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("METRICS_ADDR", ":9100")
+
+	cfg := ConfigFromEnv("chat")
+
+	if cfg.OTLPEndpoint != "otel-collector:4317" || cfg.OTLPInsecure || cfg.SampleRatio != 0.25 || cfg.MetricsAddr != ":9100" {
+		t.Errorf("Environment overrides not applied: %+v", cfg)
+	}
+}
+
+func TestProvider_MetricsHandler_ServesPrometheusFormat(t *testing.T) {
+	p, err := New(context.Background(), Config{ServiceName: "test", SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = p.Shutdown(context.Background()) }()
+
+	counter, err := p.MeterProvider().Meter("test").Int64Counter("test.requests")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+	counter.Add(context.Background(), 3)
+
+	rec := httptest.NewRecorder()
+	p.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"test_requests_total", "go_goroutines"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics output to contain %s", want)
+		}
+	}
+}
+
+func TestProvider_TracerProvider_CreatesSampledSpans(t *testing.T) {
+	p, err := New(context.Background(), Config{ServiceName: "test", SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer func() { _ = p.Shutdown(context.Background()) }()
+
+	_, span := p.TracerProvider().Tracer("test").Start(context.Background(), "op")
+	defer span.End()
+
+	if !span.SpanContext().IsSampled() {
+		t.Error("Expected span to be sampled with ratio 1")
+	}
+}
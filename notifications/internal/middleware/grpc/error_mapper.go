@@ -1,37 +1,20 @@
 package grpc
 
 import (
-	"context"
-	"errors"
+	"log/slog"
 
+	"github.com/go-chat/lib/grpc_middleware"
 	"github.com/go-chat/notifications/internal/domain"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// ErrorMapperInterceptor returns a unary server interceptor that maps domain errors to gRPC status codes
-func ErrorMapperInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		resp, err := handler(ctx, req)
-		if err != nil {
-			return resp, mapDomainError(err)
-		}
-		return resp, nil
-	}
-}
+// ErrorDomain identifies notifications errors in google.rpc.ErrorInfo details
+const ErrorDomain = "notifications.go-chat"
 
-// mapDomainError converts domain errors to appropriate gRPC status codes
-func mapDomainError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrNotificationNotFound):
-		return status.Error(codes.NotFound, "notification not found")
-	case errors.Is(err, domain.ErrPermissionDenied):
-		return status.Error(codes.PermissionDenied, "permission denied")
-	default:
-		// Log internal error details here if needed
-		// For now, return a generic internal error
-		return status.Error(codes.Internal, "internal server error")
-	}
+// NewErrorRegistry maps notifications domain errors to the gRPC statuses clients receive.
+// Unregistered errors are logged to logger and returned as Internal.
+func NewErrorRegistry(logger *slog.Logger) *grpc_middleware.ErrorRegistry {
+	return grpc_middleware.NewErrorRegistry(ErrorDomain, logger).
+		Register(domain.ErrNotificationNotFound, grpc_middleware.ErrorMapping{Code: codes.NotFound, Message: "notification not found", Reason: "NOTIFICATION_NOT_FOUND"}).
+		Register(domain.ErrPermissionDenied, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "permission denied", Reason: "PERMISSION_DENIED"})
 }
-
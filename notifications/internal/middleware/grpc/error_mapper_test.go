@@ -2,7 +2,7 @@ package grpc
 
 import (
 	"context"
-	"errors"
+	"log/slog"
 	"testing"
 
 	"github.com/go-chat/notifications/internal/domain"
@@ -11,8 +11,11 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func TestErrorMapperInterceptor_MapsNotificationNotFound(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+// testLogger discards the registry's logs of mapped and internal errors
+var testLogger = slog.New(slog.DiscardHandler)
+
+func TestErrorRegistry_MapsNotificationNotFound(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrNotificationNotFound
 	}
@@ -33,8 +36,8 @@ func TestErrorMapperInterceptor_MapsNotificationNotFound(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsPermissionDenied(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrPermissionDenied
 	}
@@ -54,45 +57,3 @@ func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
 		t.Errorf("Expected code PermissionDenied, got: %v", st.Code())
 	}
 }
-
-func TestErrorMapperInterceptor_MapsUnknownErrorToInternal(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	unknownErr := errors.New("unknown error")
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return nil, unknownErr
-	}
-
-	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err == nil {
-		t.Fatal("Expected error, got nil")
-	}
-
-	st, ok := status.FromError(err)
-	if !ok {
-		t.Fatalf("Expected gRPC status error, got: %v", err)
-	}
-
-	if st.Code() != codes.Internal {
-		t.Errorf("Expected code Internal, got: %v", st.Code())
-	}
-}
-
-func TestErrorMapperInterceptor_PassesThroughSuccessfulResponse(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	expectedResp := "success"
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return expectedResp, nil
-	}
-
-	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err != nil {
-		t.Fatalf("Expected no error, got: %v", err)
-	}
-
-	if resp != expectedResp {
-		t.Errorf("Expected response %v, got: %v", expectedResp, resp)
-	}
-}
-
@@ -16,7 +16,7 @@ docker-up: ## Start all services with docker-compose
 	@echo "All services started ✓"
 	@echo ""
 	@echo "🌐 Gateway API: http://localhost:8080"
-	@echo "📚 API Documentation (Swagger): http://localhost:8081"
+	@echo "📚 API Documentation (Swagger): http://localhost:8080/docs"
 	@echo ""
 	@echo "Individual gRPC services (for debugging):"
 	@echo "  - Auth Service: localhost:9001"
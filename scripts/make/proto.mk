@@ -6,12 +6,20 @@ SERVICES := auth users social chat notifications
 .PHONY: proto-gen
 proto-gen: ## Generate Go code from proto files for all services
 	@echo "Generating proto files for all services..."
+	@echo "Generating lib..."
+	@buf generate --template lib/buf.gen.yaml
 	@for service in $(SERVICES); do \
 		echo "Generating $$service..."; \
 		buf generate --template $$service/buf.gen.yaml; \
 	done
 	@echo "Proto generation completed ✓"
 
+.PHONY: proto-gen-lib
+proto-gen-lib: ## Generate proto files for the shared lib options
+	@echo "Generating proto files for lib..."
+	@buf generate --template lib/buf.gen.yaml
+	@echo "Lib proto generation completed ✓"
+
 .PHONY: proto-gen-auth
 proto-gen-auth: ## Generate proto files for auth service
 	@echo "Generating proto files for auth service..."
@@ -63,7 +71,7 @@ proto-breaking: ## Check for breaking changes in proto files
 .PHONY: proto-clean
 proto-clean: ## Clean generated proto files
 	@echo "Cleaning generated proto files..."
-	@for service in $(SERVICES); do \
+	@for service in lib $(SERVICES); do \
 		echo "Cleaning $$service/pkg/..."; \
 		rm -rf $$service/pkg/*; \
 		touch $$service/pkg/.gitkeep; \
@@ -0,0 +1,20 @@
+package grpc
+
+import (
+	"github.com/go-chat/lib/audit"
+	socialv1 "github.com/go-chat/social/pkg/api/social/v1"
+)
+
+// AuditMethods declares the social methods recorded in the audit log. The actor is the
+// authenticated user; the target is the other user affected.
+func AuditMethods() map[string]audit.MethodConfig {
+	return map[string]audit.MethodConfig{
+		socialv1.SocialService_BlockUser_FullMethodName: {
+			RequestFields: []string{"target_user_id"},
+		},
+		socialv1.SocialService_AcceptFriendRequest_FullMethodName: {
+			RequestFields:  []string{"request_id"},
+			ResponseFields: []string{"request.requester_id"},
+		},
+	}
+}
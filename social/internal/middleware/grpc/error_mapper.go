@@ -1,47 +1,25 @@
 package grpc
 
 import (
-	"context"
-	"errors"
+	"log/slog"
 
+	"github.com/go-chat/lib/grpc_middleware"
 	"github.com/go-chat/social/internal/domain"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// ErrorMapperInterceptor returns a unary server interceptor that maps domain errors to gRPC status codes
-func ErrorMapperInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		resp, err := handler(ctx, req)
-		if err != nil {
-			return resp, mapDomainError(err)
-		}
-		return resp, nil
-	}
-}
+// ErrorDomain identifies social errors in google.rpc.ErrorInfo details
+const ErrorDomain = "social.go-chat"
 
-// mapDomainError converts domain errors to appropriate gRPC status codes
-func mapDomainError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrRequestNotFound):
-		return status.Error(codes.NotFound, "friend request not found")
-	case errors.Is(err, domain.ErrRequestAlreadyExists):
-		return status.Error(codes.AlreadyExists, "friend request already exists")
-	case errors.Is(err, domain.ErrAlreadyFriends):
-		return status.Error(codes.AlreadyExists, "users are already friends")
-	case errors.Is(err, domain.ErrNotFriends):
-		return status.Error(codes.NotFound, "users are not friends")
-	case errors.Is(err, domain.ErrUserBlocked):
-		return status.Error(codes.PermissionDenied, "user is blocked")
-	case errors.Is(err, domain.ErrPermissionDenied):
-		return status.Error(codes.PermissionDenied, "permission denied")
-	case errors.Is(err, domain.ErrSelfAction):
-		return status.Error(codes.InvalidArgument, "cannot perform this action on yourself")
-	default:
-		// Log internal error details here if needed
-		// For now, return a generic internal error
-		return status.Error(codes.Internal, "internal server error")
-	}
+// NewErrorRegistry maps social domain errors to the gRPC statuses clients receive.
+// Unregistered errors are logged to logger and returned as Internal.
+func NewErrorRegistry(logger *slog.Logger) *grpc_middleware.ErrorRegistry {
+	return grpc_middleware.NewErrorRegistry(ErrorDomain, logger).
+		Register(domain.ErrRequestNotFound, grpc_middleware.ErrorMapping{Code: codes.NotFound, Message: "friend request not found", Reason: "FRIEND_REQUEST_NOT_FOUND"}).
+		Register(domain.ErrRequestAlreadyExists, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "friend request already exists", Reason: "FRIEND_REQUEST_ALREADY_EXISTS"}).
+		Register(domain.ErrAlreadyFriends, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "users are already friends", Reason: "ALREADY_FRIENDS"}).
+		Register(domain.ErrNotFriends, grpc_middleware.ErrorMapping{Code: codes.NotFound, Message: "users are not friends", Reason: "NOT_FRIENDS"}).
+		Register(domain.ErrUserBlocked, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "user is blocked", Reason: "USER_BLOCKED"}).
+		Register(domain.ErrPermissionDenied, grpc_middleware.ErrorMapping{Code: codes.PermissionDenied, Message: "permission denied", Reason: "PERMISSION_DENIED"}).
+		Register(domain.ErrSelfAction, grpc_middleware.ErrorMapping{Code: codes.InvalidArgument, Message: "cannot perform this action on yourself", Reason: "SELF_ACTION"})
 }
-
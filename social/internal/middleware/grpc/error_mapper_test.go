@@ -2,7 +2,7 @@ package grpc
 
 import (
 	"context"
-	"errors"
+	"log/slog"
 	"testing"
 
 	"github.com/go-chat/social/internal/domain"
@@ -11,8 +11,11 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func TestErrorMapperInterceptor_MapsRequestNotFound(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+// testLogger discards the registry's logs of mapped and internal errors
+var testLogger = slog.New(slog.DiscardHandler)
+
+func TestErrorRegistry_MapsRequestNotFound(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrRequestNotFound
 	}
@@ -33,8 +36,8 @@ func TestErrorMapperInterceptor_MapsRequestNotFound(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsRequestAlreadyExists(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsRequestAlreadyExists(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrRequestAlreadyExists
 	}
@@ -55,8 +58,8 @@ func TestErrorMapperInterceptor_MapsRequestAlreadyExists(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsAlreadyFriends(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsAlreadyFriends(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrAlreadyFriends
 	}
@@ -77,8 +80,8 @@ func TestErrorMapperInterceptor_MapsAlreadyFriends(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsPermissionDenied(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrPermissionDenied
 	}
@@ -99,8 +102,8 @@ func TestErrorMapperInterceptor_MapsPermissionDenied(t *testing.T) {
 	}
 }
 
-func TestErrorMapperInterceptor_MapsSelfAction(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
+func TestErrorRegistry_MapsSelfAction(t *testing.T) {
+	interceptor := NewErrorRegistry(testLogger).UnaryServerInterceptor()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return nil, domain.ErrSelfAction
 	}
@@ -120,45 +123,3 @@ func TestErrorMapperInterceptor_MapsSelfAction(t *testing.T) {
 		t.Errorf("Expected code InvalidArgument, got: %v", st.Code())
 	}
 }
-
-func TestErrorMapperInterceptor_MapsUnknownErrorToInternal(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	unknownErr := errors.New("unknown error")
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return nil, unknownErr
-	}
-
-	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err == nil {
-		t.Fatal("Expected error, got nil")
-	}
-
-	st, ok := status.FromError(err)
-	if !ok {
-		t.Fatalf("Expected gRPC status error, got: %v", err)
-	}
-
-	if st.Code() != codes.Internal {
-		t.Errorf("Expected code Internal, got: %v", st.Code())
-	}
-}
-
-func TestErrorMapperInterceptor_PassesThroughSuccessfulResponse(t *testing.T) {
-	interceptor := ErrorMapperInterceptor()
-	expectedResp := "success"
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return expectedResp, nil
-	}
-
-	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-
-	if err != nil {
-		t.Fatalf("Expected no error, got: %v", err)
-	}
-
-	if resp != expectedResp {
-		t.Errorf("Expected response %v, got: %v", expectedResp, resp)
-	}
-}
-
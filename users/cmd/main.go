@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net"
+	"os"
 
+	"github.com/go-chat/lib/admin"
 	"github.com/go-chat/lib/grpc_middleware"
+	"github.com/go-chat/lib/health"
+	"github.com/go-chat/lib/telemetry"
 	"github.com/go-chat/users/internal/handler"
 	grpcmw "github.com/go-chat/users/internal/middleware/grpc"
 	"github.com/go-chat/users/internal/service"
@@ -20,41 +26,88 @@ const (
 func main() {
 	log.Println("Users Service starting...")
 
-	// Create middleware manager with validation enabled by default
-	mgr, err := grpc_middleware.NewManager()
+	// Structured JSON logs for per-request logging
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service", "users")
+
+	// Tracing and Prometheus metrics; spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	telemetryCfg := telemetry.ConfigFromEnv("users")
+	tel, err := telemetry.New(context.Background(), telemetryCfg)
 	if err != nil {
-		log.Fatalf("Failed to create middleware manager: %v", err)
+		log.Fatalf("Failed to initialize telemetry: %v", err)
 	}
+	go func() {
+		if err := tel.ServeMetrics(); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
 
-	// Get interceptor chains
-	unaryInterceptors, err := mgr.UnaryInterceptors()
+	// Staging sets RESPONSE_VALIDATION to catch handlers returning responses that break their own rules
+	responseValidation, err := grpc_middleware.ResponseActionsFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to get unary interceptors: %v", err)
+		log.Fatalf("Invalid response validation config: %v", err)
 	}
 
-	// Add users service error mapper middleware
-	usersErrorMapper := grpcmw.ErrorMapperInterceptor()
-	unaryInterceptors = append(unaryInterceptors, usersErrorMapper)
+	// Per-method limits come from RATE_LIMITS so each deployment can tune them without a rebuild
+	rateLimits, err := grpc_middleware.RateLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid rate limit config: %v", err)
+	}
 
-	streamInterceptors, err := mgr.StreamInterceptors()
+	// Create middleware manager with validation enabled by default, panic recovery, authorization, request logging, telemetry, deadlines and rate limits
+	mgr, err := grpc_middleware.NewManager(
+		grpc_middleware.WithRecovery(nil),
+		grpc_middleware.WithResponseValidation(responseValidation),
+		grpc_middleware.WithLogger(logger),
+		grpc_middleware.WithTelemetry(tel.TracerProvider(), tel.MeterProvider()),
+		grpc_middleware.WithAuthorization(grpc_middleware.ServiceTokenAuthenticator(grpc_middleware.ServiceTokenFromEnv())),
+		grpc_middleware.WithDeadlines(grpc_middleware.DefaultDeadlines()),
+		grpc_middleware.WithRateLimit(rateLimits),
+		// Map domain errors to statuses innermost, so logging and metrics see the final code
+		grpc_middleware.WithErrorRegistry(grpcmw.NewErrorRegistry(logger)),
+	)
 	if err != nil {
-		log.Fatalf("Failed to get stream interceptors: %v", err)
+		log.Fatalf("Failed to create middleware manager: %v", err)
+	}
+
+	serverOpts, err := mgr.ServerOptions()
+	if err != nil {
+		log.Fatalf("Failed to build interceptor chains: %v", err)
 	}
 
 	// Create gRPC server with middleware
-	grpcServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(unaryInterceptors...),
-		grpc.ChainStreamInterceptor(streamInterceptors...),
-	)
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// TODO: Replace with actual service implementation in next iteration
-	// For now, use nil service - handlers will panic if called
+	// For now, use nil service - handlers will panic if called, which recovery turns into Internal errors
 	var userService service.UserService = nil
 
 	userHandler := handler.NewServer(userService)
 	usersv1.RegisterUserServiceServer(grpcServer, userHandler)
 	reflection.Register(grpcServer)
 
+	// Expose grpc.health.v1; dependency checks are added as repositories and clients are wired in
+	healthServer := health.NewServer([]string{usersv1.UserService_ServiceDesc.ServiceName})
+	healthServer.Register(grpcServer)
+
+	// Methods without a (go_chat.auth) option are denied; surface them before they are called
+	if missing := mgr.UnannotatedMethods(grpcServer.GetServiceInfo()); len(missing) > 0 {
+		log.Printf("Methods without an authorization policy will be denied: %v", missing)
+	}
+	go healthServer.Run(context.Background())
+	// On SIGTERM report NOT_SERVING first, so load balancers stop routing here, then drain in-flight calls
+	go healthServer.DrainOnSignal(grpcServer, health.DefaultDrainDelay)
+
+	// pprof, config and route table on an internal listener; ADMIN_ADDR defaults to localhost
+	adminServer := admin.New(admin.AddrFromEnv(),
+		admin.WithConfig(telemetryCfg),
+		admin.WithRoutes(admin.GRPCRoutes(grpcServer)),
+	)
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+
 	listener, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("Failed to listen on %s: %v", port, err)
@@ -64,4 +117,5 @@ func main() {
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
+	log.Println("Users Service stopped")
 }
@@ -1,40 +1,22 @@
 package grpc
 
 import (
-	"context"
-	"errors"
+	"log/slog"
 
+	"github.com/go-chat/lib/grpc_middleware"
 	"github.com/go-chat/users/internal/domain"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// ErrorMapperInterceptor returns a unary server interceptor that maps domain errors to gRPC status codes
-func ErrorMapperInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		resp, err := handler(ctx, req)
-		if err != nil {
-			return resp, mapDomainError(err)
-		}
-		return resp, nil
-	}
-}
+// ErrorDomain identifies users errors in google.rpc.ErrorInfo details
+const ErrorDomain = "users.go-chat"
 
-// mapDomainError converts domain errors to appropriate gRPC status codes
-func mapDomainError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrProfileAlreadyExists):
-		return status.Error(codes.AlreadyExists, "profile already exists")
-	case errors.Is(err, domain.ErrProfileNotFound):
-		return status.Error(codes.NotFound, "profile not found")
-	case errors.Is(err, domain.ErrNicknameAlreadyExists):
-		return status.Error(codes.AlreadyExists, "nickname already taken")
-	case errors.Is(err, domain.ErrInvalidNickname):
-		return status.Error(codes.InvalidArgument, "invalid nickname format")
-	default:
-		// Log internal error details here if needed
-		// For now, return a generic internal error
-		return status.Error(codes.Internal, "internal server error")
-	}
+// NewErrorRegistry maps users domain errors to the gRPC statuses clients receive.
+// Unregistered errors are logged to logger and returned as Internal.
+func NewErrorRegistry(logger *slog.Logger) *grpc_middleware.ErrorRegistry {
+	return grpc_middleware.NewErrorRegistry(ErrorDomain, logger).
+		Register(domain.ErrProfileAlreadyExists, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "profile already exists", Reason: "PROFILE_ALREADY_EXISTS"}).
+		Register(domain.ErrProfileNotFound, grpc_middleware.ErrorMapping{Code: codes.NotFound, Message: "profile not found", Reason: "PROFILE_NOT_FOUND"}).
+		Register(domain.ErrNicknameAlreadyExists, grpc_middleware.ErrorMapping{Code: codes.AlreadyExists, Message: "nickname already taken", Reason: "NICKNAME_TAKEN"}).
+		Register(domain.ErrInvalidNickname, grpc_middleware.ErrorMapping{Code: codes.InvalidArgument, Message: "invalid nickname format", Reason: "INVALID_NICKNAME"})
 }